@@ -15,264 +15,3262 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/health": {
+        "/admin/audit": {
             "get": {
-                "description": "Проверка доступности сервиса",
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Возвращает записи о создании, отмене, удалении и повторе задач в порядке добавления. Следующую страницу даёт параметр after из поля next",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "admin"
+                ],
+                "summary": "Журнал аудита",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID принципала",
+                        "name": "principal",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор",
+                        "name": "tenant",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "task:create",
+                            "task:cancel",
+                            "task:delete",
+                            "task:rerun"
+                        ],
+                        "type": "string",
+                        "description": "Действие",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "target",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "success",
+                            "denied",
+                            "failure"
+                        ],
+                        "type": "string",
+                        "description": "Исход",
+                        "name": "outcome",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Не раньше (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Раньше (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Записи с seq больше заданного",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Размер страницы, до 1000",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Healthcheck",
                 "responses": {
                     "200": {
-                        "description": "ok",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/phttp.AuditPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный фильтр",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет API-ключа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Ключу не разрешена область admin",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Журнал не читается",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
                         }
                     }
                 }
             }
         },
-        "/tasks": {
-            "post": {
-                "description": "Инициализирует задачу со статусом Pending и возвращает её с сгенерированным ID",
+        "/admin/audit/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Выгружает записи журнала как есть, по одной JSON-записи на строку (NDJSON), с хешами цепочки. Фильтры те же, что у /admin/audit, limit по умолчанию не ограничен",
                 "produces": [
-                    "application/json"
+                    "application/x-ndjson"
                 ],
                 "tags": [
-                    "tasks"
+                    "admin"
+                ],
+                "summary": "Выгрузка журнала аудита",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID принципала",
+                        "name": "principal",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор",
+                        "name": "tenant",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Действие",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "target",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Исход",
+                        "name": "outcome",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Не раньше (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Раньше (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Записи с seq больше заданного",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Число записей",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Создать новую задачу",
                 "responses": {
                     "200": {
-                        "description": "Задача успешно создана",
+                        "description": "Записи NDJSON",
                         "schema": {
-                            "$ref": "#/definitions/domen.Task"
+                            "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
+                    "400": {
+                        "description": "Некорректный фильтр",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет API-ключа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Ключу не разрешена область admin",
                         "schema": {
-                            "$ref": "#/definitions/phttp.ErrorResponse"
+                            "$ref": "#/definitions/phttp.Problem"
                         }
                     }
                 }
             }
         },
-        "/tasks/all": {
+        "/admin/audit/verify": {
             "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Проверяет цепочку хешей всего журнала. При нарушении valid равно false, а error указывает первую испорченную строку",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tasks"
+                    "admin"
                 ],
-                "summary": "Получить список всех задач",
+                "summary": "Проверка журнала аудита",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domen.TaskListItem"
-                            }
+                            "$ref": "#/definitions/domen.AuditVerification"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет API-ключа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Ключу не разрешена область admin",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Журнал не читается",
                         "schema": {
-                            "$ref": "#/definitions/phttp.ErrorResponse"
+                            "$ref": "#/definitions/phttp.Problem"
                         }
                     }
                 }
             }
         },
-        "/tasks/{id}": {
+        "/admin/config": {
             "get": {
-                "description": "Возвращает задачу по её идентификатору",
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Возвращает конфигурацию по именам переменных окружения. Секреты заменены на [REDACTED]",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tasks"
+                    "admin"
                 ],
-                "summary": "Получить задачу по ID",
-                "parameters": [
+                "summary": "Действующая конфигурация",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет API-ключа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Ключу не разрешена область admin",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/log-level": {
+            "get": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "ID задачи",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "ApiKey": []
                     }
                 ],
+                "description": "Возвращает глобальный уровень и уровни, заданные именованным логгерам (main, http, batch, metrics)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Уровни логирования",
                 "responses": {
                     "200": {
-                        "description": "Задача найдена",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domen.Task"
+                            "$ref": "#/definitions/phttp.LogLevels"
                         }
                     },
-                    "404": {
-                        "description": "Задача не найдена",
+                    "401": {
+                        "description": "Нет API-ключа",
                         "schema": {
-                            "$ref": "#/definitions/phttp.ErrorResponse"
+                            "$ref": "#/definitions/phttp.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
+                    "403": {
+                        "description": "Ключу не разрешена область admin",
                         "schema": {
-                            "$ref": "#/definitions/phttp.ErrorResponse"
+                            "$ref": "#/definitions/phttp.Problem"
                         }
                     }
                 }
             },
-            "delete": {
-                "description": "Удаляет задачу из системы по её идентификатору",
+            "put": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Меняет глобальный уровень или уровень именованного логгера без перезапуска. Уровень логгера действует и на его потомков (\"http\" для \"http.client\")",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "tasks"
+                    "admin"
                 ],
-                "summary": "Удалить задачу по ID",
+                "summary": "Изменить уровень логирования",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID задачи",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Логгер и уровень",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/phttp.SetLogLevelRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "Уровни после изменения",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.LogLevels"
+                        }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
+                    "400": {
+                        "description": "Неизвестный уровень",
                         "schema": {
-                            "$ref": "#/definitions/phttp.ErrorResponse"
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет API-ключа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Ключу не разрешена область admin",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
                         }
                     }
                 }
             }
         },
-        "/tasks/{id}/cancel": {
-            "put": {
-                "description": "Прерывает выполнение задачи, если она ещё не завершена",
-                "tags": [
-                    "tasks"
-                ],
-                "summary": "Отменить задачу",
-                "parameters": [
+        "/admin/runtime": {
+            "get": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "ID задачи",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "ApiKey": []
                     }
                 ],
+                "description": "Возвращает время работы, число горутин, память и сведения о сборке",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Состояние рантайма",
                 "responses": {
                     "200": {
-                        "description": "Задача отменена",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/phttp.RuntimeInfo"
                         }
                     },
-                    "404": {
-                        "description": "Задача не найдена",
+                    "401": {
+                        "description": "Нет API-ключа",
                         "schema": {
-                            "$ref": "#/definitions/phttp.ErrorResponse"
+                            "$ref": "#/definitions/phttp.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка",
+                    "403": {
+                        "description": "Ключу не разрешена область admin",
                         "schema": {
-                            "$ref": "#/definitions/phttp.ErrorResponse"
+                            "$ref": "#/definitions/phttp.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "domen.Status": {
-            "type": "string",
-            "enum": [
-                "PENDING",
-                "RUNNING",
-                "COMPLETED",
-                "FAILED",
-                "CANCELED"
-            ],
-            "x-enum-varnames": [
-                "StatusPending",
-                "StatusRunning",
-                "StatusCompleted",
-                "StatusFailed",
-                "StatusCancelled"
-            ]
         },
-        "domen.Task": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "duration": {
-                    "description": "Duration of the task execution\nexample: 3m0s",
-                    "type": "string"
-                },
-                "ended_at": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "result": {
-                    "type": "string"
-                },
-                "started_at": {
-                    "type": "string"
+        "/health": {
+            "get": {
+                "description": "Всегда отвечает ok. Устарел: используйте /livez и /readyz",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Healthcheck",
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/livez": {
+            "get": {
+                "description": "Проверяет, что процесс жив и его фоновые процессы работают. С параметром verbose отвечает текстом для человека",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness-проба",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Текстовый отчёт по компонентам",
+                        "name": "verbose",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Процесс жив",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Процесс нужно перезапустить",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Проверяет, что сервис запущен, не останавливается и его зависимости доступны. С параметром verbose отвечает текстом для человека",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness-проба",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Текстовый отчёт по компонентам",
+                        "name": "verbose",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сервис готов принимать запросы",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервис запускается, останавливается или зависимость недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/batches": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Создаёт пакет из count задач (не больше 1000) типа type с параметрами payload и запускает их. Payload проверяется по JSON Schema типа задачи",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "batches"
+                ],
+                "summary": "Создать пакет задач",
+                "parameters": [
+                    {
+                        "description": "Число, тип и параметры задач",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/phttp.BatchCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domen.Batch"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышена квота арендатора",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/batches/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Возвращает пакет с числом задач по статусам и общим прогрессом",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "batches"
+                ],
+                "summary": "Получить пакет задач",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пакета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domen.BatchProgress"
+                        }
+                    },
+                    "403": {
+                        "description": "Политика доступа запрещает читать задачи пакета",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Пакет не найден",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/batches/{id}/cancel": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Отменяет все незавершённые задачи пакета и возвращает результат по каждой",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "batches"
+                ],
+                "summary": "Отменить пакет задач",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пакета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.BulkResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Пакет не найден",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/task-types": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Возвращает зарегистрированные типы задач с JSON Schema их payload",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "task-types"
+                ],
+                "summary": "Типы задач",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domen.TaskType"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tasks": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Инициализирует задачу со статусом Pending и возвращает её с сгенерированным ID. Payload проверяется по JSON Schema типа задачи",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Создать новую задачу",
+                "parameters": [
+                    {
+                        "description": "Тип и параметры задачи",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.CreateTaskRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Задача успешно создана",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.TaskV1"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия задачи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неизвестный тип или payload не прошёл схему",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышена квота арендатора",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tasks/all": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Получить список всех задач",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domen.TaskListItem"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tasks/batch": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Создаёт count задач (не больше 1000) типа type с параметрами payload и возвращает результат по каждой. Payload проверяется по JSON Schema типа задачи",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Создать несколько задач",
+                "parameters": [
+                    {
+                        "description": "Число, тип и параметры задач",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/phttp.BatchCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.BulkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышена квота арендатора",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tasks/bulk-cancel": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Отменяет задачи по списку ID или по фильтру (не больше 1000) и возвращает результат по каждой",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Отменить несколько задач",
+                "parameters": [
+                    {
+                        "description": "Список ID или фильтр",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/phttp.BulkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.BulkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tasks/bulk-delete": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Удаляет задачи по списку ID или по фильтру (не больше 1000) и возвращает результат по каждой",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Удалить несколько задач",
+                "parameters": [
+                    {
+                        "description": "Список ID или фильтр",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/phttp.BulkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.BulkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tasks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Возвращает задачу по её идентификатору",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Получить задачу по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Задача найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.TaskV1"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия задачи"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Удаляет задачу из системы по её идентификатору",
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Удалить задачу по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ожидаемая версия задачи (ETag)",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия задачи не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tasks/{id}/cancel": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Прерывает выполнение задачи, если она ещё не завершена",
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Отменить задачу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ожидаемая версия задачи (ETag)",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Задача отменена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия задачи"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Задача уже завершена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия задачи не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tasks/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Возвращает все переходы статуса задачи с временем и причиной",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "История статусов задачи",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domen.Transition"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tasks/{id}/rerun": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Создаёт и запускает новую задачу, связанную с завершённой задачей через rerun_of. Исходная задача не меняется. Payload из тела заменяет payload исходной задачи и проверяется по схеме её типа",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Повторить задачу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID исходной задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новый payload",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.RerunRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Новая задача",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.TaskV1"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия задачи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Payload не прошёл схему типа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Задача ещё не завершена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышена квота арендатора",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tasks/{id}/reruns": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Возвращает все повторы задачи, включая повторы повторов, в порядке создания",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Повторы задачи",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID исходной задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/phttp.TaskV1"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/batches": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Создаёт пакет из count задач (не больше 1000) типа type с параметрами payload и запускает их. Payload проверяется по JSON Schema типа задачи",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Создать пакет задач",
+                "parameters": [
+                    {
+                        "description": "Число, тип и параметры задач",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/phttp.BatchCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/phttp.BatchV2"
+                                        }
+                                    }
+                                }
+                            ]
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Адрес пакета"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышена квота арендатора",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/batches/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Возвращает пакет с числом задач по статусам и общим прогрессом",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Получить пакет задач",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пакета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/phttp.BatchV2"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Политика доступа запрещает читать задачи пакета",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Пакет не найден",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/batches/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Отменяет все незавершённые задачи пакета и возвращает результат по каждой",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Отменить пакет задач",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пакета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/phttp.BulkItemV2"
+                                            }
+                                        },
+                                        "meta": {
+                                            "$ref": "#/definitions/phttp.BulkMeta"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Пакет не найден",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/task-types": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Типы задач",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/domen.TaskType"
+                                            }
+                                        },
+                                        "meta": {
+                                            "$ref": "#/definitions/phttp.ListMeta"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tasks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Список задач",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/phttp.TaskV2"
+                                            }
+                                        },
+                                        "meta": {
+                                            "$ref": "#/definitions/phttp.ListMeta"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Создаёт задачу указанного типа. Payload проверяется по JSON Schema типа задачи",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Создать задачу",
+                "parameters": [
+                    {
+                        "description": "Тип и параметры задачи",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.CreateTaskRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/phttp.TaskV2"
+                                        }
+                                    }
+                                }
+                            ]
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия задачи"
+                            },
+                            "Location": {
+                                "type": "string",
+                                "description": "Адрес задачи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неизвестный тип или payload не прошёл схему",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышена квота арендатора",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tasks/batch": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Создаёт count задач (не больше 1000) типа type с параметрами payload и возвращает результат по каждой. Payload проверяется по JSON Schema типа задачи",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Создать несколько задач",
+                "parameters": [
+                    {
+                        "description": "Число, тип и параметры задач",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/phttp.BatchCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/phttp.BulkItemV2"
+                                            }
+                                        },
+                                        "meta": {
+                                            "$ref": "#/definitions/phttp.BulkMeta"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышена квота арендатора",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tasks/bulk-cancel": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Отменяет задачи по списку ID или по фильтру (не больше 1000) и возвращает результат по каждой",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Отменить несколько задач",
+                "parameters": [
+                    {
+                        "description": "Список ID или фильтр",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/phttp.BulkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/phttp.BulkItemV2"
+                                            }
+                                        },
+                                        "meta": {
+                                            "$ref": "#/definitions/phttp.BulkMeta"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tasks/bulk-delete": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Удаляет задачи по списку ID или по фильтру (не больше 1000) и возвращает результат по каждой",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Удалить несколько задач",
+                "parameters": [
+                    {
+                        "description": "Список ID или фильтр",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/phttp.BulkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/phttp.BulkItemV2"
+                                            }
+                                        },
+                                        "meta": {
+                                            "$ref": "#/definitions/phttp.BulkMeta"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tasks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Получить задачу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/phttp.TaskV2"
+                                        }
+                                    }
+                                }
+                            ]
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия задачи"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Удалить задачу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ожидаемая версия задачи (ETag)",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия задачи не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tasks/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Прерывает выполнение задачи и возвращает её новое состояние",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Отменить задачу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ожидаемая версия задачи (ETag)",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/phttp.TaskV2"
+                                        }
+                                    }
+                                }
+                            ]
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия задачи"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Задача уже завершена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия задачи не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tasks/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "История статусов задачи",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/phttp.TransitionV2"
+                                            }
+                                        },
+                                        "meta": {
+                                            "$ref": "#/definitions/phttp.ListMeta"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tasks/{id}/rerun": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Создаёт и запускает повтор завершённой задачи с тем же типом. Payload берётся из тела, а без него — из исходной задачи; новый payload проверяется по схеме типа",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Повторить задачу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID исходной задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новый payload",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.RerunRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/phttp.TaskV2"
+                                        }
+                                    }
+                                }
+                            ]
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Адрес новой задачи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Payload не прошёл схему типа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Задача ещё не завершена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышена квота арендатора",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/tasks/{id}/reruns": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Повторы задачи",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID исходной задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/phttp.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/phttp.TaskV2"
+                                            }
+                                        },
+                                        "meta": {
+                                            "$ref": "#/definitions/phttp.ListMeta"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Действие запрещено политикой доступа",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка",
+                        "schema": {
+                            "$ref": "#/definitions/phttp.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "domen.AccessDenial": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "example: task:cancel",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "example: task was created by alice; roles [user] may task:cancel only own tasks",
+                    "type": "string"
+                },
+                "roles": {
+                    "description": "Roles of the principal the policy evaluated",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "task_id": {
+                    "description": "ID of the task the action was denied on",
+                    "type": "string"
+                }
+            }
+        },
+        "domen.AuditRecord": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "task:cancel"
+                },
+                "client_ip": {
+                    "type": "string",
+                    "example": "10.0.0.7"
+                },
+                "created": {
+                    "description": "ID of the task the action created from target: the new task of task:rerun",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "hash": {
+                    "description": "SHA-256 of this record with an empty hash field, chained through prev_hash",
+                    "type": "string"
+                },
+                "outcome": {
+                    "type": "string",
+                    "enum": [
+                        "success",
+                        "denied",
+                        "failure"
+                    ],
+                    "example": "success"
+                },
+                "prev_hash": {
+                    "description": "Hash of the previous record, hex SHA-256",
+                    "type": "string"
+                },
+                "principal": {
+                    "description": "Empty when authentication is disabled",
+                    "type": "string",
+                    "example": "alice"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "seq": {
+                    "description": "Sequence number of the record in the log, starting at 1",
+                    "type": "integer",
+                    "example": 42
+                },
+                "target": {
+                    "description": "ID of the task; empty when the action failed before a task was chosen",
+                    "type": "string"
+                },
+                "tenant": {
+                    "type": "string",
+                    "example": "team-a"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "domen.AuditVerification": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "First violation of the hash chain",
+                    "type": "string",
+                    "example": "audit log tampered: line 7: record does not match its hash"
+                },
+                "last_hash": {
+                    "description": "Hash of the last valid record. Kept elsewhere, it also reveals a truncated log",
+                    "type": "string"
+                },
+                "records": {
+                    "description": "Number of records verified before the first broken one",
+                    "type": "integer",
+                    "example": 1024
+                },
+                "valid": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "domen.Batch": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "description": "FinishedAt is set once every task of the batch reaches a terminal state",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "task_ids": {
+                    "description": "IDs of the tasks owned by the batch",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "domen.BatchProgress": {
+            "type": "object",
+            "properties": {
+                "counts": {
+                    "description": "Number of member tasks by status",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "done": {
+                    "description": "Number of member tasks in a terminal state, deleted ones included",
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "description": "FinishedAt is set once every task of the batch reaches a terminal state",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "missing": {
+                    "description": "Number of member tasks that were deleted",
+                    "type": "integer"
+                },
+                "progress": {
+                    "description": "Share of done tasks, from 0 to 1\nexample: 0.5",
+                    "type": "number"
+                },
+                "task_ids": {
+                    "description": "IDs of the tasks owned by the batch",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domen.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "JSON Pointer to the invalid field of the request body\nexample: /payload/count",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "domen.Status": {
+            "type": "string",
+            "enum": [
+                "PENDING",
+                "RUNNING",
+                "COMPLETED",
+                "FAILED",
+                "CANCELED"
+            ],
+            "x-enum-varnames": [
+                "StatusPending",
+                "StatusRunning",
+                "StatusCompleted",
+                "StatusFailed",
+                "StatusCancelled"
+            ]
+        },
+        "domen.TaskListItem": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "domen.TaskType": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "description": "example: default",
+                    "type": "string"
+                },
+                "schema": {
+                    "description": "JSON Schema of the task payload",
+                    "type": "object"
+                }
+            }
+        },
+        "domen.Transition": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "from": {
+                    "$ref": "#/definitions/domen.Status"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to": {
+                    "$ref": "#/definitions/domen.Status"
+                }
+            }
+        },
+        "health.CheckResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "name": {
+                    "type": "string",
+                    "example": "repository"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.CheckResult"
+                    }
+                },
+                "state": {
+                    "type": "string",
+                    "example": "ready"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "phttp.AuditPage": {
+            "type": "object",
+            "properties": {
+                "next": {
+                    "description": "Pass as \"after\" to get the next page; absent on the last page",
+                    "type": "integer",
+                    "example": 100
+                },
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domen.AuditRecord"
+                    }
+                }
+            }
+        },
+        "phttp.BatchCreateRequest": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "type": {
+                    "description": "example: default",
+                    "type": "string"
+                }
+            }
+        },
+        "phttp.BatchV2": {
+            "type": "object",
+            "properties": {
+                "counts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "done": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "missing": {
+                    "type": "integer"
+                },
+                "progress": {
+                    "type": "number",
+                    "example": 0.4
+                },
+                "task_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 10
+                }
+            }
+        },
+        "phttp.BuildInfo": {
+            "type": "object",
+            "properties": {
+                "go_version": {
+                    "type": "string",
+                    "example": "go1.24.0"
+                },
+                "modified": {
+                    "type": "boolean"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "github.com/gaz358/myprog/workmate/cmd/server"
+                },
+                "revision": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string",
+                    "example": "(devel)"
+                }
+            }
+        },
+        "phttp.BulkFilter": {
+            "type": "object",
+            "properties": {
+                "created_from": {
+                    "type": "string"
+                },
+                "created_to": {
+                    "type": "string"
+                },
+                "queues": {
+                    "description": "Task types to select",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "report"
+                    ]
+                },
+                "statuses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domen.Status"
+                    },
+                    "example": [
+                        "PENDING"
+                    ]
+                }
+            }
+        },
+        "phttp.BulkItemResult": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code — стабильный код ошибки, как в Problem.",
+                    "type": "string",
+                    "example": "invalid_transition"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
                 },
                 "status": {
                     "$ref": "#/definitions/domen.Status"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
-        "domen.TaskListItem": {
+        "phttp.BulkItemV2": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/phttp.ItemErrorV2"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ok": {
+                    "type": "boolean"
+                },
+                "task": {
+                    "$ref": "#/definitions/phttp.TaskV2"
+                }
+            }
+        },
+        "phttp.BulkMeta": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "succeeded": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "phttp.BulkRequest": {
+            "type": "object",
+            "properties": {
+                "filter": {
+                    "$ref": "#/definitions/phttp.BulkFilter"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "phttp.BulkResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/phttp.BulkItemResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "phttp.CreateTaskRequest": {
+            "type": "object",
+            "properties": {
+                "payload": {
+                    "type": "object"
+                },
+                "type": {
+                    "description": "example: default",
+                    "type": "string"
+                }
+            }
+        },
+        "phttp.Envelope": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "meta": {}
+            }
+        },
+        "phttp.ItemErrorV2": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "invalid_transition"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "invalid status transition COMPLETED -\u003e CANCELED"
+                }
+            }
+        },
+        "phttp.ListMeta": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "phttp.LogLevels": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "string",
+                    "example": "info"
+                },
+                "loggers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "phttp.MemoryInfo": {
+            "type": "object",
+            "properties": {
+                "gc_pause_total_ns": {
+                    "type": "integer"
+                },
+                "heap_alloc": {
+                    "type": "integer"
+                },
+                "heap_inuse": {
+                    "type": "integer"
+                },
+                "heap_objects": {
+                    "type": "integer"
+                },
+                "num_gc": {
+                    "type": "integer"
+                },
+                "sys": {
+                    "type": "integer"
+                },
+                "total_alloc": {
+                    "type": "integer"
+                }
+            }
+        },
+        "phttp.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "not_found"
+                },
+                "denial": {
+                    "description": "Denial explains why the access policy denied a forbidden action",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domen.AccessDenial"
+                        }
+                    ]
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "task 42: not found"
+                },
+                "errors": {
+                    "description": "Errors lists invalid fields for invalid_argument problems",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domen.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/tasks/42"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "/problems/not_found"
+                }
+            }
+        },
+        "phttp.RerunRequest": {
+            "type": "object",
+            "properties": {
+                "payload": {
+                    "description": "Replaces the payload of the original task; checked against the schema of its type",
+                    "type": "object"
+                }
+            }
+        },
+        "phttp.RuntimeInfo": {
+            "type": "object",
+            "properties": {
+                "build": {
+                    "$ref": "#/definitions/phttp.BuildInfo"
+                },
+                "gomaxprocs": {
+                    "type": "integer",
+                    "example": 8
+                },
+                "goroutines": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "memory": {
+                    "$ref": "#/definitions/phttp.MemoryInfo"
+                },
+                "num_cpu": {
+                    "type": "integer",
+                    "example": 8
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "uptime": {
+                    "type": "string",
+                    "example": "1h2m3s"
+                },
+                "uptime_seconds": {
+                    "type": "integer",
+                    "example": 3723
+                }
+            }
+        },
+        "phttp.SetLogLevelRequest": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "string",
+                    "example": "debug"
+                },
+                "logger": {
+                    "type": "string",
+                    "example": "http"
+                }
+            }
+        },
+        "phttp.TaskV1": {
             "type": "object",
             "properties": {
+                "batch_id": {
+                    "description": "BatchID is the batch the task belongs to, empty for standalone tasks",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
                 "duration": {
+                    "description": "Duration of the task execution",
+                    "type": "string",
+                    "example": "3m0s"
+                },
+                "ended_at": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
+                "payload": {
+                    "description": "Payload is validated against the JSON Schema of the task type",
+                    "type": "object"
+                },
+                "rerun_of": {
+                    "description": "RerunOf is the ID of the task this one re-runs, empty for original tasks",
+                    "type": "string"
+                },
+                "result": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
                 "status": {
+                    "$ref": "#/definitions/domen.Status"
+                },
+                "type": {
+                    "description": "Type is the registered task type, see GET /task-types",
+                    "type": "string",
+                    "example": "default"
+                },
+                "version": {
+                    "description": "Version is incremented on every update and is used for optimistic locking",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "phttp.TaskV2": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "description": "CreatedBy — ID ключа или пользователя, создавшего задачу",
+                    "type": "string",
+                    "example": "ci-pipeline"
+                },
+                "duration": {
+                    "description": "Duration — время выполнения: до конца задачи или до момента ответа,\nесли задача ещё идёт. До старта равно нулю.",
+                    "type": "string",
+                    "example": "3m0s"
+                },
+                "duration_ms": {
+                    "type": "integer",
+                    "example": 180000
+                },
+                "ended_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f0c7f9e-6a43-4a57-9d6b-6f1f4c0b2a11"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "rerun_of": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "string",
+                    "example": "OK"
+                },
+                "started_at": {
                     "type": "string"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domen.Status"
+                        }
+                    ],
+                    "example": "RUNNING"
+                },
+                "tenant_id": {
+                    "description": "TenantID — арендатор, которому принадлежит задача",
+                    "type": "string",
+                    "example": "team-a"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 2
                 }
             }
         },
-        "phttp.ErrorResponse": {
+        "phttp.TransitionV2": {
             "type": "object",
             "properties": {
-                "message": {
+                "at": {
+                    "type": "string"
+                },
+                "from": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domen.Status"
+                        }
+                    ],
+                    "example": "PENDING"
+                },
+                "reason": {
                     "type": "string",
-                    "example": "something went wrong"
+                    "example": "started"
+                },
+                "to": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domen.Status"
+                        }
+                    ],
+                    "example": "RUNNING"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKey": {
+            "description": "\"Bearer \u003cAPI-ключ или JWT\u003e\" или заголовок X-API-Key. Без настроенных ключей и JWKS не требуется",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
-	Version:          "1.0",
+	Version:          "2.0",
 	Host:             "localhost:8080",
 	BasePath:         "/",
 	Schemes:          []string{},
@@ -1,21 +1,42 @@
 // @title           Tasks API
-// @version         1.0
+// @version         2.0
 // @description     Сервис управления задачами
 // @host            localhost:8080
 // @BasePath        /
+//
+// @securityDefinitions.apikey  ApiKey
+// @in                          header
+// @name                        Authorization
+// @description                 "Bearer <API-ключ или JWT>" или заголовок X-API-Key. Без настроенных ключей и JWKS не требуется
 package main
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gaz358/myprog/workmate/config"
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/internal/audit"
+	"github.com/gaz358/myprog/workmate/internal/auth"
 	"github.com/gaz358/myprog/workmate/internal/delivery/phttp"
+	"github.com/gaz358/myprog/workmate/internal/health"
+	"github.com/gaz358/myprog/workmate/internal/metrics"
+	"github.com/gaz358/myprog/workmate/internal/policy"
+	"github.com/gaz358/myprog/workmate/internal/tracing"
 	"github.com/gaz358/myprog/workmate/pkg/logger"
 	"github.com/gaz358/myprog/workmate/repository/memory"
+	sqlrepo "github.com/gaz358/myprog/workmate/repository/sql"
 	"github.com/gaz358/myprog/workmate/usecase"
 
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -23,6 +44,8 @@ import (
 	_ "github.com/gaz358/myprog/workmate/cmd/server/docs"
 
 	"github.com/go-chi/chi/v5"
+
+	_ "modernc.org/sqlite"
 )
 
 func main() {
@@ -31,12 +54,164 @@ func main() {
 	logger.SetLevel(parseLogLevel(cfg.LogLevel))
 	logg := logger.Global().Named("main")
 
-	repo := memory.NewInMemoryRepo()
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingExporter, cfg.TracingEndpoint)
+	if err != nil {
+		logg.Fatalw("failed to init tracing", "exporter", cfg.TracingExporter, "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logg.Errorw("failed to flush traces", "error", err)
+		}
+	}()
+
+	// rawRepo — хранилище без обёрток метрик и трейсинга: для проб и
+	// наблюдателя метрик, которые не должны попадать в статистику запросов
+	rawRepo, batches, closeRepo, err := newRepository(cfg)
+	if err != nil {
+		logg.Fatalw("failed to init repository", "storage", cfg.Storage, "error", err)
+	}
+	defer closeRepo()
+
+	probes := health.NewRegistry()
+	probes.RegisterReadiness("repository", health.Repository(rawRepo))
+
+	m := metrics.New()
+	m.RegisterTaskStates(rawRepo)
+	metricsCtx, stopMetrics := context.WithCancel(logger.ToContext(context.Background(), logger.Global().Named("metrics")))
+	defer stopMetrics()
+	metricsWatcher := &health.Tracker{}
+	probes.RegisterLiveness("metrics-watcher", metricsWatcher.Check)
+	go func() {
+		err := m.Run(metricsCtx, rawRepo)
+		if err != nil {
+			logg.Errorw("metrics watcher stopped", "error", err)
+		}
+		metricsWatcher.Stop(err)
+	}()
+	repo := m.InstrumentRepository(tracing.InstrumentRepository(rawRepo))
+
 	uc := usecase.NewTaskUseCase(repo, cfg.TaskDuration)
+	tenantQuotas, err := usecase.ParseQuotas(cfg.TenantQuotas)
+	if err != nil {
+		logg.Fatalw("failed to parse tenant quotas", "error", err)
+	}
+	uc.SetQuotas(usecase.Quotas{
+		Default: usecase.Quota{
+			MaxPending: cfg.TenantMaxPending,
+			MaxRunning: cfg.TenantMaxRunning,
+			PerMinute:  cfg.TenantTasksPerMinute,
+		},
+		Tenants: tenantQuotas,
+	})
+	// Файлы, которые перечитываются по SIGHUP
+	reloaders := make(map[string]func() error)
+	if cfg.PolicyFile != "" {
+		pol, err := policy.Load(cfg.PolicyFile)
+		if err != nil {
+			logg.Fatalw("failed to load access policy", "file", cfg.PolicyFile, "error", err)
+		}
+		uc.SetPolicy(pol)
+		reloaders["access policy"] = pol.Reload
+	}
+	var auditLog phttp.AuditReader
+	if cfg.AuditFile != "" {
+		fileLog, err := audit.Open(cfg.AuditFile)
+		if err != nil {
+			logg.Fatalw("failed to open audit log", "file", cfg.AuditFile, "error", err)
+		}
+		defer fileLog.Close()
+		// Испорченный журнал не мешает работе, но о нём нужно знать сразу
+		if v, err := fileLog.Verify(); err != nil || !v.Valid {
+			logg.Errorw("audit log verification failed", "file", cfg.AuditFile, "error", err, "violation", v.Error)
+		}
+		uc.SetAuditLog(fileLog)
+		auditLog = fileLog
+	}
+	if err := loadTaskTypes(uc, cfg.TaskTypesDir); err != nil {
+		logg.Fatalw("failed to load task types", "dir", cfg.TaskTypesDir, "error", err)
+	}
 	handler := phttp.NewHandler(uc)
 
+	batchUC := usecase.NewBatchUseCase(uc, batches)
+	batchUC.OnFinished(func(ctx context.Context, p *domen.BatchProgress) {
+		logg.Infow("batch finished", "batch_id", p.ID, "counts", p.Counts, "missing", p.Missing)
+	})
+	watchCtx, stopWatch := context.WithCancel(logger.ToContext(context.Background(), logger.Global().Named("batch")))
+	defer stopWatch()
+	batchWatcher := &health.Tracker{}
+	probes.RegisterLiveness("batch-watcher", batchWatcher.Check)
+	go func() {
+		err := batchUC.Run(watchCtx)
+		if err != nil {
+			logg.Errorw("batch watcher stopped", "error", err)
+		}
+		batchWatcher.Stop(err)
+	}()
+
+	batchHandler := phttp.NewBatchHandler(batchUC)
+
+	keys, err := newKeyStore(cfg)
+	if err != nil {
+		logg.Fatalw("failed to load api keys", "error", err)
+	}
+	var keyAuth, tokenAuth auth.Authenticator
+	if keys.Len() > 0 {
+		keyAuth = keys
+	}
+	if cfg.JWTJWKSFile != "" {
+		jwtAuth, err := auth.NewJWTAuthenticator(auth.JWTConfig{
+			JWKSFile:  cfg.JWTJWKSFile,
+			Issuer:    cfg.JWTIssuer,
+			Audience:  cfg.JWTAudience,
+			ClockSkew: cfg.JWTClockSkew,
+		})
+		if err != nil {
+			logg.Fatalw("failed to load JWKS", "file", cfg.JWTJWKSFile, "error", err)
+		}
+		tokenAuth = jwtAuth
+		reloaders["JWKS"] = jwtAuth.Reload
+	}
+	if len(reloaders) > 0 {
+		go reloadOnHangup(reloaders, logg)
+	}
+	// Без ключей и JWKS API открыт, как раньше; /admin тогда не подключается
+	authEnabled := keyAuth != nil || tokenAuth != nil
+	authenticate := func(next http.Handler) http.Handler { return next }
+	if authEnabled {
+		authenticate = phttp.Authenticate(auth.ByFormat(keyAuth, tokenAuth))
+	} else {
+		logg.Warnw("API authentication disabled: no API keys or JWKS configured")
+	}
+
 	r := chi.NewRouter()
-	r.Mount("/tasks", handler.Routes())
+	r.Use(
+		phttp.Trace,
+		phttp.RequestID,
+		phttp.AccessLog(logger.Global().Named("http")),
+		phttp.Instrument(m),
+		phttp.Recoverer,
+		phttp.WithTimeouts(phttp.Timeouts{Default: cfg.HTTPTimeout, Bulk: cfg.HTTPBulkTimeout}),
+	)
+	r.Handle("/metrics", m.Handler())
+	probeHandler := phttp.NewHealthHandler(probes)
+	r.Get("/livez", probeHandler.Livez)
+	r.Get("/readyz", probeHandler.Readyz)
+	if authEnabled {
+		r.With(authenticate, phttp.RequireScope(domen.ScopeAdmin)).
+			Mount("/admin", phttp.NewAdminHandler(cfg.Values(), cfg.AdminPprof, auditLog).Routes())
+	}
+	r.Route("/v1", func(r chi.Router) {
+		r.Use(authenticate)
+		phttp.MountV1(r, handler, batchHandler)
+	})
+	r.With(authenticate).Mount("/v2", phttp.NewV2Handler(uc, batchUC).Routes())
+	// Пути без версии остаются синонимами /v1 для старых клиентов
+	r.Group(func(r chi.Router) {
+		r.Use(phttp.Deprecated("/v1"), authenticate)
+		phttp.MountV1(r, handler, batchHandler)
+	})
 	r.Get("/swagger/*", httpSwagger.WrapHandler)
 
 	srv := &http.Server{
@@ -46,17 +221,32 @@ func main() {
 	}
 
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		logg.Fatalw("failed to listen", "addr", srv.Addr, "error", err)
+	}
 	go func() {
 		logg.Infow("Starting HTTP server", "addr", srv.Addr)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logg.Fatalw("ListenAndServe failed", "error", err)
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logg.Fatalw("Serve failed", "error", err)
+		}
+	}()
+	// Порт уже слушается, но запросы на экземпляр направляются только
+	// после того, как хранилище ответило и наблюдатель пакетов подписался
+	go func() {
+		if err := awaitStartup(cfg.StartupTimeout, health.Repository(rawRepo), batchUC.Watching()); err != nil {
+			logg.Fatalw("startup failed", "timeout", cfg.StartupTimeout, "error", err)
 		}
+		probes.MarkReady()
+		logg.Infow("Server is ready")
 	}()
 
 	<-quit
 	logg.Infow("Shutting down server…")
+	probes.Drain()
+	time.Sleep(cfg.DrainDelay)
 
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
@@ -66,6 +256,135 @@ func main() {
 	logg.Infow("Server exited gracefully")
 }
 
+// awaitStartup ждёт, пока наблюдатель пакетов подпишется на изменения и
+// хранилище пройдёт проверку, но не дольше timeout.
+func awaitStartup(timeout time.Duration, repoCheck health.Check, watching <-chan struct{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	select {
+	case <-watching:
+	case <-ctx.Done():
+		return fmt.Errorf("batch watcher did not start: %w", ctx.Err())
+	}
+	for {
+		err := repoCheck(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("repository is not ready: %w", err)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// newRepository выбирает хранилище задач и пакетов по cfg.Storage.
+func newRepository(cfg *config.Config) (domen.TaskRepository, domen.BatchRepository, func(), error) {
+	switch cfg.Storage {
+	case "memory":
+		return memory.NewInMemoryRepo(), memory.NewBatchRepo(), func() {}, nil
+	case "sharded":
+		return memory.NewShardedRepo(memory.DefaultShards), memory.NewBatchRepo(), func() {}, nil
+	case "sql":
+		var dialect sqlrepo.Dialect
+		switch cfg.DBDriver {
+		case "sqlite":
+			dialect = sqlrepo.SQLite
+		case "postgres", "pgx":
+			dialect = sqlrepo.Postgres
+		default:
+			return nil, nil, nil, fmt.Errorf("DB_DRIVER %q is not supported, use sqlite or postgres", cfg.DBDriver)
+		}
+		// В сервер собран только драйвер SQLite. Диалект Postgres пакет
+		// repository/sql поддерживает, но без драйвера sql.Open выдал бы
+		// невнятную ошибку
+		if !slices.Contains(sql.Drivers(), cfg.DBDriver) {
+			return nil, nil, nil, fmt.Errorf("DB_DRIVER %q is not built into this server, available drivers: %s",
+				cfg.DBDriver, strings.Join(sql.Drivers(), ", "))
+		}
+		db, err := sql.Open(cfg.DBDriver, cfg.DBDSN)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if cfg.DBDriver == "sqlite" {
+			db.SetMaxOpenConns(1)
+		}
+		repo := sqlrepo.NewSQLRepo(db, dialect)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := repo.Migrate(ctx); err != nil {
+			_ = db.Close()
+			return nil, nil, nil, err
+		}
+		return repo, repo.Batches(), func() { _ = db.Close() }, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown storage %q", cfg.Storage)
+	}
+}
+
+// newKeyStore собирает API-ключи из API_KEYS, API_KEYS_FILE и ADMIN_TOKEN.
+func newKeyStore(cfg *config.Config) (*auth.KeyStore, error) {
+	keys, err := auth.ParseKeys(cfg.APIKeys)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.APIKeysFile != "" {
+		fileKeys, err := auth.LoadKeysFile(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+	if cfg.AdminToken != "" {
+		keys = append(keys, auth.Key{ID: "admin", SHA256: auth.HashKey(cfg.AdminToken), Scopes: []string{domen.ScopeAdmin}})
+	}
+	return auth.NewKeyStore(keys)
+}
+
+// reloadOnHangup перечитывает JWKS и политику доступа по SIGHUP, чтобы
+// ротация ключей шлюза и правка ролей не требовали перезапуска.
+func reloadOnHangup(reloaders map[string]func() error, logg logger.TypeOfLogger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		for name, reload := range reloaders {
+			if err := reload(); err != nil {
+				logg.Errorw("failed to reload, keeping previous version", "file", name, "error", err)
+				continue
+			}
+			logg.Infow("reloaded", "file", name)
+		}
+	}
+}
+
+// loadTaskTypes регистрирует типы задач из файлов <name>.json каталога dir.
+// Файл содержит {"description": "...", "schema": {...}}.
+func loadTaskTypes(uc *usecase.TaskUseCase, dir string) error {
+	if dir == "" {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		var t domen.TaskType
+		if err := json.Unmarshal(data, &t); err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+		t.Name = strings.TrimSuffix(filepath.Base(f), ".json")
+		if err := uc.RegisterTaskType(t); err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+	}
+	return nil
+}
+
 func parseLogLevel(level string) logger.LogLevel {
 	switch level {
 	case "debug":
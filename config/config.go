@@ -12,6 +12,10 @@ import (
 const (
 	defaultTaskDuration    = 60 * time.Second
 	defaultShutdownTimeout = 5 * time.Second
+	defaultStartupTimeout  = 30 * time.Second
+	defaultHTTPTimeout     = 10 * time.Second
+	defaultHTTPBulkTimeout = 60 * time.Second
+	defaultJWTClockSkew    = 30 * time.Second
 )
 
 type Config struct {
@@ -19,28 +23,186 @@ type Config struct {
 	LogLevel        string
 	TaskDuration    time.Duration
 	ShutdownTimeout time.Duration
+	// StartupTimeout ограничивает ожидание хранилища и наблюдателя пакетов
+	// при запуске: до их готовности readiness проваливается.
+	StartupTimeout time.Duration
+	// DrainDelay — сколько ждать после провала readiness перед остановкой
+	// сервера, чтобы балансировщик успел убрать экземпляр.
+	DrainDelay time.Duration
+
+	// HTTPTimeout ограничивает обработку одиночных запросов к API,
+	// HTTPBulkTimeout — списков, пакетов и массовых операций.
+	HTTPTimeout     time.Duration
+	HTTPBulkTimeout time.Duration
+
+	// Storage — хранилище задач: memory, sharded или sql.
+	Storage string
+	// DBDriver — драйвер database/sql для STORAGE=sql: sqlite или postgres.
+	DBDriver string
+	DBDSN    string
+
+	// TaskTypesDir — каталог с описаниями типов задач <name>.json.
+	// Пусто — доступен только тип default.
+	TaskTypesDir string
+
+	// TracingExporter — куда отправлять трейсы: none, stdout или otlp.
+	TracingExporter string
+	// TracingEndpoint — host:port OTLP/HTTP-коллектора для TracingExporter=otlp.
+	TracingEndpoint string
+
+	// APIKeys — API-ключи вида "id:sha256:scope,scope;...", APIKeysFile —
+	// JSON-файл с ключами. Без ключей аутентификация выключена.
+	APIKeys     string
+	APIKeysFile string
+	// JWTJWKSFile — локальный JWKS для проверки JWT шлюза; пусто — JWT
+	// не принимаются. Файл перечитывается по SIGHUP.
+	JWTJWKSFile  string
+	JWTIssuer    string
+	JWTAudience  string
+	JWTClockSkew time.Duration
+	// TenantMaxPending, TenantMaxRunning и TenantTasksPerMinute — квота
+	// арендатора по умолчанию, 0 — без ограничения. TenantQuotas
+	// переопределяет её для отдельных арендаторов:
+	// "tenant:pending,running,per_minute;...".
+	TenantMaxPending     int
+	TenantMaxRunning     int
+	TenantTasksPerMinute int
+	TenantQuotas         string
+	// PolicyFile — JSON-файл политики доступа к задачам (роли и владение
+	// задачами); пусто — права определяются только областями доступа.
+	// Файл перечитывается по SIGHUP.
+	PolicyFile string
+	// AuditFile — файл журнала аудита (NDJSON с цепочкой хешей); пусто —
+	// аудит выключен. Журнал доступен в /admin/audit.
+	AuditFile string
+	// AdminToken — секрет ключа "admin" с областью admin, для доступа к /admin.
+	AdminToken string
+	// AdminPprof подключает net/http/pprof в /admin/debug/pprof.
+	AdminPprof bool
 }
 
+// redacted заменяет значения секретов в Values.
+const redacted = "[REDACTED]"
+
 func Load() *Config {
 	if err := godotenv.Load(); err != nil {
 		log.Println("[config] .env не найден, используются переменные окружения по умолчанию")
 	}
 
 	cfg := &Config{
-		Port:            getEnv("PORT", "8080"),
-		LogLevel:        getEnv("LOG_LEVEL", "info"),
-		TaskDuration:    getEnvAsDuration("TASK_DURATION", defaultTaskDuration),
-		ShutdownTimeout: getEnvAsDuration("SHUTDOWN_TIMEOUT", defaultShutdownTimeout),
+		Port:                 getEnv("PORT", "8080"),
+		LogLevel:             getEnv("LOG_LEVEL", "info"),
+		TaskDuration:         getEnvAsDuration("TASK_DURATION", defaultTaskDuration),
+		ShutdownTimeout:      getEnvAsDuration("SHUTDOWN_TIMEOUT", defaultShutdownTimeout),
+		StartupTimeout:       getEnvAsDuration("STARTUP_TIMEOUT", defaultStartupTimeout),
+		DrainDelay:           getEnvAsDuration("SHUTDOWN_DRAIN_DELAY", 0),
+		HTTPTimeout:          getEnvAsDuration("HTTP_TIMEOUT", defaultHTTPTimeout),
+		HTTPBulkTimeout:      getEnvAsDuration("HTTP_BULK_TIMEOUT", defaultHTTPBulkTimeout),
+		Storage:              getEnv("STORAGE", "memory"),
+		DBDriver:             getEnv("DB_DRIVER", "sqlite"),
+		DBDSN:                getEnv("DB_DSN", "file:tasks.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"),
+		TaskTypesDir:         getEnv("TASK_TYPES_DIR", ""),
+		TracingExporter:      getEnv("TRACING_EXPORTER", "none"),
+		TracingEndpoint:      getEnv("TRACING_OTLP_ENDPOINT", "localhost:4318"),
+		APIKeys:              getEnv("API_KEYS", ""),
+		APIKeysFile:          getEnv("API_KEYS_FILE", ""),
+		JWTJWKSFile:          getEnv("JWT_JWKS_FILE", ""),
+		JWTIssuer:            getEnv("JWT_ISSUER", ""),
+		JWTAudience:          getEnv("JWT_AUDIENCE", ""),
+		JWTClockSkew:         getEnvAsDuration("JWT_CLOCK_SKEW", defaultJWTClockSkew),
+		TenantMaxPending:     getEnvAsInt("TENANT_MAX_PENDING", 0),
+		TenantMaxRunning:     getEnvAsInt("TENANT_MAX_RUNNING", 0),
+		TenantTasksPerMinute: getEnvAsInt("TENANT_TASKS_PER_MINUTE", 0),
+		TenantQuotas:         getEnv("TENANT_QUOTAS", ""),
+		PolicyFile:           getEnv("POLICY_FILE", ""),
+		AuditFile:            getEnv("AUDIT_FILE", ""),
+		AdminToken:           getEnv("ADMIN_TOKEN", ""),
+		AdminPprof:           getEnvAsBool("ADMIN_PPROF", false),
 	}
 
 	log.Printf("[config] PORT=%s", cfg.Port)
 	log.Printf("[config] LOG_LEVEL=%s", cfg.LogLevel)
 	log.Printf("[config] TASK_DURATION=%s", cfg.TaskDuration)
-	log.Printf("[config] SHUTDOWN_TIMEOUT=%s", cfg.ShutdownTimeout)
+	log.Printf("[config] STARTUP_TIMEOUT=%s", cfg.StartupTimeout)
+	log.Printf("[config] SHUTDOWN_TIMEOUT=%s SHUTDOWN_DRAIN_DELAY=%s", cfg.ShutdownTimeout, cfg.DrainDelay)
+	log.Printf("[config] HTTP_TIMEOUT=%s HTTP_BULK_TIMEOUT=%s", cfg.HTTPTimeout, cfg.HTTPBulkTimeout)
+	log.Printf("[config] STORAGE=%s", cfg.Storage)
+	if cfg.Storage == "sql" {
+		log.Printf("[config] DB_DRIVER=%s", cfg.DBDriver)
+	}
+	if cfg.TaskTypesDir != "" {
+		log.Printf("[config] TASK_TYPES_DIR=%s", cfg.TaskTypesDir)
+	}
+	log.Printf("[config] TRACING_EXPORTER=%s", cfg.TracingExporter)
+	if cfg.TracingExporter == "otlp" {
+		log.Printf("[config] TRACING_OTLP_ENDPOINT=%s", cfg.TracingEndpoint)
+	}
+	if cfg.APIKeysFile != "" {
+		log.Printf("[config] API_KEYS_FILE=%s", cfg.APIKeysFile)
+	}
+	if cfg.JWTJWKSFile != "" {
+		log.Printf("[config] JWT_JWKS_FILE=%s JWT_ISSUER=%s JWT_AUDIENCE=%s JWT_CLOCK_SKEW=%s",
+			cfg.JWTJWKSFile, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTClockSkew)
+	}
+	log.Printf("[config] TENANT_MAX_PENDING=%d TENANT_MAX_RUNNING=%d TENANT_TASKS_PER_MINUTE=%d",
+		cfg.TenantMaxPending, cfg.TenantMaxRunning, cfg.TenantTasksPerMinute)
+	if cfg.TenantQuotas != "" {
+		log.Printf("[config] TENANT_QUOTAS=%s", cfg.TenantQuotas)
+	}
+	if cfg.PolicyFile != "" {
+		log.Printf("[config] POLICY_FILE=%s", cfg.PolicyFile)
+	}
+	if cfg.AuditFile != "" {
+		log.Printf("[config] AUDIT_FILE=%s", cfg.AuditFile)
+	}
+	log.Printf("[config] ADMIN=%t ADMIN_PPROF=%t", cfg.AdminToken != "", cfg.AdminPprof)
 
 	return cfg
 }
 
+// Values возвращает действующую конфигурацию по именам переменных окружения.
+// Секреты (ADMIN_TOKEN, хеши API_KEYS и DB_DSN, который может содержать
+// пароль) заменены на [REDACTED], незаданные — пустые.
+func (c *Config) Values() map[string]string {
+	return map[string]string{
+		"PORT":                    c.Port,
+		"LOG_LEVEL":               c.LogLevel,
+		"TASK_DURATION":           c.TaskDuration.String(),
+		"STARTUP_TIMEOUT":         c.StartupTimeout.String(),
+		"SHUTDOWN_TIMEOUT":        c.ShutdownTimeout.String(),
+		"SHUTDOWN_DRAIN_DELAY":    c.DrainDelay.String(),
+		"HTTP_TIMEOUT":            c.HTTPTimeout.String(),
+		"HTTP_BULK_TIMEOUT":       c.HTTPBulkTimeout.String(),
+		"STORAGE":                 c.Storage,
+		"DB_DRIVER":               c.DBDriver,
+		"DB_DSN":                  secret(c.DBDSN),
+		"TASK_TYPES_DIR":          c.TaskTypesDir,
+		"TRACING_EXPORTER":        c.TracingExporter,
+		"TRACING_OTLP_ENDPOINT":   c.TracingEndpoint,
+		"API_KEYS":                secret(c.APIKeys),
+		"API_KEYS_FILE":           c.APIKeysFile,
+		"JWT_JWKS_FILE":           c.JWTJWKSFile,
+		"JWT_ISSUER":              c.JWTIssuer,
+		"JWT_AUDIENCE":            c.JWTAudience,
+		"JWT_CLOCK_SKEW":          c.JWTClockSkew.String(),
+		"TENANT_MAX_PENDING":      strconv.Itoa(c.TenantMaxPending),
+		"TENANT_MAX_RUNNING":      strconv.Itoa(c.TenantMaxRunning),
+		"TENANT_TASKS_PER_MINUTE": strconv.Itoa(c.TenantTasksPerMinute),
+		"TENANT_QUOTAS":           c.TenantQuotas,
+		"POLICY_FILE":             c.PolicyFile,
+		"AUDIT_FILE":              c.AuditFile,
+		"ADMIN_TOKEN":             secret(c.AdminToken),
+		"ADMIN_PPROF":             strconv.FormatBool(c.AdminPprof),
+	}
+}
+
+func secret(v string) string {
+	if v == "" {
+		return ""
+	}
+	return redacted
+}
+
 func getEnv(key string, def string) string {
 	val := os.Getenv(key)
 	if val == "" {
@@ -61,3 +223,29 @@ func getEnvAsDuration(key string, def time.Duration) time.Duration {
 	}
 	return time.Duration(i) * time.Second
 }
+
+func getEnvAsInt(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	i, err := strconv.Atoi(val)
+	if err != nil || i < 0 {
+		log.Printf("[config] неверное значение %s=%q, используется по умолчанию: %d", key, val, def)
+		return def
+	}
+	return i
+}
+
+func getEnvAsBool(key string, def bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Printf("[config] неверное значение %s=%q: %v, используется по умолчанию: %v", key, val, err, def)
+		return def
+	}
+	return b
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_ValuesRedactsSecrets(t *testing.T) {
+	c := &Config{
+		Port:       "8080",
+		DBDSN:      "postgres://app:hunter2@db/tasks",
+		APIKeys:    "ci:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08:tasks:read",
+		AdminToken: "admin-secret",
+	}
+	values := c.Values()
+	for _, key := range []string{"DB_DSN", "API_KEYS", "ADMIN_TOKEN"} {
+		assert.Equal(t, redacted, values[key], key)
+	}
+	assert.Equal(t, "8080", values["PORT"])
+
+	// Незаданный секрет остаётся пустым
+	assert.Empty(t, (&Config{}).Values()["API_KEYS"])
+}
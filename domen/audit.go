@@ -0,0 +1,106 @@
+package domen
+
+import (
+	"context"
+	"time"
+)
+
+// Исходы действий в журнале аудита.
+const (
+	OutcomeSuccess = "success"
+	// OutcomeDenied — действие запрещено политикой доступа.
+	OutcomeDenied = "denied"
+	// OutcomeFailure — действие разрешено, но не выполнено.
+	OutcomeFailure = "failure"
+)
+
+// swagger:model AuditRecord
+type AuditRecord struct {
+	// Sequence number of the record in the log, starting at 1
+	Seq  int64     `json:"seq" example:"42"`
+	Time time.Time `json:"time"`
+	// Empty when authentication is disabled
+	Principal string `json:"principal,omitempty" example:"alice"`
+	Tenant    string `json:"tenant,omitempty" example:"team-a"`
+	Action    string `json:"action" example:"task:cancel"`
+	// ID of the task; empty when the action failed before a task was chosen
+	Target string `json:"target,omitempty"`
+	// ID of the task the action created from target: the new task of task:rerun
+	Created   string `json:"created,omitempty"`
+	Outcome   string `json:"outcome" example:"success" enums:"success,denied,failure"`
+	Error     string `json:"error,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	ClientIP  string `json:"client_ip,omitempty" example:"10.0.0.7"`
+	// Hash of the previous record, hex SHA-256
+	PrevHash string `json:"prev_hash"`
+	// SHA-256 of this record with an empty hash field, chained through prev_hash
+	Hash string `json:"hash"`
+}
+
+// AuditLog — журнал аудита, куда записи только добавляются. Seq, PrevHash
+// и Hash проставляет сам журнал.
+type AuditLog interface {
+	Append(ctx context.Context, r *AuditRecord) error
+}
+
+// swagger:model AuditVerification
+type AuditVerification struct {
+	Valid bool `json:"valid" example:"true"`
+	// Number of records verified before the first broken one
+	Records int64 `json:"records" example:"1024"`
+	// Hash of the last valid record. Kept elsewhere, it also reveals a truncated log
+	LastHash string `json:"last_hash"`
+	// First violation of the hash chain
+	Error string `json:"error,omitempty" example:"audit log tampered: line 7: record does not match its hash"`
+}
+
+// AuditFilter описывает выборку записей журнала аудита. Пустые поля не фильтруют.
+type AuditFilter struct {
+	Principal string
+	Tenant    string
+	Action    string
+	Target    string
+	Outcome   string
+	// From — нижняя граница Time включительно, To — верхняя не включительно.
+	From time.Time
+	To   time.Time
+	// AfterSeq выбирает записи с Seq больше заданного: продолжение выборки.
+	AfterSeq int64
+	// Limit ограничивает число записей, 0 — без ограничения.
+	Limit int
+}
+
+// Match сообщает, подходит ли запись под фильтр (без учёта Limit).
+func (f AuditFilter) Match(r *AuditRecord) bool {
+	switch {
+	case r.Seq <= f.AfterSeq,
+		f.Principal != "" && r.Principal != f.Principal,
+		f.Tenant != "" && r.Tenant != f.Tenant,
+		f.Action != "" && r.Action != f.Action,
+		f.Target != "" && r.Target != f.Target,
+		f.Outcome != "" && r.Outcome != f.Outcome,
+		!f.From.IsZero() && r.Time.Before(f.From),
+		!f.To.IsZero() && !r.Time.Before(f.To):
+		return false
+	}
+	return true
+}
+
+// Origin — откуда пришёл запрос: его ID и адрес клиента.
+type Origin struct {
+	RequestID string
+	ClientIP  string
+}
+
+type originKey struct{}
+
+// WithOrigin возвращает контекст с источником запроса.
+func WithOrigin(ctx context.Context, o Origin) context.Context {
+	return context.WithValue(ctx, originKey{}, o)
+}
+
+// OriginFromContext возвращает источник запроса; вне HTTP-запроса он пуст.
+func OriginFromContext(ctx context.Context) Origin {
+	o, _ := ctx.Value(originKey{}).(Origin)
+	return o
+}
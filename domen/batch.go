@@ -0,0 +1,90 @@
+package domen
+
+import (
+	"context"
+	"time"
+)
+
+// swagger:model Batch
+type Batch struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// IDs of the tasks owned by the batch
+	TaskIDs []string `json:"task_ids"`
+
+	// FinishedAt is set once every task of the batch reaches a terminal state
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+
+	// TenantID is the tenant that owns the batch and its tasks
+	TenantID string `json:"-"`
+}
+
+// Clone возвращает глубокую копию пакета.
+func (b *Batch) Clone() *Batch {
+	c := *b
+	if b.TaskIDs != nil {
+		c.TaskIDs = append([]string(nil), b.TaskIDs...)
+	}
+	return &c
+}
+
+// swagger:model BatchProgress
+type BatchProgress struct {
+	Batch
+
+	// Number of member tasks by status
+	Counts map[Status]int `json:"counts"`
+
+	// Number of member tasks that were deleted
+	Missing int `json:"missing,omitempty"`
+
+	Total int `json:"total"`
+
+	// Number of member tasks in a terminal state, deleted ones included
+	Done int `json:"done"`
+
+	// Share of done tasks, from 0 to 1
+	// example: 0.5
+	Progress float64 `json:"progress"`
+}
+
+// NewBatchProgress считает сводку по задачам пакета. tasks — найденные
+// задачи пакета; те, что в пакете есть, а среди tasks нет, считаются удалёнными.
+func NewBatchProgress(b *Batch, tasks []*Task) *BatchProgress {
+	p := &BatchProgress{
+		Batch:  *b.Clone(),
+		Counts: make(map[Status]int),
+		Total:  len(b.TaskIDs),
+	}
+	for _, t := range tasks {
+		p.Counts[t.Status]++
+		if t.Status.IsTerminal() {
+			p.Done++
+		}
+	}
+	p.Missing = p.Total - len(tasks)
+	p.Done += p.Missing
+	if p.Total > 0 {
+		p.Progress = float64(p.Done) / float64(p.Total)
+	}
+	return p
+}
+
+// Finished сообщает, что все задачи пакета в терминальном состоянии или удалены.
+func (p *BatchProgress) Finished() bool {
+	return p.Done == p.Total
+}
+
+// BatchRepository хранит пакеты задач. Состав пакета не меняется после
+// создания; статусы задач хранит TaskRepository.
+type BatchRepository interface {
+	// Create сохраняет пакет, для занятого ID возвращает ErrAlreadyExists.
+	Create(ctx context.Context, b *Batch) error
+	Get(ctx context.Context, id string) (*Batch, error)
+	// Finish выставляет пакету FinishedAt. Если пакет уже завершён,
+	// возвращает ErrConflict: так завершение обрабатывается ровно один раз.
+	Finish(ctx context.Context, id string, at time.Time) error
+	// ListUnfinished возвращает незавершённые пакеты в порядке CreatedAt.
+	ListUnfinished(ctx context.Context) ([]*Batch, error)
+}
@@ -0,0 +1,32 @@
+package domen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBatchProgress(t *testing.T) {
+	b := &Batch{ID: "b1", TaskIDs: []string{"a", "b", "c", "d"}}
+	tasks := []*Task{
+		{ID: "a", Status: StatusCompleted},
+		{ID: "b", Status: StatusRunning},
+		{ID: "c", Status: StatusCancelled},
+	}
+
+	// Задача "d" удалена и считается законченной
+	p := NewBatchProgress(b, tasks)
+	assert.Equal(t, 4, p.Total)
+	assert.Equal(t, 1, p.Missing)
+	assert.Equal(t, 3, p.Done)
+	assert.Equal(t, map[Status]int{StatusCompleted: 1, StatusRunning: 1, StatusCancelled: 1}, p.Counts)
+	assert.InDelta(t, 0.75, p.Progress, 1e-9)
+	assert.False(t, p.Finished())
+
+	tasks[1].Status = StatusFailed
+	assert.True(t, NewBatchProgress(b, tasks).Finished())
+
+	// Сводка не разделяет состав с пакетом
+	p.TaskIDs[0] = "changed"
+	assert.Equal(t, "a", b.TaskIDs[0])
+}
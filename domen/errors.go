@@ -2,4 +2,29 @@ package domen
 
 import "errors"
 
-var ErrNotFound = errors.New("not found")
+// Ошибки домена. Слои выше оборачивают их через fmt.Errorf("...: %w", err),
+// а HTTP-слой сопоставляет каждой свой статус и стабильный код.
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyExists возвращается при создании задачи с уже занятым ID.
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrConflict возвращается, когда задача была изменена с момента чтения.
+	ErrConflict = errors.New("conflict")
+	// ErrInvalidArgument возвращается, когда запрос к use case некорректен.
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrNotFinished возвращается при попытке повторить незавершённую задачу.
+	ErrNotFinished = errors.New("task is not finished")
+	// ErrPreconditionFailed возвращается, когда не выполнено условие,
+	// заданное вызывающим, например ожидаемая версия задачи.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrRateLimited возвращается, когда превышен лимит запросов или квота.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnavailable возвращается, когда хранилище или другая зависимость
+	// временно недоступна и запрос можно повторить позже.
+	ErrUnavailable = errors.New("unavailable")
+	// ErrUnauthenticated возвращается, когда запрос не содержит действительных
+	// учётных данных.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden возвращается, когда принципалу не хватает прав на действие.
+	ErrForbidden = errors.New("forbidden")
+)
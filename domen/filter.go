@@ -0,0 +1,54 @@
+package domen
+
+import (
+	"slices"
+	"time"
+)
+
+// TaskFilter описывает выборку задач для TaskRepository.Find.
+// Пустые поля не фильтруют. Результат упорядочен по CreatedAt (затем по ID).
+type TaskFilter struct {
+	Statuses []Status
+	// CreatedFrom — нижняя граница CreatedAt включительно.
+	CreatedFrom time.Time
+	// CreatedTo — верхняя граница CreatedAt не включительно.
+	CreatedTo time.Time
+	// NewestFirst меняет порядок на убывающий по CreatedAt.
+	NewestFirst bool
+	// Limit ограничивает число задач, 0 — без ограничения.
+	Limit int
+	// RerunOf выбирает прямые повторы задачи с этим ID.
+	RerunOf string
+	// TenantID выбирает задачи одного арендатора.
+	TenantID string
+	// Types выбирает задачи перечисленных типов — очередей в API.
+	Types []string
+}
+
+// Match сообщает, подходит ли задача под фильтр (без учёта Limit и порядка).
+func (f TaskFilter) Match(t *Task) bool {
+	if f.RerunOf != "" && t.RerunOf != f.RerunOf {
+		return false
+	}
+	if f.TenantID != "" && t.TenantID != f.TenantID {
+		return false
+	}
+	if !f.CreatedFrom.IsZero() && t.CreatedAt.Before(f.CreatedFrom) {
+		return false
+	}
+	if !f.CreatedTo.IsZero() && !t.CreatedAt.Before(f.CreatedTo) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, t.Type) {
+		return false
+	}
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	for _, s := range f.Statuses {
+		if t.Status == s {
+			return true
+		}
+	}
+	return false
+}
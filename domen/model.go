@@ -1,6 +1,9 @@
 package domen
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Status string
 
@@ -25,6 +28,39 @@ type Task struct {
 
 	Status Status `json:"status"`
 	Result string `json:"result,omitempty"`
+
+	// Type is the registered task type, see GET /task-types
+	// example: default
+	Type string `json:"type,omitempty"`
+
+	// Payload is validated against the JSON Schema of the task type
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object"`
+
+	// Version is incremented on every update and is used for optimistic locking
+	// example: 1
+	Version int64 `json:"version"`
+
+	// BatchID is the batch the task belongs to, empty for standalone tasks
+	BatchID string `json:"batch_id,omitempty"`
+
+	// RerunOf is the ID of the task this one re-runs, empty for original tasks
+	RerunOf string `json:"rerun_of,omitempty"`
+
+	// TraceContext is the W3C traceparent of the request that created the task.
+	// The execution span links to it, so async work stays correlated.
+	TraceContext string `json:"-"`
+
+	// CreatedBy is the ID of the API key or user that created the task,
+	// empty when authentication is disabled
+	// example: ci-pipeline
+	CreatedBy string `json:"created_by,omitempty"`
+
+	// TenantID is the tenant that owns the task. Tasks of other tenants
+	// are invisible to its clients. Not part of the v1 JSON
+	TenantID string `json:"-"`
+
+	// History of status transitions, exposed via GET /tasks/{id}/history
+	History []Transition `json:"-"`
 }
 
 // swagger:model TaskListItem
@@ -33,3 +69,17 @@ type TaskListItem struct {
 	Status   string `json:"status"`
 	Duration string `json:"duration,omitempty"`
 }
+
+// Clone возвращает глубокую копию задачи, не разделяющую с оригиналом
+// срезы и карты. Новые поля ссылочных типов нужно копировать здесь же:
+// на Clone опираются репозитории, чтобы владеть своими данными.
+func (t *Task) Clone() *Task {
+	c := *t
+	if t.History != nil {
+		c.History = append([]Transition(nil), t.History...)
+	}
+	if t.Payload != nil {
+		c.Payload = append(json.RawMessage(nil), t.Payload...)
+	}
+	return &c
+}
@@ -0,0 +1,52 @@
+package domen
+
+import "fmt"
+
+// Действия над задачами, которые проверяет Policy.
+const (
+	ActionTaskRead   = "task:read"
+	ActionTaskCreate = "task:create"
+	ActionTaskCancel = "task:cancel"
+	ActionTaskDelete = "task:delete"
+	ActionTaskRerun  = "task:rerun"
+)
+
+// KnownActions перечисляет действия, которые понимает сервис.
+var KnownActions = []string{ActionTaskRead, ActionTaskCreate, ActionTaskCancel, ActionTaskDelete, ActionTaskRerun}
+
+// Policy решает, может ли принципал выполнить действие над задачей.
+// Для создания задачи task равен nil. Отказ — *AccessDeniedError.
+type Policy interface {
+	Authorize(p *Principal, action string, task *Task) error
+}
+
+// swagger:model AccessDenial
+type AccessDenial struct {
+	// example: task:cancel
+	Action string `json:"action"`
+	// ID of the task the action was denied on
+	TaskID string `json:"task_id,omitempty"`
+	// Roles of the principal the policy evaluated
+	Roles []string `json:"roles"`
+	// example: task was created by alice; roles [user] may task:cancel only own tasks
+	Reason string `json:"reason"`
+}
+
+// AccessDeniedError объясняет отказ Policy.
+// errors.Is(err, ErrForbidden) для неё истинно.
+type AccessDeniedError struct {
+	AccessDenial
+	Principal string
+}
+
+func (e *AccessDeniedError) Error() string {
+	target := ""
+	if e.TaskID != "" {
+		target = " on task " + e.TaskID
+	}
+	return fmt.Sprintf("%s: %s may not %s%s: %s", ErrForbidden, e.Principal, e.Action, target, e.Reason)
+}
+
+func (e *AccessDeniedError) Is(target error) bool {
+	return target == ErrForbidden
+}
@@ -0,0 +1,75 @@
+package domen
+
+import "context"
+
+// Области доступа (scopes) клиентов API.
+const (
+	ScopeTasksRead   = "tasks:read"
+	ScopeTasksWrite  = "tasks:write"
+	ScopeTasksCancel = "tasks:cancel"
+	// ScopeAdmin даёт доступ к /admin и включает все остальные области.
+	ScopeAdmin = "admin"
+)
+
+// KnownScopes перечисляет области доступа, которые понимает сервис.
+var KnownScopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeTasksCancel, ScopeAdmin}
+
+// DefaultTenant — арендатор задач, созданных без аутентификации, и
+// принципалов, для которых арендатор не указан.
+const DefaultTenant = "default"
+
+// Principal — аутентифицированный клиент API: API-ключ или пользователь.
+type Principal struct {
+	// ID попадает в Task.CreatedBy
+	ID     string
+	Scopes []string
+	// Tenant — арендатор, которому принадлежат задачи принципала.
+	// Пустой означает DefaultTenant.
+	Tenant string
+	// Roles — роли принципала для Policy: из API-ключа или утверждения
+	// roles токена.
+	Roles []string
+}
+
+// HasScope сообщает, разрешена ли принципалу область scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// TenantID возвращает арендатора принципала.
+func (p *Principal) TenantID() string {
+	if p.Tenant == "" {
+		return DefaultTenant
+	}
+	return p.Tenant
+}
+
+type principalKey struct{}
+
+// WithPrincipal возвращает контекст с аутентифицированным принципалом.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext возвращает принципала запроса. Без аутентификации
+// принципала нет.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// TenantFromContext возвращает арендатора принципала запроса. Без
+// принципала (аутентификация выключена, фоновые задачи сервиса) доступ
+// к данным не ограничен арендатором и ok равен false.
+func TenantFromContext(ctx context.Context) (tenant string, ok bool) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return p.TenantID(), true
+}
@@ -1,9 +1,39 @@
 package domen
 
+import "context"
+
+// TaskRepository хранит задачи. Все методы должны возвращать ctx.Err(),
+// если контекст уже отменён.
 type TaskRepository interface {
-	Create(*Task) error
-	Update(*Task) error
-	Delete(id string) error
-	Get(id string) (*Task, error)
-	List() ([]*Task, error)
+	// Create сохраняет новую задачу и выставляет ей Version = 1.
+	// Если задача с таким ID уже есть, возвращает ErrAlreadyExists.
+	Create(ctx context.Context, t *Task) error
+	// Update сохраняет задачу, только если её Version совпадает с сохранённой
+	// (compare-and-swap), иначе возвращает ErrConflict. При успехе Version
+	// переданной задачи увеличивается.
+	Update(ctx context.Context, t *Task) error
+	// Delete удаляет задачу. Если version != 0, задача удаляется, только
+	// если её Version совпадает с version, иначе возвращается ErrConflict.
+	Delete(ctx context.Context, id string, version int64) error
+	Get(ctx context.Context, id string) (*Task, error)
+	List(ctx context.Context) ([]*Task, error)
+	// Find возвращает задачи, подходящие под фильтр, в порядке CreatedAt.
+	Find(ctx context.Context, filter TaskFilter) ([]*Task, error)
+	// Watch возвращает поток изменений, упорядоченных по ревизии. Канал
+	// закрывается при отмене ctx, а также если получатель не успевает
+	// читать — тогда следует переподписаться с FromRevision последнего
+	// полученного изменения. Если эта ревизия уже вытеснена из журнала,
+	// возвращается ErrRevisionCompacted.
+	Watch(ctx context.Context, filter WatchFilter) (<-chan TaskChange, error)
+}
+
+// BulkRepository — необязательное расширение TaskRepository: пакетные
+// операции за один проход по хранилищу. Срез ошибок выровнен по входу,
+// errs[i] относится к i-му элементу и равен nil при успехе; семантика
+// каждого элемента та же, что у одиночных Create/Update/Delete.
+// Вторая ошибка означает, что пакет не выполнен целиком.
+type BulkRepository interface {
+	CreateMany(ctx context.Context, tasks []*Task) ([]error, error)
+	UpdateMany(ctx context.Context, tasks []*Task) ([]error, error)
+	DeleteMany(ctx context.Context, ids []string) ([]error, error)
 }
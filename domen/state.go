@@ -0,0 +1,66 @@
+package domen
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidTransition — базовая ошибка для недопустимой смены статуса.
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// swagger:model Transition
+type Transition struct {
+	From   Status    `json:"from,omitempty"`
+	To     Status    `json:"to"`
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// TransitionError описывает отклонённый переход и сопоставляется с ErrInvalidTransition через errors.Is.
+type TransitionError struct {
+	From Status
+	To   Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("invalid status transition %s -> %s", e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// allowedTransitions — допустимые переходы. Терминальные статусы переходов не имеют.
+var allowedTransitions = map[Status][]Status{
+	StatusPending: {StatusRunning, StatusCancelled, StatusFailed},
+	StatusRunning: {StatusCompleted, StatusFailed, StatusCancelled},
+}
+
+// IsTerminal сообщает, что из статуса нет переходов.
+func (s Status) IsTerminal() bool {
+	return s == StatusCompleted || s == StatusFailed || s == StatusCancelled
+}
+
+// CanTransition сообщает, разрешён ли переход from -> to.
+func CanTransition(from, to Status) bool {
+	for _, s := range allowedTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition переводит задачу в статус to и записывает переход в History.
+// Недопустимый переход возвращает *TransitionError и не меняет задачу.
+func (t *Task) Transition(to Status, reason string, at time.Time) error {
+	if !CanTransition(t.Status, to) {
+		return &TransitionError{From: t.Status, To: to}
+	}
+	// Полное выражение среза не даёт append писать в массив, общий с другими копиями задачи.
+	n := len(t.History)
+	t.History = append(t.History[:n:n], Transition{From: t.Status, To: to, At: at, Reason: reason})
+	t.Status = to
+	return nil
+}
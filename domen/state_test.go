@@ -0,0 +1,54 @@
+package domen
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTask_Transition(t *testing.T) {
+	now := time.Now()
+	task := &Task{ID: "t1", Status: StatusPending}
+
+	assert.NoError(t, task.Transition(StatusRunning, "started", now))
+	assert.NoError(t, task.Transition(StatusCompleted, "finished", now.Add(time.Second)))
+	assert.Equal(t, StatusCompleted, task.Status)
+
+	assert.Equal(t, []Transition{
+		{From: StatusPending, To: StatusRunning, At: now, Reason: "started"},
+		{From: StatusRunning, To: StatusCompleted, At: now.Add(time.Second), Reason: "finished"},
+	}, task.History)
+
+	// Из терминального статуса переходов нет
+	err := task.Transition(StatusRunning, "restart", now)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	var te *TransitionError
+	assert.True(t, errors.As(err, &te))
+	assert.Equal(t, StatusCompleted, te.From)
+	assert.Equal(t, StatusRunning, te.To)
+	assert.Equal(t, StatusCompleted, task.Status)
+	assert.Len(t, task.History, 2)
+}
+
+func TestCanTransition(t *testing.T) {
+	cases := []struct {
+		from, to Status
+		ok       bool
+	}{
+		{StatusPending, StatusRunning, true},
+		{StatusPending, StatusCancelled, true},
+		{StatusPending, StatusCompleted, false},
+		{StatusRunning, StatusCompleted, true},
+		{StatusRunning, StatusFailed, true},
+		{StatusRunning, StatusCancelled, true},
+		{StatusRunning, StatusPending, false},
+		{StatusCompleted, StatusCancelled, false},
+		{StatusFailed, StatusRunning, false},
+		{StatusCancelled, StatusPending, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.ok, CanTransition(c.from, c.to), "%s -> %s", c.from, c.to)
+	}
+}
@@ -0,0 +1,45 @@
+package domen
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DefaultTaskType — тип задач, созданных без явного типа.
+const DefaultTaskType = "default"
+
+// swagger:model TaskType
+type TaskType struct {
+	// example: default
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// JSON Schema of the task payload
+	Schema json.RawMessage `json:"schema" swaggertype:"object"`
+}
+
+// swagger:model FieldError
+type FieldError struct {
+	// JSON Pointer to the invalid field of the request body
+	// example: /payload/count
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError описывает ошибки проверки запроса по полям.
+// errors.Is(err, ErrInvalidArgument) для неё истинно.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidArgument
+}
@@ -0,0 +1,53 @@
+package domen
+
+import "errors"
+
+// ErrRevisionCompacted возвращается из Watch, если запрошенная ревизия
+// уже вытеснена из журнала изменений и продолжить с неё нельзя.
+var ErrRevisionCompacted = errors.New("revision compacted")
+
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "CREATED"
+	ChangeUpdated ChangeType = "UPDATED"
+	ChangeDeleted ChangeType = "DELETED"
+)
+
+// TaskChange — одно изменение в репозитории. Revision строго возрастает
+// в пределах репозитория. Для ChangeDeleted Task содержит последнее состояние.
+type TaskChange struct {
+	Revision int64      `json:"revision"`
+	Type     ChangeType `json:"type"`
+	Task     *Task      `json:"task"`
+}
+
+// WatchFilter ограничивает поток изменений. Пустые поля не фильтруют.
+type WatchFilter struct {
+	// FromRevision — отдать изменения с ревизией строго больше указанной.
+	// 0 означает только новые изменения; ревизия больше текущей —
+	// ErrInvalidArgument.
+	FromRevision int64
+	TaskID       string
+	Statuses     []Status
+	TenantID     string
+}
+
+// Match сообщает, проходит ли изменение через фильтр (без учёта FromRevision).
+func (f WatchFilter) Match(c TaskChange) bool {
+	if f.TaskID != "" && c.Task.ID != f.TaskID {
+		return false
+	}
+	if f.TenantID != "" && c.Task.TenantID != f.TenantID {
+		return false
+	}
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	for _, s := range f.Statuses {
+		if c.Task.Status == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+// Package audit хранит журнал аудита в локальном файле: одна запись
+// domen.AuditRecord в JSON на строку (NDJSON). Записи связаны в цепочку
+// хешей, поэтому изменение, удаление или вставка записи обнаруживаются
+// при проверке.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// GenesisHash — PrevHash первой записи журнала.
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// maxLine ограничивает длину одной записи при чтении файла.
+const maxLine = 1 << 20
+
+// ErrTampered возвращается, когда цепочка хешей журнала нарушена.
+var ErrTampered = errors.New("audit log tampered")
+
+// logFile — то, что FileLog использует от файла журнала; в тестах
+// подменяется, чтобы сымитировать сбой записи.
+type logFile interface {
+	io.WriteCloser
+	Sync() error
+	Truncate(size int64) error
+	Stat() (os.FileInfo, error)
+}
+
+// FileLog — журнал аудита в файле. Файл открыт только на дозапись;
+// каждая запись сбрасывается на диск до возврата из Append.
+type FileLog struct {
+	path string
+	now  func() time.Time
+
+	mu   sync.Mutex
+	f    logFile
+	size int64
+	seq  int64
+	last string
+}
+
+// Open открывает журнал path, создавая его при необходимости, и продолжает
+// цепочку с последней записи. Целиком журнал проверяет Verify.
+//
+// Недописанная последняя запись (строка без перевода строки) остаётся от
+// сбоя посреди Append, который так и не вернул успех, поэтому Open её
+// отрезает.
+func Open(path string) (*FileLog, error) {
+	l := &FileLog{path: path, now: time.Now, last: GenesisHash}
+	last, size, err := lastRecord(path)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		l.seq, l.last = last.Seq, last.Hash
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err == nil && info.Size() > size {
+		err = f.Truncate(size)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("audit: %s: drop incomplete last record: %w", path, err)
+	}
+	l.f, l.size = f, size
+	return l, nil
+}
+
+// Close закрывает файл журнала.
+func (l *FileLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// Append реализует domen.AuditLog. Пустое r.Time заменяется текущим временем.
+func (l *FileLog) Append(_ context.Context, r *domen.AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r.Time.IsZero() {
+		r.Time = l.now()
+	}
+	// В UTC и без монотонных часов: время должно пережить JSON без изменений,
+	// иначе хеш при проверке не сойдётся
+	r.Time = r.Time.UTC()
+	r.Seq = l.seq + 1
+	r.PrevHash = l.last
+	hash, err := hashOf(r)
+	if err != nil {
+		return err
+	}
+	r.Hash = hash
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := l.f.Write(line); err != nil {
+		return l.rollback(fmt.Errorf("audit: write: %w", err))
+	}
+	if err := l.f.Sync(); err != nil {
+		return l.rollback(fmt.Errorf("audit: sync: %w", err))
+	}
+	l.size += int64(len(line))
+	l.seq, l.last = r.Seq, r.Hash
+	return nil
+}
+
+// rollback отрезает от файла то, что успел дописать неудавшийся Append,
+// чтобы следующая запись не легла после обрывка.
+func (l *FileLog) rollback(err error) error {
+	if terr := l.f.Truncate(l.size); terr != nil {
+		return errors.Join(err, fmt.Errorf("audit: truncate to %d bytes: %w", l.size, terr))
+	}
+	return err
+}
+
+// hashOf — SHA-256 записи в JSON с пустым Hash. PrevHash входит в запись,
+// так что хеш покрывает и всю цепочку до неё.
+func hashOf(r *domen.AuditRecord) (string, error) {
+	c := *r
+	c.Hash = ""
+	data, err := json.Marshal(&c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Query возвращает записи, подходящие под фильтр, в порядке добавления.
+func (l *FileLog) Query(filter domen.AuditFilter) ([]domen.AuditRecord, error) {
+	records := make([]domen.AuditRecord, 0)
+	err := l.scan(func(r *domen.AuditRecord, _ []byte) bool {
+		if filter.Match(r) {
+			records = append(records, *r)
+		}
+		return filter.Limit == 0 || len(records) < filter.Limit
+	})
+	return records, err
+}
+
+// Export пишет в w подходящие под фильтр записи так, как они лежат в
+// файле: по одной JSON-записи на строку.
+func (l *FileLog) Export(w io.Writer, filter domen.AuditFilter) error {
+	n := 0
+	var werr error
+	err := l.scan(func(r *domen.AuditRecord, line []byte) bool {
+		if !filter.Match(r) {
+			return true
+		}
+		if _, werr = w.Write(append(line, '\n')); werr != nil {
+			return false
+		}
+		n++
+		return filter.Limit == 0 || n < filter.Limit
+	})
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// Verify проверяет весь журнал: номера записей идут подряд, каждая запись
+// ссылается на хеш предыдущей, а её собственный хеш совпадает с
+// содержимым. Первое нарушение попадает в результат с Valid == false;
+// ошибка возвращается, только если журнал не удалось прочитать.
+func (l *FileLog) Verify() (domen.AuditVerification, error) {
+	v := domen.AuditVerification{LastHash: GenesisHash}
+	var lineNo int64
+	var verr error
+	err := l.scan(func(r *domen.AuditRecord, _ []byte) bool {
+		lineNo++
+		hash, err := hashOf(r)
+		switch {
+		case err != nil:
+			verr = err
+		case r.Seq != lineNo:
+			verr = fmt.Errorf("%w: line %d: seq %d, expected %d", ErrTampered, lineNo, r.Seq, lineNo)
+		case r.PrevHash != v.LastHash:
+			verr = fmt.Errorf("%w: line %d: prev_hash does not match the previous record", ErrTampered, lineNo)
+		case r.Hash != hash:
+			verr = fmt.Errorf("%w: line %d: record does not match its hash", ErrTampered, lineNo)
+		default:
+			v.Records, v.LastHash = r.Seq, r.Hash
+			return true
+		}
+		return false
+	})
+	if verr == nil && errors.Is(err, ErrTampered) {
+		verr, err = err, nil
+	}
+	if err != nil {
+		return v, err
+	}
+	v.Valid = verr == nil
+	if verr != nil {
+		v.Error = verr.Error()
+	}
+	return v, nil
+}
+
+// scan читает записи файла по порядку, пока fn возвращает true. Запись,
+// которую не удаётся разобрать, — ErrTampered.
+func (l *FileLog) scan(fn func(r *domen.AuditRecord, line []byte) bool) error {
+	// Читаем только записи, дописанные целиком к началу чтения
+	l.mu.Lock()
+	info, err := l.f.Stat()
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(io.LimitReader(f, info.Size()))
+	sc.Buffer(make([]byte, 64*1024), maxLine)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		var r domen.AuditRecord
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return fmt.Errorf("%w: line %d: %v", ErrTampered, lineNo, err)
+		}
+		if !fn(&r, sc.Bytes()) {
+			return nil
+		}
+	}
+	return sc.Err()
+}
+
+// lastRecord читает последнюю целую запись файла и возвращает её вместе с
+// размером файла без недописанного хвоста. Для пустого или
+// несуществующего файла запись — nil.
+func lastRecord(path string) (*domen.AuditRecord, int64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	// Последняя запись и, возможно, недописанный хвост за ней
+	off := max(info.Size()-2*(maxLine+1), 0)
+	data := make([]byte, info.Size()-off)
+	if _, err := f.ReadAt(data, off); err != nil {
+		return nil, 0, err
+	}
+	end := bytes.LastIndexByte(data, '\n') + 1
+	if tail := len(data) - end; tail > maxLine {
+		return nil, 0, fmt.Errorf("audit: %s: file ends with %d bytes that are not a complete record; "+
+			"if they were left by a crash, truncate the file to %d bytes", path, tail, info.Size()-int64(tail))
+	}
+	size := off + int64(end)
+	data = bytes.TrimRight(data[:end], "\n")
+	if len(data) == 0 {
+		return nil, size, nil
+	}
+	line := data[bytes.LastIndexByte(data, '\n')+1:]
+	var r domen.AuditRecord
+	if err := json.Unmarshal(line, &r); err != nil {
+		return nil, 0, fmt.Errorf("%w: %s: last record: %v", ErrTampered, path, err)
+	}
+	return &r, size, nil
+}
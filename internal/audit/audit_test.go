@@ -0,0 +1,227 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestLog(t *testing.T, path string) *FileLog {
+	t.Helper()
+	l, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	return l
+}
+
+// fill добавляет записи: alice создаёт и отменяет t1, bob пытается отменить t1.
+func fill(t *testing.T, l *FileLog) {
+	t.Helper()
+	for _, r := range []domen.AuditRecord{
+		{Principal: "alice", Tenant: "team-a", Action: domen.ActionTaskCreate, Target: "t1", Outcome: domen.OutcomeSuccess, RequestID: "r1", ClientIP: "10.0.0.1"},
+		{Principal: "bob", Tenant: "team-a", Action: domen.ActionTaskCancel, Target: "t1", Outcome: domen.OutcomeDenied, Error: "forbidden"},
+		{Principal: "alice", Tenant: "team-a", Action: domen.ActionTaskCancel, Target: "t1", Outcome: domen.OutcomeSuccess},
+	} {
+		require.NoError(t, l.Append(context.Background(), &r))
+	}
+}
+
+func TestFileLog_Chain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	l := openTestLog(t, path)
+	fill(t, l)
+
+	records, err := l.Query(domen.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, GenesisHash, records[0].PrevHash)
+	for i, r := range records {
+		assert.Equal(t, int64(i+1), r.Seq)
+		if i > 0 {
+			assert.Equal(t, records[i-1].Hash, r.PrevHash)
+		}
+	}
+	assert.Equal(t, "r1", records[0].RequestID)
+	assert.Equal(t, "10.0.0.1", records[0].ClientIP)
+
+	// После перезапуска цепочка продолжается с последней записи
+	require.NoError(t, l.Close())
+	l = openTestLog(t, path)
+	require.NoError(t, l.Append(context.Background(), &domen.AuditRecord{Action: domen.ActionTaskDelete, Target: "t1", Outcome: domen.OutcomeSuccess}))
+	v, err := l.Verify()
+	require.NoError(t, err)
+	assert.True(t, v.Valid, v.Error)
+	assert.Equal(t, int64(4), v.Records)
+
+	records, err = l.Query(domen.AuditFilter{AfterSeq: 3})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, v.LastHash, records[0].Hash)
+}
+
+func TestFileLog_Query(t *testing.T) {
+	l := openTestLog(t, filepath.Join(t.TempDir(), "audit.ndjson"))
+	fill(t, l)
+
+	cases := []struct {
+		name   string
+		filter domen.AuditFilter
+		seqs   []int64
+	}{
+		{"principal", domen.AuditFilter{Principal: "alice"}, []int64{1, 3}},
+		{"action", domen.AuditFilter{Action: domen.ActionTaskCancel}, []int64{2, 3}},
+		{"outcome", domen.AuditFilter{Outcome: domen.OutcomeDenied}, []int64{2}},
+		{"target and limit", domen.AuditFilter{Target: "t1", Limit: 2}, []int64{1, 2}},
+		{"after", domen.AuditFilter{AfterSeq: 1, Limit: 1}, []int64{2}},
+		{"future", domen.AuditFilter{From: time.Now().Add(time.Hour)}, []int64{}},
+		{"past", domen.AuditFilter{To: time.Now().Add(-time.Hour)}, []int64{}},
+	}
+	for _, c := range cases {
+		records, err := l.Query(c.filter)
+		require.NoError(t, err, c.name)
+		seqs := make([]int64, len(records))
+		for i, r := range records {
+			seqs[i] = r.Seq
+		}
+		assert.Equal(t, c.seqs, seqs, c.name)
+	}
+}
+
+func TestFileLog_Export(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	l := openTestLog(t, path)
+	fill(t, l)
+
+	var buf bytes.Buffer
+	require.NoError(t, l.Export(&buf, domen.AuditFilter{}))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(data), buf.String(), "без фильтра выгрузка совпадает с файлом")
+
+	buf.Reset()
+	require.NoError(t, l.Export(&buf, domen.AuditFilter{Principal: "bob"}))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+	var r domen.AuditRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &r))
+	assert.Equal(t, int64(2), r.Seq)
+}
+
+func TestFileLog_Tampering(t *testing.T) {
+	cases := map[string]func(lines []string) []string{
+		"edited record": func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], `"outcome":"denied"`, `"outcome":"success"`, 1)
+			return lines
+		},
+		"deleted record": func(lines []string) []string {
+			return append(lines[:1], lines[2:]...)
+		},
+		"reordered records": func(lines []string) []string {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		},
+		"garbage": func(lines []string) []string {
+			lines[1] = "{"
+			return lines
+		},
+	}
+	for name, tamper := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.ndjson")
+			l := openTestLog(t, path)
+			fill(t, l)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			lines := tamper(strings.Split(strings.TrimSpace(string(data)), "\n"))
+			require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+
+			v, err := l.Verify()
+			require.NoError(t, err)
+			assert.False(t, v.Valid)
+			assert.Equal(t, int64(1), v.Records, "первая запись цела")
+			assert.Contains(t, v.Error, "line 2")
+		})
+	}
+}
+
+// tornFile дописывает только половину записи и возвращает ошибку,
+// как при нехватке места на диске.
+type tornFile struct {
+	logFile
+}
+
+func (f tornFile) Write(p []byte) (int, error) {
+	n, _ := f.logFile.Write(p[:len(p)/2])
+	return n, errors.New("no space left on device")
+}
+
+func TestFileLog_FailedAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	l := openTestLog(t, path)
+	fill(t, l)
+	before, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	f := l.f
+	l.f = tornFile{f}
+	err = l.Append(context.Background(), &domen.AuditRecord{Action: domen.ActionTaskDelete, Target: "t1"})
+	require.Error(t, err)
+	l.f = f
+
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(before), string(after), "обрывок записи отрезан")
+
+	require.NoError(t, l.Append(context.Background(), &domen.AuditRecord{Action: domen.ActionTaskDelete, Target: "t1"}))
+	v, err := l.Verify()
+	require.NoError(t, err)
+	assert.True(t, v.Valid, v.Error)
+	assert.Equal(t, int64(4), v.Records)
+}
+
+func TestFileLog_TornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	l := openTestLog(t, path)
+	fill(t, l)
+	require.NoError(t, l.Close())
+	intact, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// Сбой посреди Append оставил запись без конца строки
+	torn := append(bytes.Clone(intact), `{"seq":4,"time":"2026-`...)
+	require.NoError(t, os.WriteFile(path, torn, 0o600))
+
+	l = openTestLog(t, path)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(intact), string(data))
+	require.NoError(t, l.Append(context.Background(), &domen.AuditRecord{Action: domen.ActionTaskDelete, Target: "t1"}))
+	v, err := l.Verify()
+	require.NoError(t, err)
+	assert.True(t, v.Valid, v.Error)
+	assert.Equal(t, int64(4), v.Records)
+
+	// Хвост длиннее любой записи — не обрывок Append, его Open не трогает
+	require.NoError(t, l.Close())
+	require.NoError(t, os.WriteFile(path, append(bytes.Clone(intact), bytes.Repeat([]byte("x"), maxLine+1)...), 0o600))
+	_, err = Open(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("truncate the file to %d bytes", len(intact)))
+
+	// Испорченная целая запись — по-прежнему ErrTampered
+	require.NoError(t, os.WriteFile(path, append(bytes.Clone(intact), "{\n"...), 0o600))
+	_, err = Open(path)
+	assert.ErrorIs(t, err, ErrTampered)
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// Поддерживаемые алгоритмы подписи JWT.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// jwk — ключ из JWKS (RFC 7517) в том виде, в каком он лежит в файле.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// oct
+	K string `json:"k"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// verificationKey — ключ проверки подписи и единственный алгоритм, для
+// которого он годится. Привязка алгоритма к ключу не даёт подделать
+// подпись, например выдав открытый RSA-ключ за секрет HS256.
+type verificationKey struct {
+	kid string
+	alg string
+	key interface{}
+}
+
+// loadJWKS читает ключи подписи из JWKS-файла. Ключи с use, отличным
+// от sig, пропускаются.
+func loadJWKS(path string) ([]verificationKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	keys := make([]verificationKey, 0, len(set.Keys))
+	kids := make(map[string]bool, len(set.Keys))
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		vk, err := parseJWK(k)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %d (kid %q): %w", path, i, k.Kid, err)
+		}
+		if kids[vk.kid] {
+			return nil, fmt.Errorf("%s: duplicate kid %q", path, vk.kid)
+		}
+		kids[vk.kid] = true
+		keys = append(keys, vk)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no signing keys", path)
+	}
+	return keys, nil
+}
+
+func parseJWK(k jwk) (verificationKey, error) {
+	vk := verificationKey{kid: k.Kid}
+	var err error
+	switch k.Kty {
+	case "oct":
+		vk.alg = AlgHS256
+		var secret []byte
+		if secret, err = decodeB64(k.K); err == nil && len(secret) < 32 {
+			err = fmt.Errorf("HS256 secret must be at least 32 bytes")
+		}
+		vk.key = secret
+	case "RSA":
+		vk.alg = AlgRS256
+		vk.key, err = parseRSA(k)
+	case "EC":
+		vk.alg = AlgES256
+		vk.key, err = parseEC(k)
+	default:
+		err = fmt.Errorf("unsupported kty %q", k.Kty)
+	}
+	if err != nil {
+		return verificationKey{}, err
+	}
+	if k.Alg != "" && k.Alg != vk.alg {
+		return verificationKey{}, fmt.Errorf("alg %q does not match kty %q", k.Alg, k.Kty)
+	}
+	return vk, nil
+}
+
+func parseRSA(k jwk) (*rsa.PublicKey, error) {
+	n, err := decodeB64(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeB64(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("invalid RSA exponent")
+	}
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if pub.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("RSA key must be at least 2048 bits")
+	}
+	return pub, nil
+}
+
+func parseEC(k jwk) (*ecdsa.PublicKey, error) {
+	if k.Crv != "P-256" {
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	x, err := decodeB64(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeB64(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	if len(x) != 32 || len(y) != 32 {
+		return nil, fmt.Errorf("invalid P-256 coordinates")
+	}
+	// ecdh проверяет, что точка лежит на кривой
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
+
+func decodeB64(s string) ([]byte, error) {
+	if s == "" {
+		return nil, fmt.Errorf("missing key material")
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTConfig задаёт проверку JWT, выпущенных шлюзом.
+type JWTConfig struct {
+	// JWKSFile — локальный JWKS с ключами подписи; перечитывается Reload.
+	JWKSFile string
+	// Issuer и Audience, если заданы, должны совпасть с iss и aud токена.
+	Issuer   string
+	Audience string
+	// ClockSkew — допуск на расхождение часов при проверке exp, nbf и iat.
+	ClockSkew time.Duration
+}
+
+// JWTAuthenticator проверяет подпись и утверждения JWT и превращает sub
+// и scope в domen.Principal.
+type JWTAuthenticator struct {
+	cfg  JWTConfig
+	keys atomic.Pointer[[]verificationKey]
+	now  func() time.Time
+}
+
+// NewJWTAuthenticator загружает ключи из cfg.JWKSFile.
+func NewJWTAuthenticator(cfg JWTConfig) (*JWTAuthenticator, error) {
+	a := &JWTAuthenticator{cfg: cfg, now: time.Now}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload перечитывает JWKS-файл. При ошибке остаются прежние ключи.
+func (a *JWTAuthenticator) Reload() error {
+	keys, err := loadJWKS(a.cfg.JWKSFile)
+	if err != nil {
+		return err
+	}
+	a.keys.Store(&keys)
+	return nil
+}
+
+// tokenClaims — утверждения, которые сервис читает из токена. scope —
+// строка через пробел (RFC 8693), scp — строка или массив, tenant —
+// арендатор пользователя, roles — его роли для политики доступа.
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Scope  string      `json:"scope,omitempty"`
+	Scp    interface{} `json:"scp,omitempty"`
+	Tenant string      `json:"tenant,omitempty"`
+	Roles  []string    `json:"roles,omitempty"`
+}
+
+func (c tokenClaims) scopes() []string {
+	scopes := strings.Fields(c.Scope)
+	switch v := c.Scp.(type) {
+	case string:
+		scopes = append(scopes, strings.Fields(v)...)
+	case []interface{}:
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	// Области чужих сервисов в токене шлюза не ошибка: берём только свои
+	out := scopes[:0]
+	for _, s := range scopes {
+		if slices.Contains(domen.KnownScopes, s) && !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Authenticate проверяет токен. Любая ошибка проверки — domen.ErrUnauthenticated.
+func (a *JWTAuthenticator) Authenticate(_ context.Context, token string) (*domen.Principal, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgES256}),
+		jwt.WithLeeway(a.cfg.ClockSkew),
+		jwt.WithTimeFunc(a.now),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if a.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.cfg.Issuer))
+	}
+	if a.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(a.cfg.Audience))
+	}
+
+	var claims tokenClaims
+	if _, err := jwt.ParseWithClaims(token, &claims, a.keyFor, opts...); err != nil {
+		return nil, fmt.Errorf("%w: invalid token: %v", domen.ErrUnauthenticated, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: invalid token: missing sub", domen.ErrUnauthenticated)
+	}
+	return &domen.Principal{ID: claims.Subject, Scopes: claims.scopes(), Tenant: claims.Tenant, Roles: claims.Roles}, nil
+}
+
+// keyFor выбирает ключ по kid. Без kid подходит единственный ключ нужного
+// алгоритма. Алгоритм токена обязан совпасть с алгоритмом ключа.
+func (a *JWTAuthenticator) keyFor(t *jwt.Token) (interface{}, error) {
+	alg := t.Method.Alg()
+	kid, _ := t.Header["kid"].(string)
+
+	keys := *a.keys.Load()
+	var found *verificationKey
+	for i := range keys {
+		if kid != "" {
+			if keys[i].kid == kid {
+				found = &keys[i]
+				break
+			}
+			continue
+		}
+		if keys[i].alg != alg {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("token without kid matches several keys")
+		}
+		found = &keys[i]
+	}
+	if found == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if found.alg != alg {
+		return nil, fmt.Errorf("key %q is for %s, token is signed with %s", found.kid, found.alg, alg)
+	}
+	return found.key, nil
+}
+
+// LooksLikeJWT отличает JWT (три части через точку) от API-ключа.
+func LooksLikeJWT(credential string) bool {
+	return strings.Count(credential, ".") == 2
+}
+
+// ByFormat направляет JWT в tokens, остальные учётные данные — в keys.
+// Любой из них может быть nil, если этот способ входа не настроен.
+func ByFormat(keys, tokens Authenticator) Authenticator {
+	return byFormat{keys: keys, tokens: tokens}
+}
+
+type byFormat struct {
+	keys, tokens Authenticator
+}
+
+func (b byFormat) Authenticate(ctx context.Context, credential string) (*domen.Principal, error) {
+	next := b.keys
+	if LooksLikeJWT(credential) && b.tokens != nil {
+		next = b.tokens
+	}
+	if next == nil {
+		return nil, fmt.Errorf("%w: unsupported credentials", domen.ErrUnauthenticated)
+	}
+	return next.Authenticate(ctx, credential)
+}
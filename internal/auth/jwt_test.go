@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKeys — ключи подписи, сгенерированные на время теста.
+type testKeys struct {
+	hmac []byte
+	rsa  *rsa.PrivateKey
+	ec   *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	require.NoError(t, err)
+	return testKeys{hmac: secret, rsa: rsaKey, ec: ecKey}
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// jwks возвращает JWKS с открытыми частями ключей: kid hs, rs и es.
+func (k testKeys) jwks(t *testing.T) []byte {
+	t.Helper()
+	ecPub, err := k.ec.PublicKey.ECDH()
+	require.NoError(t, err)
+	point := ecPub.Bytes() // 0x04 || X || Y
+	data, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hs", "alg": "HS256", "k": b64(k.hmac)},
+		{"kty": "RSA", "kid": "rs", "use": "sig", "n": b64(k.rsa.N.Bytes()), "e": b64(big.NewInt(int64(k.rsa.E)).Bytes())},
+		{"kty": "EC", "kid": "es", "crv": "P-256", "x": b64(point[1:33]), "y": b64(point[33:])},
+	}})
+	require.NoError(t, err)
+	return data
+}
+
+func writeJWKS(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func mint(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+	s, err := tok.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func validClaims(now time.Time) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":    "alice",
+		"iss":    "https://gateway.example",
+		"aud":    "workmate",
+		"iat":    now.Unix(),
+		"exp":    now.Add(time.Hour).Unix(),
+		"scope":  "tasks:read tasks:write billing:read",
+		"tenant": "team-a",
+		"roles":  []string{"user"},
+	}
+}
+
+func newTestJWT(t *testing.T, keys testKeys) *JWTAuthenticator {
+	t.Helper()
+	a, err := NewJWTAuthenticator(JWTConfig{
+		JWKSFile:  writeJWKS(t, keys.jwks(t)),
+		Issuer:    "https://gateway.example",
+		Audience:  "workmate",
+		ClockSkew: 30 * time.Second,
+	})
+	require.NoError(t, err)
+	return a
+}
+
+func TestJWT_Algorithms(t *testing.T) {
+	keys := newTestKeys(t)
+	a := newTestJWT(t, keys)
+	claims := validClaims(time.Now())
+
+	tokens := map[string]string{
+		"HS256": mint(t, jwt.SigningMethodHS256, "hs", keys.hmac, claims),
+		"RS256": mint(t, jwt.SigningMethodRS256, "rs", keys.rsa, claims),
+		"ES256": mint(t, jwt.SigningMethodES256, "es", keys.ec, claims),
+		// Без kid подходит единственный ключ своего алгоритма
+		"RS256 without kid": mint(t, jwt.SigningMethodRS256, "", keys.rsa, claims),
+	}
+	for name, token := range tokens {
+		p, err := a.Authenticate(context.Background(), token)
+		require.NoError(t, err, name)
+		assert.Equal(t, "alice", p.ID, name)
+		assert.Equal(t, "team-a", p.TenantID(), name)
+		assert.Equal(t, []string{"user"}, p.Roles, name)
+		// Чужие области отбрасываются
+		assert.Equal(t, []string{domen.ScopeTasksRead, domen.ScopeTasksWrite}, p.Scopes, name)
+	}
+}
+
+func TestJWT_Rejects(t *testing.T) {
+	keys := newTestKeys(t)
+	a := newTestJWT(t, keys)
+	now := time.Now()
+
+	with := func(k string, v interface{}) jwt.MapClaims {
+		c := validClaims(now)
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+	otherRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPub, err := x509.MarshalPKIXPublicKey(&keys.rsa.PublicKey)
+	require.NoError(t, err)
+
+	cases := map[string]string{
+		"expired":          mint(t, jwt.SigningMethodRS256, "rs", keys.rsa, with("exp", now.Add(-time.Minute).Unix())),
+		"no exp":           mint(t, jwt.SigningMethodRS256, "rs", keys.rsa, with("exp", nil)),
+		"not yet valid":    mint(t, jwt.SigningMethodRS256, "rs", keys.rsa, with("nbf", now.Add(time.Minute).Unix())),
+		"wrong audience":   mint(t, jwt.SigningMethodRS256, "rs", keys.rsa, with("aud", "billing")),
+		"wrong issuer":     mint(t, jwt.SigningMethodRS256, "rs", keys.rsa, with("iss", "https://evil.example")),
+		"no subject":       mint(t, jwt.SigningMethodRS256, "rs", keys.rsa, with("sub", nil)),
+		"unknown kid":      mint(t, jwt.SigningMethodRS256, "other", otherRSA, validClaims(now)),
+		"wrong signature":  mint(t, jwt.SigningMethodRS256, "rs", otherRSA, validClaims(now)),
+		"alg mismatch":     mint(t, jwt.SigningMethodES256, "rs", keys.ec, validClaims(now)),
+		"public key as HS": mint(t, jwt.SigningMethodHS256, "rs", rsaPub, validClaims(now)),
+		"alg none":         mint(t, jwt.SigningMethodNone, "rs", jwt.UnsafeAllowNoneSignatureType, validClaims(now)),
+		"not a token":      "a.b.c",
+	}
+	for name, token := range cases {
+		_, err := a.Authenticate(context.Background(), token)
+		assert.ErrorIs(t, err, domen.ErrUnauthenticated, name)
+	}
+}
+
+func TestJWT_ClockSkew(t *testing.T) {
+	keys := newTestKeys(t)
+	a := newTestJWT(t, keys)
+	now := time.Now()
+
+	// Истёк 10 секунд назад — в пределах допуска 30 секунд
+	c := validClaims(now)
+	c["exp"] = now.Add(-10 * time.Second).Unix()
+	_, err := a.Authenticate(context.Background(), mint(t, jwt.SigningMethodES256, "es", keys.ec, c))
+	assert.NoError(t, err)
+
+	// Выпущен «в будущем» часами шлюза, спешащими на 10 секунд
+	c = validClaims(now)
+	c["iat"] = now.Add(10 * time.Second).Unix()
+	c["nbf"] = now.Add(10 * time.Second).Unix()
+	_, err = a.Authenticate(context.Background(), mint(t, jwt.SigningMethodES256, "es", keys.ec, c))
+	assert.NoError(t, err)
+}
+
+func TestJWT_Reload(t *testing.T) {
+	keys := newTestKeys(t)
+	path := writeJWKS(t, keys.jwks(t))
+	a, err := NewJWTAuthenticator(JWTConfig{JWKSFile: path})
+	require.NoError(t, err)
+
+	rotated := newTestKeys(t)
+	token := mint(t, jwt.SigningMethodRS256, "rs", rotated.rsa, validClaims(time.Now()))
+	_, err = a.Authenticate(context.Background(), token)
+	require.ErrorIs(t, err, domen.ErrUnauthenticated)
+
+	require.NoError(t, os.WriteFile(path, rotated.jwks(t), 0o600))
+	require.NoError(t, a.Reload())
+	p, err := a.Authenticate(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", p.ID)
+
+	// Битый файл не сбрасывает действующие ключи
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	assert.Error(t, a.Reload())
+	_, err = a.Authenticate(context.Background(), token)
+	assert.NoError(t, err)
+}
+
+func TestLoadJWKS_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"empty":        `{"keys": []}`,
+		"short secret": `{"keys": [{"kty": "oct", "k": "c2hvcnQ"}]}`,
+		"alg mismatch": `{"keys": [{"kty": "oct", "alg": "RS256", "k": "` + b64(make([]byte, 32)) + `"}]}`,
+		"unknown kty":  `{"keys": [{"kty": "OKP", "crv": "Ed25519", "x": "AA"}]}`,
+		"bad curve":    `{"keys": [{"kty": "EC", "crv": "P-384", "x": "AA", "y": "AA"}]}`,
+		"off curve":    `{"keys": [{"kty": "EC", "crv": "P-256", "x": "` + b64(make([]byte, 32)) + `", "y": "` + b64(make([]byte, 32)) + `"}]}`,
+	}
+	for name, data := range cases {
+		_, err := loadJWKS(writeJWKS(t, []byte(data)))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestByFormat(t *testing.T) {
+	store, err := NewKeyStore([]Key{{ID: "ci", SHA256: HashKey("ci-secret"), Scopes: []string{domen.ScopeTasksRead}}})
+	require.NoError(t, err)
+	keys := newTestKeys(t)
+	a := ByFormat(store, newTestJWT(t, keys))
+	ctx := context.Background()
+
+	p, err := a.Authenticate(ctx, "ci-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "ci", p.ID)
+
+	p, err = a.Authenticate(ctx, mint(t, jwt.SigningMethodHS256, "hs", keys.hmac, validClaims(time.Now())))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", p.ID)
+
+	// Без JWT-проверки токены уходят в хранилище ключей и не находятся
+	_, err = ByFormat(store, nil).Authenticate(ctx, "a.b.c")
+	assert.ErrorIs(t, err, domen.ErrUnauthenticated)
+	_, err = ByFormat(nil, nil).Authenticate(ctx, "ci-secret")
+	assert.ErrorIs(t, err, domen.ErrUnauthenticated)
+}
@@ -0,0 +1,135 @@
+// Package auth проверяет учётные данные клиентов API и превращает их
+// в domen.Principal.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// Authenticator проверяет учётные данные из запроса. Недействительные
+// данные дают domen.ErrUnauthenticated.
+type Authenticator interface {
+	Authenticate(ctx context.Context, credential string) (*domen.Principal, error)
+}
+
+// Key — API-ключ. Сам секрет не хранится: только его SHA-256 в hex,
+// например из `printf %s "$KEY" | sha256sum`.
+type Key struct {
+	ID     string   `json:"id"`
+	SHA256 string   `json:"sha256"`
+	Scopes []string `json:"scopes"`
+	// Tenant — арендатор, от имени которого действует ключ; пустой —
+	// domen.DefaultTenant.
+	Tenant string `json:"tenant,omitempty"`
+	// Roles — роли ключа для политики доступа (POLICY_FILE).
+	Roles []string `json:"roles,omitempty"`
+}
+
+// KeyStore — неизменяемый набор API-ключей.
+type KeyStore struct {
+	byHash map[[sha256.Size]byte]*domen.Principal
+}
+
+// NewKeyStore проверяет ключи: ID и хеши уникальны, области доступа известны.
+func NewKeyStore(keys []Key) (*KeyStore, error) {
+	s := &KeyStore{byHash: make(map[[sha256.Size]byte]*domen.Principal, len(keys))}
+	ids := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if k.ID == "" {
+			return nil, fmt.Errorf("api key without id")
+		}
+		if ids[k.ID] {
+			return nil, fmt.Errorf("api key %s: duplicate id", k.ID)
+		}
+		ids[k.ID] = true
+
+		raw, err := hex.DecodeString(k.SHA256)
+		if err != nil || len(raw) != sha256.Size {
+			return nil, fmt.Errorf("api key %s: sha256 must be 64 hex characters", k.ID)
+		}
+		var sum [sha256.Size]byte
+		copy(sum[:], raw)
+		if _, ok := s.byHash[sum]; ok {
+			return nil, fmt.Errorf("api key %s: duplicate secret", k.ID)
+		}
+		for _, scope := range k.Scopes {
+			if !slices.Contains(domen.KnownScopes, scope) {
+				return nil, fmt.Errorf("api key %s: unknown scope %q", k.ID, scope)
+			}
+		}
+		s.byHash[sum] = &domen.Principal{ID: k.ID, Scopes: slices.Clone(k.Scopes), Tenant: k.Tenant, Roles: slices.Clone(k.Roles)}
+	}
+	return s, nil
+}
+
+// Len возвращает число ключей.
+func (s *KeyStore) Len() int { return len(s.byHash) }
+
+// Authenticate ищет ключ по хешу секрета. Сравнивать хеши, а не секреты,
+// безопасно по времени: хеш не выдаёт, насколько секрет близок к верному.
+func (s *KeyStore) Authenticate(_ context.Context, credential string) (*domen.Principal, error) {
+	p, ok := s.byHash[sha256.Sum256([]byte(credential))]
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid api key", domen.ErrUnauthenticated)
+	}
+	return &domen.Principal{ID: p.ID, Scopes: slices.Clone(p.Scopes), Tenant: p.Tenant, Roles: slices.Clone(p.Roles)}, nil
+}
+
+// HashKey возвращает SHA-256 секрета в hex для Key.SHA256.
+func HashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseKeys разбирает ключи из строки вида
+// "id:sha256:scope,scope;id2@tenant:sha256:scope". Арендатор ключа
+// указывается после "@" в ID.
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		// Области доступа сами содержат двоеточие: tasks:read
+		parts := strings.SplitN(item, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("api key %q: expected id:sha256:scopes", item)
+		}
+		id, tenant, _ := strings.Cut(parts[0], "@")
+		keys = append(keys, Key{ID: id, SHA256: parts[1], Scopes: splitScopes(parts[2]), Tenant: tenant})
+	}
+	return keys, nil
+}
+
+func splitScopes(s string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(s, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// LoadKeysFile читает ключи из JSON-файла: [{"id", "sha256", "scopes", "tenant", "roles"}].
+func LoadKeysFile(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyStore_Authenticate(t *testing.T) {
+	store, err := NewKeyStore([]Key{
+		{ID: "ci", SHA256: HashKey("ci-secret"), Scopes: []string{domen.ScopeTasksRead, domen.ScopeTasksWrite}},
+		{ID: "ops", SHA256: HashKey("ops-secret"), Scopes: []string{domen.ScopeAdmin}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, store.Len())
+	ctx := context.Background()
+
+	p, err := store.Authenticate(ctx, "ci-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "ci", p.ID)
+	assert.True(t, p.HasScope(domen.ScopeTasksWrite))
+	assert.False(t, p.HasScope(domen.ScopeTasksCancel))
+
+	// admin включает все области
+	p, err = store.Authenticate(ctx, "ops-secret")
+	require.NoError(t, err)
+	assert.True(t, p.HasScope(domen.ScopeTasksCancel))
+
+	_, err = store.Authenticate(ctx, HashKey("ci-secret"))
+	assert.ErrorIs(t, err, domen.ErrUnauthenticated, "хеш сам по себе не является ключом")
+}
+
+func TestNewKeyStore_Invalid(t *testing.T) {
+	hash := HashKey("secret")
+	cases := map[string][]Key{
+		"no id":         {{SHA256: hash}},
+		"duplicate id":  {{ID: "a", SHA256: hash}, {ID: "a", SHA256: HashKey("other")}},
+		"same secret":   {{ID: "a", SHA256: hash}, {ID: "b", SHA256: hash}},
+		"bad hash":      {{ID: "a", SHA256: "secret"}},
+		"unknown scope": {{ID: "a", SHA256: hash, Scopes: []string{"tasks:everything"}}},
+	}
+	for name, keys := range cases {
+		_, err := NewKeyStore(keys)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	hash := HashKey("secret")
+	keys, err := ParseKeys("ci@team-a:" + hash + ":tasks:read,tasks:write; ops:" + HashKey("ops") + ":admin;")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, Key{ID: "ci", SHA256: hash, Scopes: []string{"tasks:read", "tasks:write"}, Tenant: "team-a"}, keys[0])
+	assert.Equal(t, []string{"admin"}, keys[1].Scopes)
+	assert.Empty(t, keys[1].Tenant)
+
+	keys, err = ParseKeys("")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, err = ParseKeys("ci-without-hash")
+	assert.Error(t, err)
+}
+
+func TestLoadKeysFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	data := `[{"id": "ci", "sha256": "` + HashKey("secret") + `", "scopes": ["tasks:read"], "tenant": "team-a", "roles": ["operator"]}]`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	keys, err := LoadKeysFile(path)
+	require.NoError(t, err)
+	store, err := NewKeyStore(keys)
+	require.NoError(t, err)
+	p, err := store.Authenticate(context.Background(), "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "ci", p.ID)
+	assert.Equal(t, "team-a", p.TenantID())
+	assert.Equal(t, []string{"operator"}, p.Roles)
+}
@@ -0,0 +1,221 @@
+package phttp
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// AdminHandler — служебные эндпоинты: уровни логирования, действующая
+// конфигурация, состояние рантайма, журнал аудита и, по желанию, pprof.
+type AdminHandler struct {
+	config  map[string]string
+	pprof   bool
+	audit   AuditReader
+	started time.Time
+}
+
+// NewAdminHandler принимает конфигурацию с уже скрытыми секретами.
+// Без журнала аудита (audit == nil) /admin/audit не подключается.
+func NewAdminHandler(config map[string]string, pprof bool, audit AuditReader) *AdminHandler {
+	return &AdminHandler{config: config, pprof: pprof, audit: audit, started: time.Now()}
+}
+
+func (h *AdminHandler) Routes() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/log-level", h.getLogLevel)
+	r.Put("/log-level", h.setLogLevel)
+	r.Get("/config", h.getConfig)
+	r.Get("/runtime", h.runtimeInfo)
+	if h.audit != nil {
+		r.Get("/audit", h.listAudit)
+		r.Get("/audit/export", h.exportAudit)
+		r.Get("/audit/verify", h.verifyAudit)
+	}
+	if h.pprof {
+		r.Mount("/debug", middleware.Profiler())
+	}
+	return r
+}
+
+// LogLevels — глобальный уровень логирования и уровни именованных логгеров.
+type LogLevels struct {
+	Level   string            `json:"level" example:"info"`
+	Loggers map[string]string `json:"loggers"`
+}
+
+// SetLogLevelRequest — тело PUT /admin/log-level. Без logger меняется
+// глобальный уровень; с logger и пустым level логгер снова следует глобальному.
+type SetLogLevelRequest struct {
+	Logger string `json:"logger,omitempty" example:"http"`
+	Level  string `json:"level" example:"debug"`
+}
+
+func currentLogLevels() LogLevels {
+	levels := LogLevels{Level: logger.Level().String(), Loggers: make(map[string]string)}
+	for n, l := range logger.NamedLevels() {
+		levels.Loggers[n] = l.String()
+	}
+	return levels
+}
+
+// @Summary      Уровни логирования
+// @Description  Возвращает глобальный уровень и уровни, заданные именованным логгерам (main, http, batch, metrics)
+// @Tags         admin
+// @Produce      json
+// @Security     ApiKey
+// @Success      200  {object}  LogLevels
+// @Failure      401  {object}  Problem  "Нет API-ключа"
+// @Failure      403  {object}  Problem  "Ключу не разрешена область admin"
+// @Router       /admin/log-level [get]
+func (h *AdminHandler) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, currentLogLevels())
+}
+
+// @Summary      Изменить уровень логирования
+// @Description  Меняет глобальный уровень или уровень именованного логгера без перезапуска. Уровень логгера действует и на его потомков ("http" для "http.client")
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     ApiKey
+// @Param        request  body      SetLogLevelRequest  true  "Логгер и уровень"
+// @Success      200      {object}  LogLevels           "Уровни после изменения"
+// @Failure      400      {object}  Problem             "Неизвестный уровень"
+// @Failure      401      {object}  Problem             "Нет API-ключа"
+// @Failure      403      {object}  Problem             "Ключу не разрешена область admin"
+// @Router       /admin/log-level [put]
+func (h *AdminHandler) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req SetLogLevelRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if req.Logger != "" && req.Level == "" {
+		logger.ResetNamedLevel(req.Logger)
+	} else {
+		lvl, err := logger.ParseLevel(req.Level)
+		if err != nil {
+			writeError(w, r, &domen.ValidationError{Fields: []domen.FieldError{{
+				Field:   "/level",
+				Message: fmt.Sprintf("unknown level %q, expected one of %s", req.Level, strings.Join(logger.KnownLevels(), ", ")),
+			}}})
+			return
+		}
+		if req.Logger == "" {
+			logger.SetLevel(lvl)
+		} else {
+			logger.SetNamedLevel(req.Logger, lvl)
+		}
+	}
+
+	logger.FromContext(r.Context()).Warnw("log level changed", "logger", req.Logger, "level", req.Level)
+	writeJSON(w, currentLogLevels())
+}
+
+// @Summary      Действующая конфигурация
+// @Description  Возвращает конфигурацию по именам переменных окружения. Секреты заменены на [REDACTED]
+// @Tags         admin
+// @Produce      json
+// @Security     ApiKey
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  Problem  "Нет API-ключа"
+// @Failure      403  {object}  Problem  "Ключу не разрешена область admin"
+// @Router       /admin/config [get]
+func (h *AdminHandler) getConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.config)
+}
+
+// RuntimeInfo — состояние процесса.
+type RuntimeInfo struct {
+	StartedAt     time.Time  `json:"started_at"`
+	Uptime        string     `json:"uptime" example:"1h2m3s"`
+	UptimeSeconds int64      `json:"uptime_seconds" example:"3723"`
+	Goroutines    int        `json:"goroutines" example:"12"`
+	GOMAXPROCS    int        `json:"gomaxprocs" example:"8"`
+	NumCPU        int        `json:"num_cpu" example:"8"`
+	Memory        MemoryInfo `json:"memory"`
+	Build         BuildInfo  `json:"build"`
+}
+
+// MemoryInfo — выборка из runtime.MemStats, в байтах.
+type MemoryInfo struct {
+	HeapAlloc   uint64 `json:"heap_alloc"`
+	HeapInuse   uint64 `json:"heap_inuse"`
+	HeapObjects uint64 `json:"heap_objects"`
+	TotalAlloc  uint64 `json:"total_alloc"`
+	Sys         uint64 `json:"sys"`
+	NumGC       uint32 `json:"num_gc"`
+	GCPauseNS   uint64 `json:"gc_pause_total_ns"`
+}
+
+// BuildInfo — сведения о сборке из debug.ReadBuildInfo.
+type BuildInfo struct {
+	GoVersion string `json:"go_version" example:"go1.24.0"`
+	Path      string `json:"path" example:"github.com/gaz358/myprog/workmate/cmd/server"`
+	Version   string `json:"version,omitempty" example:"(devel)"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// @Summary      Состояние рантайма
+// @Description  Возвращает время работы, число горутин, память и сведения о сборке
+// @Tags         admin
+// @Produce      json
+// @Security     ApiKey
+// @Success      200  {object}  RuntimeInfo
+// @Failure      401  {object}  Problem  "Нет API-ключа"
+// @Failure      403  {object}  Problem  "Ключу не разрешена область admin"
+// @Router       /admin/runtime [get]
+func (h *AdminHandler) runtimeInfo(w http.ResponseWriter, r *http.Request) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	uptime := time.Since(h.started)
+
+	writeJSON(w, RuntimeInfo{
+		StartedAt:     h.started,
+		Uptime:        uptime.Truncate(time.Second).String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		NumCPU:        runtime.NumCPU(),
+		Memory: MemoryInfo{
+			HeapAlloc:   ms.HeapAlloc,
+			HeapInuse:   ms.HeapInuse,
+			HeapObjects: ms.HeapObjects,
+			TotalAlloc:  ms.TotalAlloc,
+			Sys:         ms.Sys,
+			NumGC:       ms.NumGC,
+			GCPauseNS:   ms.PauseTotalNs,
+		},
+		Build: buildInfo(),
+	})
+}
+
+func buildInfo() BuildInfo {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return BuildInfo{GoVersion: runtime.Version()}
+	}
+	info := BuildInfo{GoVersion: bi.GoVersion, Path: bi.Path, Version: bi.Main.Version}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.Time = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
@@ -0,0 +1,153 @@
+package phttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/internal/auth"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAdminToken  = "s3cret"
+	testReaderToken = "reader-s3cret"
+)
+
+func setupAdminServer(t *testing.T, pprof bool) *httptest.Server {
+	t.Helper()
+	prev := logger.Level()
+	t.Cleanup(func() {
+		logger.SetLevel(prev)
+		logger.ResetNamedLevel("http")
+	})
+
+	keys, err := auth.NewKeyStore([]auth.Key{
+		{ID: "admin", SHA256: auth.HashKey(testAdminToken), Scopes: []string{domen.ScopeAdmin}},
+		{ID: "reader", SHA256: auth.HashKey(testReaderToken), Scopes: []string{domen.ScopeTasksRead}},
+	})
+	require.NoError(t, err)
+	cfg := map[string]string{"PORT": "8080", "ADMIN_TOKEN": "[REDACTED]"}
+	r := chi.NewRouter()
+	r.With(Authenticate(keys), RequireScope(domen.ScopeAdmin)).Mount("/admin", NewAdminHandler(cfg, pprof, nil).Routes())
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func doAdmin(t *testing.T, method, url, token, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, data
+}
+
+func TestAdmin_RequiresAdminScope(t *testing.T) {
+	server := setupAdminServer(t, false)
+
+	for _, token := range []string{"", "wrong"} {
+		resp, body := doAdmin(t, http.MethodGet, server.URL+"/admin/config", token, "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, `Bearer realm="workmate"`, resp.Header.Get("WWW-Authenticate"))
+		var p Problem
+		require.NoError(t, json.Unmarshal(body, &p))
+		assert.Equal(t, CodeUnauthenticated, p.Code)
+	}
+
+	// Ключ без области admin аутентифицирован, но доступа нет
+	resp, body := doAdmin(t, http.MethodGet, server.URL+"/admin/config", testReaderToken, "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	var p Problem
+	require.NoError(t, json.Unmarshal(body, &p))
+	assert.Equal(t, CodeForbidden, p.Code)
+
+	resp, body = doAdmin(t, http.MethodGet, server.URL+"/admin/config", testAdminToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var cfg map[string]string
+	require.NoError(t, json.Unmarshal(body, &cfg))
+	assert.Equal(t, "[REDACTED]", cfg["ADMIN_TOKEN"])
+	assert.Equal(t, "8080", cfg["PORT"])
+}
+
+func TestAdmin_LogLevel(t *testing.T) {
+	server := setupAdminServer(t, false)
+	logger.SetLevel(logger.InfoLevel)
+
+	decode := func(body []byte) LogLevels {
+		var levels LogLevels
+		require.NoError(t, json.Unmarshal(body, &levels), string(body))
+		return levels
+	}
+
+	resp, body := doAdmin(t, http.MethodGet, server.URL+"/admin/log-level", testAdminToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, LogLevels{Level: "info", Loggers: map[string]string{}}, decode(body))
+
+	// Глобальный уровень
+	resp, body = doAdmin(t, http.MethodPut, server.URL+"/admin/log-level", testAdminToken, `{"level":"warn"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "warn", decode(body).Level)
+	assert.Equal(t, logger.WarnLevel, logger.Level())
+
+	// Уровень именованного логгера
+	resp, body = doAdmin(t, http.MethodPut, server.URL+"/admin/log-level", testAdminToken, `{"logger":"http","level":"debug"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]string{"http": "debug"}, decode(body).Loggers)
+	assert.True(t, logger.Global().Named("http").Enabled(logger.DebugLevel))
+	assert.False(t, logger.Global().Named("main").Enabled(logger.InfoLevel))
+
+	// Пустой уровень возвращает логгер к глобальному
+	resp, body = doAdmin(t, http.MethodPut, server.URL+"/admin/log-level", testAdminToken, `{"logger":"http","level":""}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, decode(body).Loggers)
+
+	resp, body = doAdmin(t, http.MethodPut, server.URL+"/admin/log-level", testAdminToken, `{"level":"loud"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	var p Problem
+	require.NoError(t, json.Unmarshal(body, &p))
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, "/level", p.Errors[0].Field)
+	assert.Equal(t, logger.WarnLevel, logger.Level())
+}
+
+func TestAdmin_Runtime(t *testing.T) {
+	server := setupAdminServer(t, false)
+
+	resp, body := doAdmin(t, http.MethodGet, server.URL+"/admin/runtime", testAdminToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var info RuntimeInfo
+	require.NoError(t, json.Unmarshal(body, &info))
+	assert.Positive(t, info.Goroutines)
+	assert.Positive(t, info.Memory.Sys)
+	assert.NotEmpty(t, info.Build.GoVersion)
+	assert.False(t, info.StartedAt.IsZero())
+}
+
+func TestAdmin_Pprof(t *testing.T) {
+	resp, _ := doAdmin(t, http.MethodGet, setupAdminServer(t, false).URL+"/admin/debug/pprof/", testAdminToken, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	server := setupAdminServer(t, true)
+	resp, body := doAdmin(t, http.MethodGet, server.URL+"/admin/debug/pprof/", testAdminToken, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "goroutine")
+
+	// pprof закрыт тем же токеном
+	resp, _ = doAdmin(t, http.MethodGet, server.URL+"/admin/debug/pprof/heap", "", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,177 @@
+package phttp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+)
+
+// ndjsonContentType — тип выгрузки журнала аудита: JSON-запись на строку.
+const ndjsonContentType = "application/x-ndjson"
+
+// Размер страницы GET /admin/audit.
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditReader — журнал аудита для /admin/audit.
+type AuditReader interface {
+	Query(filter domen.AuditFilter) ([]domen.AuditRecord, error)
+	Export(w io.Writer, filter domen.AuditFilter) error
+	Verify() (domen.AuditVerification, error)
+}
+
+// AuditPage — страница журнала аудита.
+type AuditPage struct {
+	Records []domen.AuditRecord `json:"records"`
+	// Pass as "after" to get the next page; absent on the last page
+	Next int64 `json:"next,omitempty" example:"100"`
+}
+
+// @Summary      Журнал аудита
+// @Description  Возвращает записи о создании, отмене, удалении и повторе задач в порядке добавления. Следующую страницу даёт параметр after из поля next
+// @Tags         admin
+// @Produce      json
+// @Security     ApiKey
+// @Param        principal  query     string  false  "ID принципала"
+// @Param        tenant     query     string  false  "Арендатор"
+// @Param        action     query     string  false  "Действие"  Enums(task:create, task:cancel, task:delete, task:rerun)
+// @Param        target     query     string  false  "ID задачи"
+// @Param        outcome    query     string  false  "Исход"  Enums(success, denied, failure)
+// @Param        from       query     string  false  "Не раньше (RFC 3339)"
+// @Param        to         query     string  false  "Раньше (RFC 3339)"
+// @Param        after      query     int     false  "Записи с seq больше заданного"
+// @Param        limit      query     int     false  "Размер страницы, до 1000"  default(100)
+// @Success      200        {object}  AuditPage
+// @Failure      400        {object}  Problem  "Некорректный фильтр"
+// @Failure      401        {object}  Problem  "Нет API-ключа"
+// @Failure      403        {object}  Problem  "Ключу не разрешена область admin"
+// @Failure      500        {object}  Problem  "Журнал не читается"
+// @Router       /admin/audit [get]
+func (h *AdminHandler) listAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = defaultAuditLimit
+	case filter.Limit > maxAuditLimit:
+		writeError(w, r, fmt.Errorf("%w: limit must be between 1 and %d", domen.ErrInvalidArgument, maxAuditLimit))
+		return
+	}
+	// Запись сверх страницы показывает, что есть следующая
+	limit := filter.Limit
+	filter.Limit++
+	records, err := h.audit.Query(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	page := AuditPage{Records: records}
+	if len(records) > limit {
+		page.Records = records[:limit]
+		page.Next = page.Records[limit-1].Seq
+	}
+	writeJSON(w, page)
+}
+
+// @Summary      Выгрузка журнала аудита
+// @Description  Выгружает записи журнала как есть, по одной JSON-записи на строку (NDJSON), с хешами цепочки. Фильтры те же, что у /admin/audit, limit по умолчанию не ограничен
+// @Tags         admin
+// @Produce      application/x-ndjson
+// @Security     ApiKey
+// @Param        principal  query     string  false  "ID принципала"
+// @Param        tenant     query     string  false  "Арендатор"
+// @Param        action     query     string  false  "Действие"
+// @Param        target     query     string  false  "ID задачи"
+// @Param        outcome    query     string  false  "Исход"
+// @Param        from       query     string  false  "Не раньше (RFC 3339)"
+// @Param        to         query     string  false  "Раньше (RFC 3339)"
+// @Param        after      query     int     false  "Записи с seq больше заданного"
+// @Param        limit      query     int     false  "Число записей"
+// @Success      200        {string}  string   "Записи NDJSON"
+// @Failure      400        {object}  Problem  "Некорректный фильтр"
+// @Failure      401        {object}  Problem  "Нет API-ключа"
+// @Failure      403        {object}  Problem  "Ключу не разрешена область admin"
+// @Router       /admin/audit/export [get]
+func (h *AdminHandler) exportAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.ndjson"`)
+	// Заголовки уже отправлены: ошибку посреди выгрузки можно только залогировать
+	if err := h.audit.Export(w, filter); err != nil {
+		logger.FromContext(r.Context()).Errorw("audit export failed", "error", err)
+	}
+}
+
+// @Summary      Проверка журнала аудита
+// @Description  Проверяет цепочку хешей всего журнала. При нарушении valid равно false, а error указывает первую испорченную строку
+// @Tags         admin
+// @Produce      json
+// @Security     ApiKey
+// @Success      200  {object}  domen.AuditVerification
+// @Failure      401  {object}  Problem  "Нет API-ключа"
+// @Failure      403  {object}  Problem  "Ключу не разрешена область admin"
+// @Failure      500  {object}  Problem  "Журнал не читается"
+// @Router       /admin/audit/verify [get]
+func (h *AdminHandler) verifyAudit(w http.ResponseWriter, r *http.Request) {
+	v, err := h.audit.Verify()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, v)
+}
+
+func parseAuditFilter(q url.Values) (domen.AuditFilter, error) {
+	f := domen.AuditFilter{
+		Principal: q.Get("principal"),
+		Tenant:    q.Get("tenant"),
+		Action:    q.Get("action"),
+		Target:    q.Get("target"),
+		Outcome:   q.Get("outcome"),
+	}
+	var err error
+	if f.From, err = parseQueryTime(q, "from"); err != nil {
+		return f, err
+	}
+	if f.To, err = parseQueryTime(q, "to"); err != nil {
+		return f, err
+	}
+	if v := q.Get("after"); v != "" {
+		if f.AfterSeq, err = strconv.ParseInt(v, 10, 64); err != nil || f.AfterSeq < 0 {
+			return f, fmt.Errorf("%w: after must be a non-negative integer", domen.ErrInvalidArgument)
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 1 {
+			return f, fmt.Errorf("%w: limit must be a positive integer", domen.ErrInvalidArgument)
+		}
+	}
+	return f, nil
+}
+
+func parseQueryTime(q url.Values, name string) (time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s must be an RFC 3339 time", domen.ErrInvalidArgument, name)
+	}
+	return t, nil
+}
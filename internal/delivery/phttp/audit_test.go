@@ -0,0 +1,187 @@
+package phttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/internal/audit"
+	"github.com/gaz358/myprog/workmate/internal/auth"
+	"github.com/gaz358/myprog/workmate/internal/policy"
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAuditServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	scopes := []string{domen.ScopeTasksRead, domen.ScopeTasksWrite, domen.ScopeTasksCancel}
+	keys, err := auth.NewKeyStore([]auth.Key{
+		{ID: "alice", SHA256: auth.HashKey(aliceKey), Scopes: scopes, Tenant: "team-a"},
+		{ID: "bob", SHA256: auth.HashKey(bobKey), Scopes: scopes, Tenant: "team-a"},
+		{ID: "admin", SHA256: auth.HashKey(testAdminToken), Scopes: []string{domen.ScopeAdmin}},
+	})
+	require.NoError(t, err)
+	pol, err := policy.New(policy.File{
+		DefaultRoles: []string{"user"},
+		Roles: map[string][]policy.Rule{"user": {
+			{Actions: []string{domen.ActionTaskRead, domen.ActionTaskCreate}},
+			{Actions: []string{domen.ActionTaskCancel, domen.ActionTaskDelete, domen.ActionTaskRerun}, Own: true},
+		}},
+	})
+	require.NoError(t, err)
+	log, err := audit.Open(filepath.Join(t.TempDir(), "audit.ndjson"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = log.Close() })
+
+	uc := usecase.NewTaskUseCase(memory.NewInMemoryRepo(), time.Hour)
+	uc.SetPolicy(pol)
+	uc.SetAuditLog(log)
+	r := chi.NewRouter()
+	r.Use(RequestID)
+	r.With(Authenticate(keys)).Mount("/v2", NewV2Handler(uc, usecase.NewBatchUseCase(uc, memory.NewBatchRepo())).Routes())
+	r.With(Authenticate(keys), RequireScope(domen.ScopeAdmin)).Mount("/admin", NewAdminHandler(nil, false, log).Routes())
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAudit_Records(t *testing.T) {
+	server := setupAuditServer(t)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v2/tasks", nil)
+	require.NoError(t, err)
+	req.Header.Set(APIKeyHeader, aliceKey)
+	req.Header.Set(RequestIDHeader, "req-1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	var created struct {
+		Data TaskV2 `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	id := created.Data.ID
+
+	resp, _ = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/"+id+"/cancel", bobKey, "")
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, _ = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/"+id+"/cancel", aliceKey, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/bulk-delete", aliceKey, `{"ids": ["`+id+`", "missing"]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	// Чтение в журнал не попадает
+	resp, _ = doWithKey(t, http.MethodGet, server.URL+"/v2/tasks", aliceKey, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	query := func(q string) AuditPage {
+		resp, body := doAdmin(t, http.MethodGet, server.URL+"/admin/audit"+q, testAdminToken, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+		var page AuditPage
+		require.NoError(t, json.Unmarshal(body, &page))
+		return page
+	}
+
+	page := query("")
+	require.Len(t, page.Records, 5)
+	assert.Zero(t, page.Next)
+	type entry struct{ principal, action, target, outcome string }
+	got := make([]entry, len(page.Records))
+	for i, r := range page.Records {
+		got[i] = entry{r.Principal, r.Action, r.Target, r.Outcome}
+	}
+	assert.Equal(t, []entry{
+		{"alice", domen.ActionTaskCreate, id, domen.OutcomeSuccess},
+		{"bob", domen.ActionTaskCancel, id, domen.OutcomeDenied},
+		{"alice", domen.ActionTaskCancel, id, domen.OutcomeSuccess},
+		{"alice", domen.ActionTaskDelete, id, domen.OutcomeSuccess},
+		{"alice", domen.ActionTaskDelete, "missing", domen.OutcomeFailure},
+	}, got)
+	first := page.Records[0]
+	assert.Equal(t, "req-1", first.RequestID)
+	assert.Equal(t, "127.0.0.1", first.ClientIP)
+	assert.Equal(t, "team-a", first.Tenant)
+	assert.Contains(t, page.Records[1].Error, "created by alice")
+	assert.NotEmpty(t, page.Records[2].RequestID, "ID запроса генерируется, если клиент его не прислал")
+
+	// Постраничный обход и фильтры
+	page = query("?principal=alice&limit=2")
+	require.Len(t, page.Records, 2)
+	assert.Equal(t, int64(3), page.Next)
+	page = query("?principal=alice&limit=2&after=3")
+	require.Len(t, page.Records, 2)
+	assert.Equal(t, int64(4), page.Records[0].Seq)
+	assert.Zero(t, page.Next)
+	assert.Len(t, query("?outcome=denied").Records, 1)
+	assert.Len(t, query("?action=task:cancel&target="+id).Records, 2)
+
+	for _, q := range []string{"?limit=0", "?limit=5000", "?after=x", "?from=yesterday"} {
+		resp, body := doAdmin(t, http.MethodGet, server.URL+"/admin/audit"+q, testAdminToken, "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "%s: %s", q, body)
+	}
+	resp, _ = doAdmin(t, http.MethodGet, server.URL+"/admin/audit", aliceKey, "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestAudit_RerunLineage(t *testing.T) {
+	server := setupAuditServer(t)
+
+	resp, body := doWithKey(t, http.MethodPost, server.URL+"/v2/tasks", aliceKey, "")
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	var task struct {
+		Data TaskV2 `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &task))
+	orig := task.Data.ID
+	resp, _ = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/"+orig+"/cancel", aliceKey, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, body = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/"+orig+"/rerun", aliceKey, "")
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	require.NoError(t, json.Unmarshal(body, &task))
+
+	resp, body = doAdmin(t, http.MethodGet, server.URL+"/admin/audit?action="+domen.ActionTaskRerun, testAdminToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var page AuditPage
+	require.NoError(t, json.Unmarshal(body, &page))
+	require.Len(t, page.Records, 1)
+	assert.Equal(t, orig, page.Records[0].Target)
+	assert.Equal(t, task.Data.ID, page.Records[0].Created, "запись повтора называет новую задачу")
+}
+
+func TestAudit_ExportAndVerify(t *testing.T) {
+	server := setupAuditServer(t)
+	for i := 0; i < 3; i++ {
+		resp, _ := doWithKey(t, http.MethodPost, server.URL+"/v2/tasks", aliceKey, "")
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+
+	resp, body := doAdmin(t, http.MethodGet, server.URL+"/admin/audit/export?action=task:create", testAdminToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	require.Len(t, lines, 3)
+	var prev domen.AuditRecord
+	for i, line := range lines {
+		var r domen.AuditRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &r))
+		assert.Equal(t, int64(i+1), r.Seq)
+		if i > 0 {
+			assert.Equal(t, prev.Hash, r.PrevHash)
+		}
+		prev = r
+	}
+
+	resp, body = doAdmin(t, http.MethodGet, server.URL+"/admin/audit/verify", testAdminToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var v domen.AuditVerification
+	require.NoError(t, json.Unmarshal(body, &v))
+	assert.True(t, v.Valid)
+	assert.Equal(t, int64(3), v.Records)
+	assert.Equal(t, prev.Hash, v.LastHash)
+}
@@ -0,0 +1,63 @@
+package phttp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/internal/auth"
+)
+
+// APIKeyHeader — альтернатива Authorization: Bearer для API-ключей.
+const APIKeyHeader = "X-API-Key"
+
+// Authenticate требует учётные данные из Authorization: Bearer или
+// X-API-Key и кладёт принципала в контекст запроса.
+func Authenticate(a auth.Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			credential := r.Header.Get(APIKeyHeader)
+			if credential == "" {
+				credential, _ = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			}
+			if credential == "" {
+				unauthenticated(w, r, fmt.Errorf("%w: credentials required", domen.ErrUnauthenticated))
+				return
+			}
+			p, err := a.Authenticate(r.Context(), credential)
+			if err != nil {
+				unauthenticated(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(domen.WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+func unauthenticated(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="workmate"`)
+	writeError(w, r, err)
+}
+
+// Проверки областей доступа для маршрутов задач и пакетов.
+var (
+	canRead   = RequireScope(domen.ScopeTasksRead)
+	canWrite  = RequireScope(domen.ScopeTasksWrite)
+	canCancel = RequireScope(domen.ScopeTasksCancel)
+)
+
+// RequireScope пропускает запрос, только если принципалу разрешена
+// область scope. Без Authenticate в цепочке принципала нет и проверка
+// не выполняется: аутентификация выключена.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p, ok := domen.PrincipalFromContext(r.Context()); ok && !p.HasScope(scope) {
+				writeError(w, r, fmt.Errorf("%w: %s lacks scope %s", domen.ErrForbidden, p.ID, scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
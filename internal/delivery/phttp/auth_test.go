@@ -0,0 +1,215 @@
+package phttp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/internal/auth"
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Ключи тестового сервера: читатель, писатель и оператор с правом отмены.
+const (
+	readerKey   = "reader-secret"
+	writerKey   = "writer-secret"
+	operatorKey = "operator-secret"
+)
+
+func setupAuthServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	keys, err := auth.NewKeyStore([]auth.Key{
+		{ID: "reader", SHA256: auth.HashKey(readerKey), Scopes: []string{domen.ScopeTasksRead}},
+		{ID: "writer", SHA256: auth.HashKey(writerKey), Scopes: []string{domen.ScopeTasksRead, domen.ScopeTasksWrite}},
+		{ID: "operator", SHA256: auth.HashKey(operatorKey), Scopes: []string{domen.ScopeTasksRead, domen.ScopeTasksCancel}},
+	})
+	require.NoError(t, err)
+
+	uc := usecase.NewTaskUseCase(memory.NewInMemoryRepo(), time.Hour)
+	batchUC := usecase.NewBatchUseCase(uc, memory.NewBatchRepo())
+	r := chi.NewRouter()
+	r.Route("/v1", func(r chi.Router) {
+		r.Use(Authenticate(keys))
+		MountV1(r, NewHandler(uc), NewBatchHandler(batchUC))
+	})
+	r.With(Authenticate(keys)).Mount("/v2", NewV2Handler(uc, batchUC).Routes())
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func doWithKey(t *testing.T, method, url, key, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if key != "" {
+		req.Header.Set(APIKeyHeader, key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var data json.RawMessage
+	_ = json.NewDecoder(resp.Body).Decode(&data)
+	return resp, data
+}
+
+func TestAuth_Credentials(t *testing.T) {
+	server := setupAuthServer(t)
+
+	resp, body := doWithKey(t, http.MethodGet, server.URL+"/v1/tasks/all", "", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+	var p Problem
+	require.NoError(t, json.Unmarshal(body, &p))
+	assert.Equal(t, CodeUnauthenticated, p.Code)
+
+	resp, _ = doWithKey(t, http.MethodGet, server.URL+"/v1/tasks/all", "wrong", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Ключ принимается и в X-API-Key, и в Authorization: Bearer
+	resp, _ = doWithKey(t, http.MethodGet, server.URL+"/v1/tasks/all", readerKey, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v2/tasks", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+readerKey)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAuth_Scopes(t *testing.T) {
+	server := setupAuthServer(t)
+
+	resp, body := doWithKey(t, http.MethodPost, server.URL+"/v1/tasks/", writerKey, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var task domen.Task
+	require.NoError(t, json.Unmarshal(body, &task))
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		status int
+	}{
+		{"reader creates", http.MethodPost, "/v1/tasks/", readerKey, http.StatusForbidden},
+		{"reader reads", http.MethodGet, "/v1/tasks/" + task.ID, readerKey, http.StatusOK},
+		{"reader lists task types", http.MethodGet, "/v1/task-types", readerKey, http.StatusOK},
+		{"reader cancels", http.MethodPut, "/v1/tasks/" + task.ID + "/cancel", readerKey, http.StatusForbidden},
+		{"writer cancels", http.MethodPut, "/v1/tasks/" + task.ID + "/cancel", writerKey, http.StatusForbidden},
+		{"operator deletes", http.MethodDelete, "/v1/tasks/" + task.ID, operatorKey, http.StatusForbidden},
+		{"operator creates batch", http.MethodPost, "/v2/batches", operatorKey, http.StatusForbidden},
+		{"operator cancels", http.MethodPost, "/v2/tasks/" + task.ID + "/cancel", operatorKey, http.StatusOK},
+		{"writer deletes", http.MethodDelete, "/v2/tasks/" + task.ID, writerKey, http.StatusNoContent},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp, body := doWithKey(t, c.method, server.URL+c.path, c.key, "")
+			assert.Equal(t, c.status, resp.StatusCode, string(body))
+			if c.status == http.StatusForbidden {
+				var p Problem
+				require.NoError(t, json.Unmarshal(body, &p))
+				assert.Equal(t, CodeForbidden, p.Code)
+			}
+		})
+	}
+}
+
+func TestAuth_CreatedBy(t *testing.T) {
+	server := setupAuthServer(t)
+
+	// В v1 created_by не попадает: его форма заморожена
+	resp, body := doWithKey(t, http.MethodPost, server.URL+"/v2/tasks", writerKey, "")
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	var created struct {
+		Data TaskV2 `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &created))
+	assert.Equal(t, "writer", created.Data.CreatedBy)
+
+	resp, body = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/batch", writerKey, `{"count": 2}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var env struct {
+		Data []BulkItemV2 `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &env))
+	require.Len(t, env.Data, 2)
+	for _, item := range env.Data {
+		require.NotNil(t, item.Task)
+		assert.Equal(t, "writer", item.Task.CreatedBy)
+	}
+
+	// Задачу читает другой ключ, но автор остаётся прежним
+	resp, body = doWithKey(t, http.MethodGet, server.URL+"/v2/tasks/"+created.Data.ID, readerKey, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var got struct {
+		Data TaskV2 `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, "writer", got.Data.CreatedBy)
+}
+
+func TestAuth_JWT(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	jwks := `{"keys": [{"kty": "oct", "kid": "gw", "k": "` + base64.RawURLEncoding.EncodeToString(secret) + `"}]}`
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, []byte(jwks), 0o600))
+	tokens, err := auth.NewJWTAuthenticator(auth.JWTConfig{JWKSFile: path, Audience: "workmate"})
+	require.NoError(t, err)
+
+	uc := usecase.NewTaskUseCase(memory.NewInMemoryRepo(), time.Hour)
+	r := chi.NewRouter()
+	r.With(Authenticate(auth.ByFormat(nil, tokens))).Mount("/v2", NewV2Handler(uc, usecase.NewBatchUseCase(uc, memory.NewBatchRepo())).Routes())
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	mint := func(scope string) string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": "bob", "aud": "workmate", "scope": scope, "exp": time.Now().Add(time.Minute).Unix(),
+		})
+		tok.Header["kid"] = "gw"
+		s, err := tok.SignedString(secret)
+		require.NoError(t, err)
+		return s
+	}
+	do := func(method, path, token string) (*http.Response, []byte) {
+		req, err := http.NewRequest(method, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var data json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&data)
+		return resp, data
+	}
+
+	// Области из токена проверяются так же, как области API-ключа
+	resp, _ := do(http.MethodPost, "/v2/tasks", mint("tasks:read"))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, body := do(http.MethodPost, "/v2/tasks", mint("tasks:read tasks:write"))
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	var created struct {
+		Data TaskV2 `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &created))
+	assert.Equal(t, "bob", created.Data.CreatedBy)
+
+	resp, _ = do(http.MethodGet, "/v2/tasks", "not-a-jwt")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,107 @@
+package phttp
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+type BatchHandler struct {
+	uc *usecase.BatchUseCase
+}
+
+func NewBatchHandler(uc *usecase.BatchUseCase) *BatchHandler {
+	return &BatchHandler{uc: uc}
+}
+
+// Routes: все операции с пакетом затрагивают до 1000 задач.
+func (h *BatchHandler) Routes() http.Handler {
+	r := chi.NewRouter()
+	r.Use(bulkTimeout)
+	r.With(canWrite).Post("/", h.create)
+	r.With(canRead).Get("/{id}", h.get)
+	r.With(canCancel).Put("/{id}/cancel", h.cancel)
+	return r
+}
+
+// @Summary      Создать пакет задач
+// @Description  Создаёт пакет из count задач (не больше 1000) типа type с параметрами payload и запускает их. Payload проверяется по JSON Schema типа задачи
+// @Tags         batches
+// @Security     ApiKey
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BatchCreateRequest  true  "Число, тип и параметры задач"
+// @Success      200      {object}  domen.Batch
+// @Failure      400      {object}  Problem  "Некорректный запрос"
+// @Failure      403      {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      429      {object}  Problem  "Превышена квота арендатора"
+// @Failure      500      {object}  Problem  "Внутренняя ошибка"
+// @Router       /v1/batches [post]
+func (h *BatchHandler) create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lg := logger.FromContext(ctx)
+
+	var req BatchCreateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	batch, err := h.uc.CreateBatch(ctx, req.Count, req.spec())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	lg.Infow("batch created", "id", batch.ID, "tasks", len(batch.TaskIDs))
+	writeJSON(w, batch)
+}
+
+// @Summary      Получить пакет задач
+// @Description  Возвращает пакет с числом задач по статусам и общим прогрессом
+// @Tags         batches
+// @Security     ApiKey
+// @Produce      json
+// @Param        id   path      string  true  "ID пакета"
+// @Success      200  {object}  domen.BatchProgress
+// @Failure      403  {object}  Problem  "Политика доступа запрещает читать задачи пакета"
+// @Failure      404  {object}  Problem  "Пакет не найден"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v1/batches/{id} [get]
+func (h *BatchHandler) get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	progress, err := h.uc.GetBatch(ctx, id)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("batch %s: %w", id, err))
+		return
+	}
+
+	writeJSON(w, progress)
+}
+
+// @Summary      Отменить пакет задач
+// @Description  Отменяет все незавершённые задачи пакета и возвращает результат по каждой
+// @Tags         batches
+// @Security     ApiKey
+// @Produce      json
+// @Param        id   path      string  true  "ID пакета"
+// @Success      200  {object}  BulkResponse
+// @Failure      404  {object}  Problem  "Пакет не найден"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v1/batches/{id}/cancel [put]
+func (h *BatchHandler) cancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	results, err := h.uc.CancelBatch(ctx, id)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("batch %s: %w", id, err))
+		return
+	}
+	writeBulk(w, r, results)
+}
@@ -0,0 +1,261 @@
+package phttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupBatchServer(t *testing.T) (*httptest.Server, <-chan *domen.BatchProgress) {
+	t.Helper()
+	uc := usecase.NewTaskUseCase(memory.NewInMemoryRepo(), 200*time.Millisecond)
+	batchUC := usecase.NewBatchUseCase(uc, memory.NewBatchRepo())
+
+	finished := make(chan *domen.BatchProgress, 10)
+	batchUC.OnFinished(func(_ context.Context, p *domen.BatchProgress) { finished <- p })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = batchUC.Run(ctx) }()
+	// Run подписывается на изменения асинхронно
+	time.Sleep(20 * time.Millisecond)
+
+	r := chi.NewRouter()
+	r.Mount("/tasks", NewHandler(uc).Routes())
+	r.Mount("/batches", NewBatchHandler(batchUC).Routes())
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server, finished
+}
+
+func getBatch(t *testing.T, url string) domen.BatchProgress {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var p domen.BatchProgress
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+	return p
+}
+
+func TestBatchHandler_Completion(t *testing.T) {
+	server, finished := setupBatchServer(t)
+
+	resp := postJSON(t, server.URL+"/batches/", BatchCreateRequest{Count: 3})
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var batch domen.Batch
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&batch))
+	require.Len(t, batch.TaskIDs, 3)
+
+	p := getBatch(t, server.URL+"/batches/"+batch.ID)
+	assert.Equal(t, 3, p.Total)
+	assert.False(t, p.Finished())
+
+	// Задачи пакета знают свой пакет
+	taskResp, err := http.Get(server.URL + "/tasks/" + batch.TaskIDs[0])
+	require.NoError(t, err)
+	defer taskResp.Body.Close()
+	var task domen.Task
+	require.NoError(t, json.NewDecoder(taskResp.Body).Decode(&task))
+	assert.Equal(t, batch.ID, task.BatchID)
+
+	select {
+	case done := <-finished:
+		assert.Equal(t, batch.ID, done.ID)
+		assert.Equal(t, 3, done.Counts[domen.StatusCompleted])
+		assert.False(t, done.FinishedAt.IsZero())
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch did not finish")
+	}
+
+	p = getBatch(t, server.URL+"/batches/"+batch.ID)
+	assert.Equal(t, 3, p.Done)
+	assert.InDelta(t, 1.0, p.Progress, 1e-9)
+	assert.False(t, p.FinishedAt.IsZero())
+
+	// Обработчик завершения вызывается один раз
+	select {
+	case <-finished:
+		t.Fatal("batch finished twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestBatchHandler_Cancel(t *testing.T) {
+	server, finished := setupBatchServer(t)
+
+	resp := postJSON(t, server.URL+"/batches/", BatchCreateRequest{Count: 4})
+	defer resp.Body.Close()
+	var batch domen.Batch
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&batch))
+
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/batches/"+batch.ID+"/cancel", nil)
+	require.NoError(t, err)
+	cancelResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	out := decodeBulk(t, cancelResp)
+	assert.Equal(t, 4, out.Succeeded)
+
+	select {
+	case done := <-finished:
+		assert.Equal(t, 4, done.Counts[domen.StatusCancelled])
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch did not finish")
+	}
+}
+
+func TestBatchHandler_NotFound(t *testing.T) {
+	server, _ := setupBatchServer(t)
+
+	resp, err := http.Get(server.URL + "/batches/missing")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/batches/missing/cancel", nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = postJSON(t, server.URL+"/batches/", BatchCreateRequest{Count: 0})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// flakyRepo сохраняет задачи в repo, но каждый failAt-й Create возвращает ошибку.
+type flakyRepo struct {
+	domen.TaskRepository
+	failAt int
+	calls  atomic.Int32
+}
+
+func (r *flakyRepo) Create(ctx context.Context, t *domen.Task) error {
+	if int(r.calls.Add(1))%r.failAt == 0 {
+		return errors.New("disk full")
+	}
+	return r.TaskRepository.Create(ctx, t)
+}
+
+// countingBatches считает созданные пакеты и может отказывать в создании.
+type countingBatches struct {
+	domen.BatchRepository
+	err     error
+	created atomic.Int32
+}
+
+func (r *countingBatches) Create(ctx context.Context, b *domen.Batch) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.created.Add(1)
+	return r.BatchRepository.Create(ctx, b)
+}
+
+func TestBatchHandler_CreateRollback(t *testing.T) {
+	cases := []struct {
+		name     string
+		failAt   int
+		batchErr error
+	}{
+		{"task create fails", 3, nil},
+		{"batch create fails", 1000, errors.New("batch store unavailable")},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repo := memory.NewInMemoryRepo()
+			batches := &countingBatches{BatchRepository: memory.NewBatchRepo(), err: c.batchErr}
+			uc := usecase.NewTaskUseCase(&flakyRepo{TaskRepository: repo, failAt: c.failAt}, 50*time.Millisecond)
+			r := chi.NewRouter()
+			r.Mount("/batches", NewBatchHandler(usecase.NewBatchUseCase(uc, batches)).Routes())
+			server := httptest.NewServer(r)
+			defer server.Close()
+
+			resp := postJSON(t, server.URL+"/batches/", BatchCreateRequest{Count: 5})
+			resp.Body.Close()
+			assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+			// Ни пакета, ни его задач, ни запущенных задач не осталось
+			assert.Zero(t, batches.created.Load())
+			time.Sleep(100 * time.Millisecond)
+			tasks, err := repo.List(context.Background())
+			require.NoError(t, err)
+			assert.Empty(t, tasks)
+		})
+	}
+}
+
+// compactingRepo отдаёт первому подписчику одно постороннее изменение и
+// закрывает поток, теряя остальные, а продолжение с этой ревизии
+// отклоняет как вытесненное.
+type compactingRepo struct {
+	domen.TaskRepository
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (r *compactingRepo) Watch(ctx context.Context, f domen.WatchFilter) (<-chan domen.TaskChange, error) {
+	switch r.calls.Add(1) {
+	case 1:
+		ch := make(chan domen.TaskChange, 1)
+		ch <- domen.TaskChange{Revision: 1, Type: domen.ChangeUpdated, Task: &domen.Task{ID: "other"}}
+		go func() {
+			<-r.release
+			close(ch)
+		}()
+		return ch, nil
+	case 2:
+		return nil, domen.ErrRevisionCompacted
+	}
+	return r.TaskRepository.Watch(ctx, f)
+}
+
+func TestBatchUseCase_ReconcileAfterCompaction(t *testing.T) {
+	repo := &compactingRepo{TaskRepository: memory.NewInMemoryRepo(), release: make(chan struct{})}
+	uc := usecase.NewTaskUseCase(repo, 20*time.Millisecond)
+	batchUC := usecase.NewBatchUseCase(uc, memory.NewBatchRepo())
+	finished := make(chan *domen.BatchProgress, 1)
+	batchUC.OnFinished(func(_ context.Context, p *domen.BatchProgress) { finished <- p })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = batchUC.Run(ctx) }()
+	<-batchUC.Watching()
+
+	batch, err := batchUC.CreateBatch(ctx, 2, usecase.TaskSpec{})
+	require.NoError(t, err)
+	// Задачи завершаются, пока наблюдатель не получает изменений
+	require.Eventually(t, func() bool {
+		p, err := batchUC.GetBatch(ctx, batch.ID)
+		require.NoError(t, err)
+		return p.Counts[domen.StatusCompleted] == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	select {
+	case <-finished:
+		t.Fatal("batch finished before the watcher caught up")
+	default:
+	}
+
+	close(repo.release)
+	select {
+	case p := <-finished:
+		assert.Equal(t, batch.ID, p.ID)
+		assert.Equal(t, 2, p.Counts[domen.StatusCompleted])
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch was not reconciled after compaction")
+	}
+}
@@ -0,0 +1,184 @@
+package phttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+	"github.com/gaz358/myprog/workmate/usecase"
+)
+
+// BatchCreateRequest — тело POST /tasks/batch и /batches: count задач
+// одного типа с одинаковым payload.
+type BatchCreateRequest struct {
+	Count int `json:"count" example:"10"`
+	// example: default
+	Type    string          `json:"type,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object"`
+}
+
+func (req BatchCreateRequest) spec() usecase.TaskSpec {
+	return usecase.TaskSpec{Type: req.Type, Payload: req.Payload}
+}
+
+// BulkRequest — тело POST /tasks/bulk-cancel и /tasks/bulk-delete.
+// Нужно указать ровно одно из полей ids и filter.
+type BulkRequest struct {
+	IDs    []string    `json:"ids,omitempty"`
+	Filter *BulkFilter `json:"filter,omitempty"`
+}
+
+// BulkFilter выбирает задачи по статусу, очереди (типу задачи) и времени
+// создания [created_from, created_to). Незнакомые поля отклоняются.
+type BulkFilter struct {
+	Statuses []domen.Status `json:"statuses,omitempty" example:"PENDING"`
+	// Task types to select
+	Queues      []string  `json:"queues,omitempty" example:"report"`
+	CreatedFrom time.Time `json:"created_from,omitempty"`
+	CreatedTo   time.Time `json:"created_to,omitempty"`
+}
+
+// BulkItemResult — результат пакетной операции для одной задачи.
+type BulkItemResult struct {
+	ID      string       `json:"id"`
+	Status  domen.Status `json:"status,omitempty"`
+	Version int64        `json:"version,omitempty"`
+	// Code — стабильный код ошибки, как в Problem.
+	Code  string `json:"code,omitempty" example:"invalid_transition"`
+	Error string `json:"error,omitempty"`
+}
+
+// BulkResponse — ответ пакетных операций.
+type BulkResponse struct {
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+// @Summary      Создать несколько задач
+// @Description  Создаёт count задач (не больше 1000) типа type с параметрами payload и возвращает результат по каждой. Payload проверяется по JSON Schema типа задачи
+// @Tags         tasks
+// @Security     ApiKey
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BatchCreateRequest  true  "Число, тип и параметры задач"
+// @Success      200      {object}  BulkResponse
+// @Failure      400      {object}  Problem  "Некорректный запрос"
+// @Failure      403      {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      429      {object}  Problem  "Превышена квота арендатора"
+// @Failure      500      {object}  Problem  "Внутренняя ошибка"
+// @Router       /v1/tasks/batch [post]
+func (h *Handler) createBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req BatchCreateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	results, err := h.uc.CreateTasks(ctx, req.Count, req.spec())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeBulk(w, r, results)
+}
+
+// @Summary      Отменить несколько задач
+// @Description  Отменяет задачи по списку ID или по фильтру (не больше 1000) и возвращает результат по каждой
+// @Tags         tasks
+// @Security     ApiKey
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BulkRequest  true  "Список ID или фильтр"
+// @Success      200      {object}  BulkResponse
+// @Failure      400      {object}  Problem  "Некорректный запрос"
+// @Failure      500      {object}  Problem  "Внутренняя ошибка"
+// @Router       /v1/tasks/bulk-cancel [post]
+func (h *Handler) bulkCancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sel, err := decodeSelector(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	results, err := h.uc.CancelTasks(ctx, sel)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeBulk(w, r, results)
+}
+
+// @Summary      Удалить несколько задач
+// @Description  Удаляет задачи по списку ID или по фильтру (не больше 1000) и возвращает результат по каждой
+// @Tags         tasks
+// @Security     ApiKey
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BulkRequest  true  "Список ID или фильтр"
+// @Success      200      {object}  BulkResponse
+// @Failure      400      {object}  Problem  "Некорректный запрос"
+// @Failure      500      {object}  Problem  "Внутренняя ошибка"
+// @Router       /v1/tasks/bulk-delete [post]
+func (h *Handler) bulkDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sel, err := decodeSelector(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	results, err := h.uc.DeleteTasks(ctx, sel)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeBulk(w, r, results)
+}
+
+func decodeSelector(r *http.Request) (usecase.BulkSelector, error) {
+	// Опечатка в фильтре не должна молча расширять выборку до всех задач
+	var req BulkRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return usecase.BulkSelector{}, fmt.Errorf("%w: invalid request body: %w", domen.ErrInvalidArgument, err)
+	}
+	sel := usecase.BulkSelector{IDs: req.IDs}
+	if req.Filter != nil {
+		sel.Filter = &domen.TaskFilter{
+			Statuses:    req.Filter.Statuses,
+			Types:       req.Filter.Queues,
+			CreatedFrom: req.Filter.CreatedFrom,
+			CreatedTo:   req.Filter.CreatedTo,
+		}
+	}
+	return sel, nil
+}
+
+func writeBulk(w http.ResponseWriter, r *http.Request, results []usecase.BulkResult) {
+	resp := BulkResponse{Results: make([]BulkItemResult, len(results))}
+	for i, res := range results {
+		item := BulkItemResult{ID: res.ID}
+		if res.Err != nil {
+			p := problemFor(res.Err)
+			item.Code, item.Error = p.Code, p.Detail
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+		if res.Task != nil {
+			item.Status = res.Task.Status
+			item.Version = res.Task.Version
+		}
+		resp.Results[i] = item
+	}
+	logger.FromContext(r.Context()).Infow("bulk operation finished", "succeeded", resp.Succeeded, "failed", resp.Failed)
+	writeJSON(w, resp)
+}
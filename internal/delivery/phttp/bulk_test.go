@@ -0,0 +1,102 @@
+package phttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postJSON(t *testing.T, url string, body interface{}) *http.Response {
+	t.Helper()
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	require.NoError(t, err)
+	return resp
+}
+
+func decodeBulk(t *testing.T, resp *http.Response) BulkResponse {
+	t.Helper()
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var out BulkResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	return out
+}
+
+func TestTaskHandler_Bulk(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	created := decodeBulk(t, postJSON(t, server.URL+"/batch", BatchCreateRequest{Count: 5}))
+	assert.Equal(t, 5, created.Succeeded)
+	assert.Equal(t, 0, created.Failed)
+	require.Len(t, created.Results, 5)
+
+	ids := make([]string, len(created.Results))
+	for i, r := range created.Results {
+		assert.NotEmpty(t, r.ID)
+		assert.Equal(t, int64(1), r.Version)
+		ids[i] = r.ID
+	}
+
+	// Отмена по списку: одна задача не существует
+	canceled := decodeBulk(t, postJSON(t, server.URL+"/bulk-cancel", BulkRequest{IDs: append(ids[:2:2], "missing")}))
+	assert.Equal(t, 2, canceled.Succeeded)
+	assert.Equal(t, 1, canceled.Failed)
+	assert.Equal(t, domen.StatusCancelled, canceled.Results[0].Status)
+	assert.Equal(t, domen.StatusCancelled, canceled.Results[1].Status)
+	assert.Equal(t, "missing", canceled.Results[2].ID)
+	assert.NotEmpty(t, canceled.Results[2].Error)
+
+	// Ждём завершения остальных задач (длительность в тестах 200ms)
+	time.Sleep(400 * time.Millisecond)
+
+	// Завершённые задачи отменить нельзя
+	completed := decodeBulk(t, postJSON(t, server.URL+"/bulk-cancel", BulkRequest{
+		Filter: &BulkFilter{Statuses: []domen.Status{domen.StatusCompleted}},
+	}))
+	assert.Equal(t, 0, completed.Succeeded)
+	assert.Equal(t, 3, completed.Failed)
+
+	deleted := decodeBulk(t, postJSON(t, server.URL+"/bulk-delete", BulkRequest{
+		Filter: &BulkFilter{Statuses: []domen.Status{domen.StatusCancelled}},
+	}))
+	assert.Equal(t, 2, deleted.Succeeded)
+	assert.ElementsMatch(t, ids[:2], []string{deleted.Results[0].ID, deleted.Results[1].ID})
+
+	getResp, err := http.Get(server.URL + "/" + ids[0])
+	require.NoError(t, err)
+	defer getResp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, getResp.StatusCode)
+}
+
+func TestTaskHandler_BulkInvalid(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	cases := []struct {
+		path string
+		body interface{}
+	}{
+		{"/batch", BatchCreateRequest{Count: 0}},
+		{"/batch", BatchCreateRequest{Count: 1001}},
+		{"/bulk-cancel", BulkRequest{}},
+		{"/bulk-delete", BulkRequest{IDs: []string{"a"}, Filter: &BulkFilter{}}},
+		{"/bulk-delete", "not an object"},
+		// Незнакомое поле фильтра — ошибка, а не выборка всех задач
+		{"/bulk-delete", map[string]any{"filter": map[string]any{"queue": "report"}}},
+		{"/bulk-cancel", map[string]any{"ids": []string{"a"}, "dry_run": true}},
+	}
+	for _, c := range cases {
+		resp := postJSON(t, server.URL+c.path, c.body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "%s %v", c.path, c.body)
+	}
+}
@@ -0,0 +1,90 @@
+package phttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskHandler_IfMatch(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/", "application/json", nil)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+
+	var created domen.Task
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+	// Устаревшая версия
+	staleReq, err := http.NewRequest(http.MethodPut, server.URL+"/"+created.ID+"/cancel", nil)
+	assert.NoError(t, err)
+	staleReq.Header.Set("If-Match", `"100"`)
+	staleResp, err := http.DefaultClient.Do(staleReq)
+	assert.NoError(t, err)
+	defer staleResp.Body.Close()
+	assert.Equal(t, http.StatusPreconditionFailed, staleResp.StatusCode)
+
+	// Актуальная версия из GET
+	getResp, err := http.Get(server.URL + "/" + created.ID)
+	assert.NoError(t, err)
+	defer getResp.Body.Close()
+	etag := getResp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	cancelReq, err := http.NewRequest(http.MethodPut, server.URL+"/"+created.ID+"/cancel", nil)
+	assert.NoError(t, err)
+	cancelReq.Header.Set("If-Match", etag)
+	cancelResp, err := http.DefaultClient.Do(cancelReq)
+	assert.NoError(t, err)
+	defer cancelResp.Body.Close()
+	// Задача могла перейти в RUNNING между GET и PUT
+	if cancelResp.StatusCode != http.StatusPreconditionFailed {
+		assert.Equal(t, http.StatusOK, cancelResp.StatusCode)
+		assert.NotEqual(t, etag, cancelResp.Header.Get("ETag"))
+	}
+
+	// Удаление с устаревшей версией
+	delReq, err := http.NewRequest(http.MethodDelete, server.URL+"/"+created.ID, nil)
+	assert.NoError(t, err)
+	delReq.Header.Set("If-Match", `"1"`)
+	delResp, err := http.DefaultClient.Do(delReq)
+	assert.NoError(t, err)
+	defer delResp.Body.Close()
+	assert.Equal(t, http.StatusPreconditionFailed, delResp.StatusCode)
+}
+
+func TestTaskHandler_CancelNotOverwrittenByRun(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/", "application/json", nil)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	var created domen.Task
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+	cancelReq, err := http.NewRequest(http.MethodPut, server.URL+"/"+created.ID+"/cancel", nil)
+	assert.NoError(t, err)
+	cancelResp, err := http.DefaultClient.Do(cancelReq)
+	assert.NoError(t, err)
+	defer cancelResp.Body.Close()
+	assert.Equal(t, http.StatusOK, cancelResp.StatusCode)
+
+	// Ждём дольше длительности задачи (200ms), чтобы run успел отработать
+	time.Sleep(400 * time.Millisecond)
+
+	getResp, err := http.Get(server.URL + "/" + created.ID)
+	assert.NoError(t, err)
+	defer getResp.Body.Close()
+	var fetched domen.Task
+	assert.NoError(t, json.NewDecoder(getResp.Body).Decode(&fetched))
+	assert.Equal(t, domen.StatusCancelled, fetched.Status)
+}
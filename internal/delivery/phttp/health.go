@@ -0,0 +1,94 @@
+package phttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gaz358/myprog/workmate/internal/health"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+)
+
+// HealthHandler отдаёт liveness- и readiness-пробы по реестру проверок.
+type HealthHandler struct {
+	reg *health.Registry
+}
+
+func NewHealthHandler(reg *health.Registry) *HealthHandler {
+	return &HealthHandler{reg: reg}
+}
+
+// @Summary      Liveness-проба
+// @Description  Проверяет, что процесс жив и его фоновые процессы работают. С параметром verbose отвечает текстом для человека
+// @Tags         health
+// @Produce      json
+// @Produce      plain
+// @Param        verbose  query     bool           false  "Текстовый отчёт по компонентам"
+// @Success      200      {object}  health.Report  "Процесс жив"
+// @Failure      503      {object}  health.Report  "Процесс нужно перезапустить"
+// @Router       /livez [get]
+func (h *HealthHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	h.write(w, r, "livez", h.reg.Live(r.Context()))
+}
+
+// @Summary      Readiness-проба
+// @Description  Проверяет, что сервис запущен, не останавливается и его зависимости доступны. С параметром verbose отвечает текстом для человека
+// @Tags         health
+// @Produce      json
+// @Produce      plain
+// @Param        verbose  query     bool           false  "Текстовый отчёт по компонентам"
+// @Success      200      {object}  health.Report  "Сервис готов принимать запросы"
+// @Failure      503      {object}  health.Report  "Сервис запускается, останавливается или зависимость недоступна"
+// @Router       /readyz [get]
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	h.write(w, r, "readyz", h.reg.Ready(r.Context()))
+}
+
+func (h *HealthHandler) write(w http.ResponseWriter, r *http.Request, probe string, rep health.Report) {
+	status := http.StatusOK
+	if !rep.OK() {
+		status = http.StatusServiceUnavailable
+		logger.FromContext(r.Context()).Warnw("probe failed", "probe", probe, "state", rep.State, "checks", rep.Checks)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	if _, ok := r.URL.Query()["verbose"]; ok {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(verboseReport(probe, rep)))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(rep)
+}
+
+// verboseReport форматирует отчёт построчно, как пробы Kubernetes:
+//
+//	[+]repository ok (1ms)
+//	[-]batch-watcher failed: stopped
+//	readyz check failed
+func verboseReport(probe string, rep health.Report) string {
+	var b strings.Builder
+	if rep.State != "" {
+		mark := "+"
+		if rep.State != health.StateReady {
+			mark = "-"
+		}
+		fmt.Fprintf(&b, "[%s]state %s\n", mark, rep.State)
+	}
+	for _, c := range rep.Checks {
+		if c.Status == health.StatusOK {
+			fmt.Fprintf(&b, "[+]%s ok (%dms)\n", c.Name, c.LatencyMS)
+		} else {
+			fmt.Fprintf(&b, "[-]%s failed: %s (%dms)\n", c.Name, c.Error, c.LatencyMS)
+		}
+	}
+	if rep.OK() {
+		fmt.Fprintf(&b, "%s check passed\n", probe)
+	} else {
+		fmt.Fprintf(&b, "%s check failed\n", probe)
+	}
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package phttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gaz358/myprog/workmate/internal/health"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupProbeServer(t *testing.T, reg *health.Registry) *httptest.Server {
+	t.Helper()
+	h := NewHealthHandler(reg)
+	r := chi.NewRouter()
+	r.Get("/livez", h.Livez)
+	r.Get("/readyz", h.Readyz)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHealthHandler_Probes(t *testing.T) {
+	reg := health.NewRegistry()
+	var repoErr atomic.Value // string
+	repoErr.Store("")
+	reg.RegisterReadiness("repository", func(context.Context) error {
+		if msg := repoErr.Load().(string); msg != "" {
+			return errors.New(msg)
+		}
+		return nil
+	})
+	server := setupProbeServer(t, reg)
+
+	decode := func(body []byte) health.Report {
+		var rep health.Report
+		require.NoError(t, json.Unmarshal(body, &rep), string(body))
+		return rep
+	}
+
+	// При запуске процесс жив, но не готов
+	resp, body := doRaw(t, http.MethodGet, server.URL+"/livez", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, health.StatusOK, decode(body).Status)
+
+	resp, body = doRaw(t, http.MethodGet, server.URL+"/readyz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, health.StateStarting, decode(body).State)
+
+	reg.MarkReady()
+	resp, body = doRaw(t, http.MethodGet, server.URL+"/readyz", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	rep := decode(body)
+	require.Len(t, rep.Checks, 1)
+	assert.Equal(t, "repository", rep.Checks[0].Name)
+	assert.Equal(t, health.StatusOK, rep.Checks[0].Status)
+
+	repoErr.Store("database is locked")
+	resp, body = doRaw(t, http.MethodGet, server.URL+"/readyz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	rep = decode(body)
+	assert.Equal(t, health.StatusFail, rep.Status)
+	assert.Equal(t, "database is locked", rep.Checks[0].Error)
+
+	// Недоступная зависимость не делает процесс мёртвым
+	resp, _ = doRaw(t, http.MethodGet, server.URL+"/livez", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	repoErr.Store("")
+	reg.Drain()
+	resp, body = doRaw(t, http.MethodGet, server.URL+"/readyz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, health.StateDraining, decode(body).State)
+}
+
+func TestHealthHandler_Verbose(t *testing.T) {
+	reg := health.NewRegistry()
+	reg.MarkReady()
+	reg.RegisterReadiness("repository", func(context.Context) error { return nil })
+	reg.RegisterLiveness("batch-watcher", func(context.Context) error { return health.ErrStopped })
+	server := setupProbeServer(t, reg)
+
+	resp, body := doRaw(t, http.MethodGet, server.URL+"/readyz?verbose", "")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain"))
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	require.Len(t, lines, 4, string(body))
+	assert.Equal(t, "[+]state ready", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "[-]batch-watcher failed: stopped"), lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "[+]repository ok"), lines[2])
+	assert.Equal(t, "readyz check failed", lines[3])
+}
@@ -0,0 +1,52 @@
+package phttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskHandler_History(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/", "application/json", nil)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	var created domen.Task
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+	// Ждём завершения задачи (длительность в тестах 200ms)
+	time.Sleep(400 * time.Millisecond)
+
+	histResp, err := http.Get(server.URL + "/" + created.ID + "/history")
+	assert.NoError(t, err)
+	defer histResp.Body.Close()
+	assert.Equal(t, http.StatusOK, histResp.StatusCode)
+
+	var history []domen.Transition
+	assert.NoError(t, json.NewDecoder(histResp.Body).Decode(&history))
+	statuses := make([]domen.Status, 0, len(history))
+	for _, tr := range history {
+		statuses = append(statuses, tr.To)
+	}
+	assert.Equal(t, []domen.Status{domen.StatusPending, domen.StatusRunning, domen.StatusCompleted}, statuses)
+
+	// Отмена завершённой задачи недопустима
+	cancelReq, err := http.NewRequest(http.MethodPut, server.URL+"/"+created.ID+"/cancel", nil)
+	assert.NoError(t, err)
+	cancelResp, err := http.DefaultClient.Do(cancelReq)
+	assert.NoError(t, err)
+	defer cancelResp.Body.Close()
+	assert.Equal(t, http.StatusConflict, cancelResp.StatusCode)
+
+	// История несуществующей задачи
+	missingResp, err := http.Get(server.URL + "/missing/history")
+	assert.NoError(t, err)
+	defer missingResp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, missingResp.StatusCode)
+}
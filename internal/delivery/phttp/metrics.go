@@ -0,0 +1,32 @@
+package phttp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/internal/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Instrument учитывает каждый запрос в метриках по шаблону маршрута chi
+// (например /v1/tasks/{id}). Запросы без маршрута идут под route="unmatched".
+func Instrument(m *metrics.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			m.ObserveHTTP(r.Method, route, status, time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package phttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/internal/metrics"
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrument(t *testing.T) {
+	m := metrics.New()
+	uc := usecase.NewTaskUseCase(memory.NewInMemoryRepo(), time.Hour)
+	batchUC := usecase.NewBatchUseCase(uc, memory.NewBatchRepo())
+
+	r := chi.NewRouter()
+	r.Use(Instrument(m))
+	r.Handle("/metrics", m.Handler())
+	r.Route("/v1", func(r chi.Router) { MountV1(r, NewHandler(uc), NewBatchHandler(batchUC)) })
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	doRaw(t, http.MethodPost, server.URL+"/v1/tasks/", "")
+	doRaw(t, http.MethodGet, server.URL+"/v1/tasks/a", "")
+	doRaw(t, http.MethodGet, server.URL+"/v1/tasks/b", "")
+	doRaw(t, http.MethodGet, server.URL+"/nowhere", "")
+
+	resp, err := http.Get(server.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	text := string(body)
+
+	// В метках шаблон маршрута, а не путь с ID
+	assert.Contains(t, text, `workmate_http_requests_total{method="POST",route="/v1/tasks",status="200"} 1`)
+	assert.Contains(t, text, `workmate_http_requests_total{method="GET",route="/v1/tasks/{id}",status="404"} 2`)
+	assert.Contains(t, text, `workmate_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, text, `workmate_http_request_duration_seconds_count{method="GET",route="/v1/tasks/{id}",status="404"} 2`)
+}
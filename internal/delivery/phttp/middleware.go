@@ -0,0 +1,175 @@
+package phttp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader — заголовок с ID запроса во входящих запросах и ответах.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen ограничивает длину принятого от клиента ID запроса.
+const maxRequestIDLen = 128
+
+// RequestIDFromContext возвращает ID запроса, выставленный RequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	return domen.OriginFromContext(ctx).RequestID
+}
+
+// RequestID берёт ID запроса из X-Request-ID или генерирует новый,
+// кладёт его в контекст вместе с адресом клиента (domen.Origin) и
+// возвращает клиенту в том же заголовке. Слишком длинные и непечатные
+// значения заменяются.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		origin := domen.Origin{RequestID: id, ClientIP: clientIP(r)}
+		next.ServeHTTP(w, r.WithContext(domen.WithOrigin(r.Context(), origin)))
+	})
+}
+
+// clientIP — адрес, с которого пришло соединение. X-Forwarded-For не
+// учитывается: клиент может подставить туда что угодно.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// AccessLog кладёт в контекст логгер запроса с полем request_id и после
+// ответа пишет одну строку access-лога: маршрут, статус, размер и время.
+// Use case и репозиторий берут этот логгер через logger.FromContext.
+func AccessLog(log logger.TypeOfLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			lg := log
+			if id := RequestIDFromContext(r.Context()); id != "" {
+				lg = lg.WithField("request_id", id)
+			}
+			ctx := logger.ToContext(r.Context(), lg)
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := ""
+			if rctx := chi.RouteContext(ctx); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+			logger.FromContext(ctx).Infow("request completed",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", route,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"duration_ms", time.Since(start).Milliseconds(),
+				"remote_addr", r.RemoteAddr,
+			)
+		})
+	}
+}
+
+// Recoverer превращает панику обработчика в ответ 500 Problem и пишет
+// её в лог со стеком. http.ErrAbortHandler пробрасывается дальше.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler { //nolint:errorlint // сравнение значения паники
+				panic(rec)
+			}
+			logger.FromContext(r.Context()).Errorw("panic recovered",
+				"method", r.Method, "path", r.URL.Path, "panic", fmt.Sprint(rec), "stack", string(debug.Stack()))
+			p := newProblem(http.StatusInternalServerError, CodeInternal, "internal server error")
+			p.Instance = r.URL.Path
+			writeProblem(w, p)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Timeouts задаёт время обработки запросов по классам маршрутов.
+// Нулевое значение снимает ограничение.
+type Timeouts struct {
+	// Default — обычные запросы к одной задаче или пакету.
+	Default time.Duration
+	// Bulk — пакетные операции и списки, затрагивающие до 1000 задач.
+	Bulk time.Duration
+}
+
+// DefaultTimeouts действуют, если WithTimeouts не подключён.
+var DefaultTimeouts = Timeouts{Default: 10 * time.Second, Bulk: time.Minute}
+
+type timeoutsKey struct{}
+
+// WithTimeouts передаёт маршрутам таймауты t. Сам дедлайн ставится уже
+// после выбора маршрута, по его классу: вложенный контекст не может
+// продлить дедлайн внешнего, поэтому общий таймаут на корне не годится.
+func WithTimeouts(t Timeouts) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), timeoutsKey{}, t)))
+		})
+	}
+}
+
+// defaultTimeout и bulkTimeout ограничивают время обработки маршрутов своего
+// класса. Истёкший дедлайн доходит до клиента как 504 timeout.
+func defaultTimeout(next http.Handler) http.Handler {
+	return routeTimeout(next, func(t Timeouts) time.Duration { return t.Default })
+}
+
+func bulkTimeout(next http.Handler) http.Handler {
+	return routeTimeout(next, func(t Timeouts) time.Duration { return t.Bulk })
+}
+
+func routeTimeout(next http.Handler, pick func(Timeouts) time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, ok := r.Context().Value(timeoutsKey{}).(Timeouts)
+		if !ok {
+			t = DefaultTimeouts
+		}
+		d := pick(t)
+		if d <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
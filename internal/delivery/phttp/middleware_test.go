@@ -0,0 +1,182 @@
+package phttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+// syncBuffer — приёмник логов, безопасный для записи из обработчиков сервера.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// entries возвращает записанные строки лога с сообщением msg.
+func (b *syncBuffer) entries(t *testing.T, msg string) []map[string]interface{} {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var e map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &e), line)
+		if e["msg"] == msg {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// slowRepo ждёт отмены контекста в Get и List, как медленное хранилище.
+type slowRepo struct {
+	domen.TaskRepository
+}
+
+func (r slowRepo) Get(ctx context.Context, _ string) (*domen.Task, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (r slowRepo) List(ctx context.Context) ([]*domen.Task, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func setupMiddlewareServer(t *testing.T, repo domen.TaskRepository, timeouts Timeouts) (*httptest.Server, *syncBuffer) {
+	t.Helper()
+	logs := &syncBuffer{}
+	uc := usecase.NewTaskUseCase(repo, time.Hour)
+
+	r := chi.NewRouter()
+	r.Use(RequestID, AccessLog(logger.NewWithSink(zapcore.DebugLevel, logs)), Recoverer, WithTimeouts(timeouts))
+	r.Route("/v1", func(r chi.Router) {
+		MountV1(r, NewHandler(uc), NewBatchHandler(usecase.NewBatchUseCase(uc, memory.NewBatchRepo())))
+	})
+	r.Get("/panic", func(http.ResponseWriter, *http.Request) { panic("boom") })
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server, logs
+}
+
+func TestRequestID(t *testing.T) {
+	server, logs := setupMiddlewareServer(t, memory.NewInMemoryRepo(), DefaultTimeouts)
+
+	// Без заголовка ID генерируется
+	resp, _ := doRaw(t, http.MethodPost, server.URL+"/v1/tasks/", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	generated := resp.Header.Get(RequestIDHeader)
+	assert.NotEmpty(t, generated)
+
+	// ID клиента возвращается как есть
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/tasks/all", nil)
+	require.NoError(t, err)
+	req.Header.Set(RequestIDHeader, "client-id-1")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "client-id-1", resp.Header.Get(RequestIDHeader))
+
+	// Слишком длинный ID заменяется
+	req, err = http.NewRequest(http.MethodGet, server.URL+"/v1/tasks/all", nil)
+	require.NoError(t, err)
+	req.Header.Set(RequestIDHeader, strings.Repeat("a", maxRequestIDLen+1))
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	replaced := resp.Header.Get(RequestIDHeader)
+	assert.NotEmpty(t, replaced)
+	assert.NotEqual(t, strings.Repeat("a", maxRequestIDLen+1), replaced)
+
+	// ID попадает и в access-лог, и в строки, которые пишет обработчик
+	require.Eventually(t, func() bool { return len(logs.entries(t, "request completed")) == 3 }, time.Second, 10*time.Millisecond)
+	created := logs.entries(t, "task created")
+	require.Len(t, created, 1)
+	assert.Equal(t, generated, created[0]["request_id"])
+}
+
+func TestAccessLog(t *testing.T) {
+	server, logs := setupMiddlewareServer(t, memory.NewInMemoryRepo(), DefaultTimeouts)
+
+	resp, _ := doRaw(t, http.MethodGet, server.URL+"/v1/tasks/missing", "")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	var entries []map[string]interface{}
+	require.Eventually(t, func() bool {
+		entries = logs.entries(t, "request completed")
+		return len(entries) == 1
+	}, time.Second, 10*time.Millisecond)
+	e := entries[0]
+	assert.Equal(t, resp.Header.Get(RequestIDHeader), e["request_id"])
+	assert.Equal(t, "GET", e["method"])
+	assert.Equal(t, "/v1/tasks/missing", e["path"])
+	assert.Equal(t, "/v1/tasks/{id}", e["route"])
+	assert.EqualValues(t, http.StatusNotFound, e["status"])
+	assert.Contains(t, e, "duration_ms")
+	assert.Contains(t, e, "bytes")
+}
+
+func TestRecoverer(t *testing.T) {
+	server, logs := setupMiddlewareServer(t, memory.NewInMemoryRepo(), DefaultTimeouts)
+
+	resp, body := doRaw(t, http.MethodGet, server.URL+"/panic", "")
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, problemContentType, resp.Header.Get("Content-Type"))
+	var p Problem
+	require.NoError(t, json.Unmarshal(body, &p))
+	assert.Equal(t, CodeInternal, p.Code)
+	assert.Equal(t, "/panic", p.Instance)
+	assert.NotContains(t, string(body), "boom")
+
+	require.Eventually(t, func() bool { return len(logs.entries(t, "request completed")) == 1 }, time.Second, 10*time.Millisecond)
+	panics := logs.entries(t, "panic recovered")
+	require.Len(t, panics, 1)
+	assert.Equal(t, "boom", panics[0]["panic"])
+	assert.Equal(t, resp.Header.Get(RequestIDHeader), panics[0]["request_id"])
+	assert.EqualValues(t, http.StatusInternalServerError, logs.entries(t, "request completed")[0]["status"])
+}
+
+func TestTimeouts(t *testing.T) {
+	server, _ := setupMiddlewareServer(t, slowRepo{}, Timeouts{Default: 50 * time.Millisecond, Bulk: 300 * time.Millisecond})
+
+	start := time.Now()
+	resp, body := doRaw(t, http.MethodGet, server.URL+"/v1/tasks/1", "")
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	var p Problem
+	require.NoError(t, json.Unmarshal(body, &p))
+	assert.Equal(t, CodeTimeout, p.Code)
+	single := time.Since(start)
+
+	// Списки относятся к массовым операциям и получают свой, больший таймаут
+	start = time.Now()
+	resp, _ = doRaw(t, http.MethodGet, server.URL+"/v1/tasks/all", "")
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	bulk := time.Since(start)
+
+	assert.Less(t, single, 250*time.Millisecond)
+	assert.GreaterOrEqual(t, bulk, 300*time.Millisecond)
+}
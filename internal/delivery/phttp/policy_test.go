@@ -0,0 +1,192 @@
+package phttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/internal/auth"
+	"github.com/gaz358/myprog/workmate/internal/policy"
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Ключи пользователей alice и bob, оператора ops, наблюдателя viewer и
+// пользователя carol, который видит только свои задачи. Области доступа у
+// всех одинаковые: различаются только роли.
+const (
+	aliceKey  = "alice-secret"
+	bobKey    = "bob-secret"
+	opsKey    = "ops-secret"
+	viewerKey = "viewer-secret"
+	carolKey  = "carol-secret"
+)
+
+func setupPolicyServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	scopes := []string{domen.ScopeTasksRead, domen.ScopeTasksWrite, domen.ScopeTasksCancel}
+	keys, err := auth.NewKeyStore([]auth.Key{
+		{ID: "alice", SHA256: auth.HashKey(aliceKey), Scopes: scopes},
+		{ID: "bob", SHA256: auth.HashKey(bobKey), Scopes: scopes},
+		{ID: "ops", SHA256: auth.HashKey(opsKey), Scopes: scopes},
+		{ID: "viewer", SHA256: auth.HashKey(viewerKey), Scopes: scopes, Roles: []string{"viewer"}},
+		{ID: "carol", SHA256: auth.HashKey(carolKey), Scopes: scopes},
+	})
+	require.NoError(t, err)
+	pol, err := policy.New(policy.File{
+		DefaultRoles: []string{"user"},
+		Bindings:     map[string][]string{"ops": {"operator"}, "carol": {"private"}},
+		Roles: map[string][]policy.Rule{
+			"user": {
+				{Actions: []string{domen.ActionTaskRead, domen.ActionTaskCreate}},
+				{Actions: []string{domen.ActionTaskCancel, domen.ActionTaskDelete, domen.ActionTaskRerun}, Own: true},
+			},
+			"operator": {{Actions: []string{policy.AnyAction}}},
+			"viewer":   {{Actions: []string{domen.ActionTaskRead}}},
+			"private": {
+				{Actions: []string{domen.ActionTaskCreate}},
+				{Actions: []string{domen.ActionTaskRead}, Own: true},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	uc := usecase.NewTaskUseCase(memory.NewInMemoryRepo(), time.Hour)
+	uc.SetPolicy(pol)
+	batchUC := usecase.NewBatchUseCase(uc, memory.NewBatchRepo())
+	r := chi.NewRouter()
+	r.Route("/v1", func(r chi.Router) {
+		r.Use(Authenticate(keys))
+		MountV1(r, NewHandler(uc), NewBatchHandler(batchUC))
+	})
+	r.With(Authenticate(keys)).Mount("/v2", NewV2Handler(uc, batchUC).Routes())
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPolicy_Ownership(t *testing.T) {
+	server := setupPolicyServer(t)
+	create := func(key string) TaskV2 {
+		resp, body := doWithKey(t, http.MethodPost, server.URL+"/v2/tasks", key, "")
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+		var created struct {
+			Data TaskV2 `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &created))
+		return created.Data
+	}
+	alices, bobs := create(aliceKey), create(bobKey)
+
+	// Чужую задачу пользователь видит, но не отменяет и не удаляет
+	resp, _ := doWithKey(t, http.MethodGet, server.URL+"/v2/tasks/"+alices.ID, bobKey, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	for _, c := range []struct{ method, path string }{
+		{http.MethodPost, "/v2/tasks/" + alices.ID + "/cancel"},
+		{http.MethodPut, "/v1/tasks/" + alices.ID + "/cancel"},
+		{http.MethodDelete, "/v2/tasks/" + alices.ID},
+		{http.MethodDelete, "/v1/tasks/" + alices.ID},
+	} {
+		resp, body := doWithKey(t, c.method, server.URL+c.path, bobKey, "")
+		require.Equal(t, http.StatusForbidden, resp.StatusCode, "%s %s: %s", c.method, c.path, body)
+		var p Problem
+		require.NoError(t, json.Unmarshal(body, &p))
+		assert.Equal(t, CodeForbidden, p.Code)
+		require.NotNil(t, p.Denial)
+		assert.Equal(t, alices.ID, p.Denial.TaskID)
+		assert.Equal(t, []string{"user"}, p.Denial.Roles)
+		assert.Contains(t, p.Denial.Reason, "created by alice")
+	}
+
+	// Массовая отмена отменяет свою задачу и отказывает по чужой
+	resp, body := doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/bulk-cancel", bobKey,
+		`{"ids": ["`+alices.ID+`", "`+bobs.ID+`"]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var bulk struct {
+		Data []BulkItemV2 `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &bulk))
+	require.Len(t, bulk.Data, 2)
+	require.NotNil(t, bulk.Data[0].Error)
+	assert.Equal(t, CodeForbidden, bulk.Data[0].Error.Code)
+	assert.True(t, bulk.Data[1].OK)
+
+	resp, body = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/bulk-delete", bobKey, `{"ids": ["`+alices.ID+`"]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	require.NoError(t, json.Unmarshal(body, &bulk))
+	require.Len(t, bulk.Data, 1)
+	require.NotNil(t, bulk.Data[0].Error)
+	assert.Equal(t, CodeForbidden, bulk.Data[0].Error.Code)
+
+	// Повторить можно только свою задачу
+	resp, _ = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/"+bobs.ID+"/rerun", aliceKey, "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, _ = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/"+bobs.ID+"/rerun", bobKey, "")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// Оператор отменяет и удаляет любые задачи арендатора
+	resp, _ = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/"+alices.ID+"/cancel", opsKey, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = doWithKey(t, http.MethodDelete, server.URL+"/v2/tasks/"+alices.ID, opsKey, "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestPolicy_Roles(t *testing.T) {
+	server := setupPolicyServer(t)
+
+	// Области доступа разрешают создание, но роль viewer — нет
+	for _, path := range []string{"/v2/tasks", "/v1/tasks/"} {
+		resp, body := doWithKey(t, http.MethodPost, server.URL+path, viewerKey, "")
+		require.Equal(t, http.StatusForbidden, resp.StatusCode, string(body))
+		var p Problem
+		require.NoError(t, json.Unmarshal(body, &p))
+		require.NotNil(t, p.Denial)
+		assert.Equal(t, domen.ActionTaskCreate, p.Denial.Action)
+		assert.Equal(t, "roles [viewer] do not allow task:create", p.Denial.Reason)
+	}
+	for _, path := range []string{"/v2/tasks/batch", "/v2/batches"} {
+		resp, _ := doWithKey(t, http.MethodPost, server.URL+path, viewerKey, `{"count": 2}`)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, path)
+	}
+
+	resp, _ := doWithKey(t, http.MethodPost, server.URL+"/v2/tasks", aliceKey, "")
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp, body := doWithKey(t, http.MethodGet, server.URL+"/v2/tasks", viewerKey, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list struct {
+		Data []TaskV2 `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &list))
+	assert.Len(t, list.Data, 1)
+}
+
+func TestPolicy_BatchRead(t *testing.T) {
+	server := setupPolicyServer(t)
+	create := func(key string) string {
+		resp, body := doWithKey(t, http.MethodPost, server.URL+"/v2/batches", key, `{"count": 2}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+		var created struct {
+			Data BatchV2 `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &created))
+		return created.Data.ID
+	}
+	alices, carols := create(aliceKey), create(carolKey)
+
+	// Сводка по пакету раскрывает статусы задач: carol не читает чужие задачи,
+	// поэтому не видит и чужой пакет
+	for _, path := range []string{"/v2/batches/", "/v1/batches/"} {
+		resp, body := doWithKey(t, http.MethodGet, server.URL+path+alices, carolKey, "")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, "%s: %s", path, body)
+		resp, body = doWithKey(t, http.MethodGet, server.URL+path+carols, carolKey, "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "%s: %s", path, body)
+		resp, body = doWithKey(t, http.MethodGet, server.URL+path+carols, bobKey, "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "%s: %s", path, body)
+	}
+}
@@ -0,0 +1,116 @@
+package phttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+)
+
+const problemContentType = "application/problem+json"
+
+// Стабильные коды ошибок API. Клиенты опираются на них, а не на текст detail.
+const (
+	CodeNotFound           = "not_found"
+	CodeAlreadyExists      = "already_exists"
+	CodeConflict           = "conflict"
+	CodeInvalidTransition  = "invalid_transition"
+	CodeNotFinished        = "not_finished"
+	CodeInvalidArgument    = "invalid_argument"
+	CodePreconditionFailed = "precondition_failed"
+	CodeRateLimited        = "rate_limited"
+	CodeUnavailable        = "unavailable"
+	CodeUnauthenticated    = "unauthenticated"
+	CodeForbidden          = "forbidden"
+	CodeTimeout            = "timeout"
+	CodeInternal           = "internal"
+)
+
+// Problem — тело ответа об ошибке по RFC 7807 с расширением code.
+type Problem struct {
+	Type     string `json:"type" example:"/problems/not_found"`
+	Title    string `json:"title" example:"Not Found"`
+	Status   int    `json:"status" example:"404"`
+	Detail   string `json:"detail,omitempty" example:"task 42: not found"`
+	Instance string `json:"instance,omitempty" example:"/tasks/42"`
+	Code     string `json:"code" example:"not_found"`
+
+	// Errors lists invalid fields for invalid_argument problems
+	Errors []domen.FieldError `json:"errors,omitempty"`
+	// Denial explains why the access policy denied a forbidden action
+	Denial *domen.AccessDenial `json:"denial,omitempty"`
+}
+
+// problemKinds сопоставляет ошибкам домена статус и код. Порядок важен:
+// берётся первая ошибка, на которую указывает errors.Is.
+var problemKinds = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{domen.ErrNotFound, http.StatusNotFound, CodeNotFound},
+	{domen.ErrAlreadyExists, http.StatusConflict, CodeAlreadyExists},
+	{domen.ErrInvalidTransition, http.StatusConflict, CodeInvalidTransition},
+	{domen.ErrNotFinished, http.StatusConflict, CodeNotFinished},
+	{domen.ErrConflict, http.StatusConflict, CodeConflict},
+	{domen.ErrInvalidArgument, http.StatusBadRequest, CodeInvalidArgument},
+	{domen.ErrPreconditionFailed, http.StatusPreconditionFailed, CodePreconditionFailed},
+	{domen.ErrRateLimited, http.StatusTooManyRequests, CodeRateLimited},
+	{domen.ErrUnavailable, http.StatusServiceUnavailable, CodeUnavailable},
+	{domen.ErrUnauthenticated, http.StatusUnauthorized, CodeUnauthenticated},
+	{domen.ErrForbidden, http.StatusForbidden, CodeForbidden},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout},
+}
+
+// problemFor строит Problem по ошибке. Текст неизвестных ошибок наружу
+// не отдаётся.
+func problemFor(err error) Problem {
+	for _, k := range problemKinds {
+		if errors.Is(err, k.err) {
+			return newProblem(k.status, k.code, err.Error())
+		}
+	}
+	return newProblem(http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+func newProblem(status int, code, detail string) Problem {
+	return Problem{
+		Type:   "/problems/" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// writeError — единственное место, где ошибка превращается в HTTP-ответ.
+// Клиентские ошибки логируются как warn, серверные — как error.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	p := problemFor(err)
+	p.Instance = r.URL.Path
+	var verr *domen.ValidationError
+	if errors.As(err, &verr) {
+		p.Errors = verr.Fields
+	}
+	var denied *domen.AccessDeniedError
+	if errors.As(err, &denied) {
+		p.Denial = &denied.AccessDenial
+	}
+
+	lg := logger.FromContext(r.Context())
+	if p.Status >= http.StatusInternalServerError {
+		lg.Errorw("request failed", "method", r.Method, "path", r.URL.Path, "status", p.Status, "error", err)
+	} else {
+		lg.Warnw("request rejected", "method", r.Method, "path", r.URL.Path, "status", p.Status, "error", err)
+	}
+	writeProblem(w, p)
+}
+
+func writeProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,200 @@
+package phttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// faultyRepo возвращает err из всех методов. Пакетных методов у него нет,
+// поэтому use case идёт по одиночным вызовам.
+type faultyRepo struct {
+	domen.TaskRepository
+	err error
+}
+
+func (r faultyRepo) Create(context.Context, *domen.Task) error   { return r.err }
+func (r faultyRepo) Update(context.Context, *domen.Task) error   { return r.err }
+func (r faultyRepo) Delete(context.Context, string, int64) error { return r.err }
+func (r faultyRepo) Get(context.Context, string) (*domen.Task, error) {
+	return nil, r.err
+}
+func (r faultyRepo) List(context.Context) ([]*domen.Task, error) { return nil, r.err }
+func (r faultyRepo) Find(context.Context, domen.TaskFilter) ([]*domen.Task, error) {
+	return nil, r.err
+}
+
+type faultyBatches struct{ err error }
+
+func (r faultyBatches) Create(context.Context, *domen.Batch) error { return r.err }
+func (r faultyBatches) Get(context.Context, string) (*domen.Batch, error) {
+	return nil, r.err
+}
+func (r faultyBatches) Finish(context.Context, string, time.Time) error { return r.err }
+func (r faultyBatches) ListUnfinished(context.Context) ([]*domen.Batch, error) {
+	return nil, r.err
+}
+
+func newProblemServer(t *testing.T, repo domen.TaskRepository, batches domen.BatchRepository) *httptest.Server {
+	t.Helper()
+	// Длительность больше времени теста: созданные задачи остаются PENDING
+	uc := usecase.NewTaskUseCase(repo, time.Hour)
+	r := chi.NewRouter()
+	r.Mount("/tasks", NewHandler(uc).Routes())
+	r.Mount("/batches", NewBatchHandler(usecase.NewBatchUseCase(uc, batches)).Routes())
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+type problemCase struct {
+	name    string
+	method  string
+	path    string
+	body    string
+	ifMatch string
+	status  int
+	code    string
+}
+
+func checkProblems(t *testing.T, server *httptest.Server, cases []problemCase) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req, err := http.NewRequest(c.method, server.URL+c.path, strings.NewReader(c.body))
+			require.NoError(t, err)
+			if c.ifMatch != "" {
+				req.Header.Set("If-Match", c.ifMatch)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, c.status, resp.StatusCode)
+			assert.Equal(t, problemContentType, resp.Header.Get("Content-Type"))
+			var p Problem
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+			assert.Equal(t, c.status, p.Status)
+			assert.Equal(t, c.code, p.Code)
+			assert.Equal(t, "/problems/"+c.code, p.Type)
+			assert.Equal(t, http.StatusText(c.status), p.Title)
+			assert.Equal(t, c.path, p.Instance)
+			assert.NotEmpty(t, p.Detail)
+		})
+	}
+}
+
+func TestProblemFor(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{domen.ErrNotFound, http.StatusNotFound, CodeNotFound},
+		{domen.ErrAlreadyExists, http.StatusConflict, CodeAlreadyExists},
+		{domen.ErrConflict, http.StatusConflict, CodeConflict},
+		{&domen.TransitionError{From: domen.StatusCompleted, To: domen.StatusCancelled}, http.StatusConflict, CodeInvalidTransition},
+		{domen.ErrNotFinished, http.StatusConflict, CodeNotFinished},
+		{domen.ErrInvalidArgument, http.StatusBadRequest, CodeInvalidArgument},
+		{domen.ErrPreconditionFailed, http.StatusPreconditionFailed, CodePreconditionFailed},
+		{domen.ErrRateLimited, http.StatusTooManyRequests, CodeRateLimited},
+		{domen.ErrUnavailable, http.StatusServiceUnavailable, CodeUnavailable},
+		{domen.ErrUnauthenticated, http.StatusUnauthorized, CodeUnauthenticated},
+		{domen.ErrForbidden, http.StatusForbidden, CodeForbidden},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout},
+		{errors.New("boom"), http.StatusInternalServerError, CodeInternal},
+	}
+	for _, c := range cases {
+		p := problemFor(fmt.Errorf("task 1: %w", c.err))
+		assert.Equal(t, c.status, p.Status, c.err.Error())
+		assert.Equal(t, c.code, p.Code, c.err.Error())
+	}
+
+	// Текст внутренних ошибок наружу не попадает
+	p := problemFor(errors.New("password=secret"))
+	assert.NotContains(t, p.Detail, "secret")
+}
+
+func TestHandlers_ClientErrors(t *testing.T) {
+	repo := memory.NewInMemoryRepo()
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "pending", CreatedAt: time.Now(), Status: domen.StatusPending}))
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "done", CreatedAt: time.Now(), Status: domen.StatusCompleted}))
+	server := newProblemServer(t, repo, memory.NewBatchRepo())
+
+	checkProblems(t, server, []problemCase{
+		{"get missing", http.MethodGet, "/tasks/missing", "", "", http.StatusNotFound, CodeNotFound},
+		{"delete missing", http.MethodDelete, "/tasks/missing", "", "", http.StatusNotFound, CodeNotFound},
+		{"delete bad If-Match", http.MethodDelete, "/tasks/pending", "", "abc", http.StatusPreconditionFailed, CodePreconditionFailed},
+		{"delete stale If-Match", http.MethodDelete, "/tasks/pending", "", `"99"`, http.StatusPreconditionFailed, CodePreconditionFailed},
+		{"cancel missing", http.MethodPut, "/tasks/missing/cancel", "", "", http.StatusNotFound, CodeNotFound},
+		{"cancel bad If-Match", http.MethodPut, "/tasks/pending/cancel", "", "W/", http.StatusPreconditionFailed, CodePreconditionFailed},
+		{"cancel stale If-Match", http.MethodPut, "/tasks/pending/cancel", "", `"99"`, http.StatusPreconditionFailed, CodePreconditionFailed},
+		{"cancel completed", http.MethodPut, "/tasks/done/cancel", "", "", http.StatusConflict, CodeInvalidTransition},
+		{"history missing", http.MethodGet, "/tasks/missing/history", "", "", http.StatusNotFound, CodeNotFound},
+		{"rerun missing", http.MethodPost, "/tasks/missing/rerun", "", "", http.StatusNotFound, CodeNotFound},
+		{"rerun pending", http.MethodPost, "/tasks/pending/rerun", "", "", http.StatusConflict, CodeNotFinished},
+		{"reruns missing", http.MethodGet, "/tasks/missing/reruns", "", "", http.StatusNotFound, CodeNotFound},
+		{"batch bad body", http.MethodPost, "/tasks/batch", "{", "", http.StatusBadRequest, CodeInvalidArgument},
+		{"batch bad count", http.MethodPost, "/tasks/batch", `{"count":0}`, "", http.StatusBadRequest, CodeInvalidArgument},
+		{"bulk-cancel no selector", http.MethodPost, "/tasks/bulk-cancel", `{}`, "", http.StatusBadRequest, CodeInvalidArgument},
+		{"bulk-cancel bad body", http.MethodPost, "/tasks/bulk-cancel", `[]`, "", http.StatusBadRequest, CodeInvalidArgument},
+		{"bulk-delete both selectors", http.MethodPost, "/tasks/bulk-delete", `{"ids":["a"],"filter":{}}`, "", http.StatusBadRequest, CodeInvalidArgument},
+		{"create batch bad body", http.MethodPost, "/batches/", "{", "", http.StatusBadRequest, CodeInvalidArgument},
+		{"create batch bad count", http.MethodPost, "/batches/", `{"count":-1}`, "", http.StatusBadRequest, CodeInvalidArgument},
+		{"get batch missing", http.MethodGet, "/batches/missing", "", "", http.StatusNotFound, CodeNotFound},
+		{"cancel batch missing", http.MethodPut, "/batches/missing/cancel", "", "", http.StatusNotFound, CodeNotFound},
+	})
+}
+
+func TestHandlers_ServerErrors(t *testing.T) {
+	unavailable := fmt.Errorf("database is down: %w", domen.ErrUnavailable)
+	server := newProblemServer(t, faultyRepo{err: unavailable}, faultyBatches{err: unavailable})
+
+	checkProblems(t, server, []problemCase{
+		{"create", http.MethodPost, "/tasks/", "", "", http.StatusServiceUnavailable, CodeUnavailable},
+		{"get", http.MethodGet, "/tasks/1", "", "", http.StatusServiceUnavailable, CodeUnavailable},
+		{"list", http.MethodGet, "/tasks/all", "", "", http.StatusServiceUnavailable, CodeUnavailable},
+		{"delete", http.MethodDelete, "/tasks/1", "", "", http.StatusServiceUnavailable, CodeUnavailable},
+		{"cancel", http.MethodPut, "/tasks/1/cancel", "", "", http.StatusServiceUnavailable, CodeUnavailable},
+		{"history", http.MethodGet, "/tasks/1/history", "", "", http.StatusServiceUnavailable, CodeUnavailable},
+		{"rerun", http.MethodPost, "/tasks/1/rerun", "", "", http.StatusServiceUnavailable, CodeUnavailable},
+		{"reruns", http.MethodGet, "/tasks/1/reruns", "", "", http.StatusServiceUnavailable, CodeUnavailable},
+		{"bulk-cancel", http.MethodPost, "/tasks/bulk-cancel", `{"ids":["1"]}`, "", http.StatusServiceUnavailable, CodeUnavailable},
+		{"bulk-delete by filter", http.MethodPost, "/tasks/bulk-delete", `{"filter":{}}`, "", http.StatusServiceUnavailable, CodeUnavailable},
+		{"create batch", http.MethodPost, "/batches/", `{"count":1}`, "", http.StatusServiceUnavailable, CodeUnavailable},
+		{"get batch", http.MethodGet, "/batches/1", "", "", http.StatusServiceUnavailable, CodeUnavailable},
+		{"cancel batch", http.MethodPut, "/batches/1/cancel", "", "", http.StatusServiceUnavailable, CodeUnavailable},
+	})
+
+	internal := newProblemServer(t, faultyRepo{err: errors.New("disk on fire")}, memory.NewBatchRepo())
+	checkProblems(t, internal, []problemCase{
+		{"list internal", http.MethodGet, "/tasks/all", "", "", http.StatusInternalServerError, CodeInternal},
+	})
+}
+
+func TestHandlers_BulkItemErrors(t *testing.T) {
+	unavailable := fmt.Errorf("database is down: %w", domen.ErrUnavailable)
+	server := newProblemServer(t, faultyRepo{err: unavailable}, memory.NewBatchRepo())
+
+	// Ошибки отдельных задач не ломают ответ целиком
+	out := decodeBulk(t, postJSON(t, server.URL+"/tasks/bulk-delete", BulkRequest{IDs: []string{"a", "b"}}))
+	assert.Equal(t, 2, out.Failed)
+	for _, item := range out.Results {
+		assert.Equal(t, CodeUnavailable, item.Code)
+		assert.NotEmpty(t, item.Error)
+	}
+}
@@ -0,0 +1,84 @@
+package phttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rerunTask(t *testing.T, url string) (*http.Response, domen.Task) {
+	t.Helper()
+	resp, err := http.Post(url+"/rerun", "application/json", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var task domen.Task
+	if resp.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&task))
+	}
+	return resp, task
+}
+
+func TestTaskHandler_Rerun(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/", "application/json", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var orig domen.Task
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&orig))
+
+	// Незавершённую задачу повторить нельзя
+	notDone, _ := rerunTask(t, server.URL+"/"+orig.ID)
+	assert.Equal(t, http.StatusConflict, notDone.StatusCode)
+
+	// Ждём завершения задачи (длительность в тестах 200ms)
+	time.Sleep(400 * time.Millisecond)
+
+	first, rerun := rerunTask(t, server.URL+"/"+orig.ID)
+	require.Equal(t, http.StatusOK, first.StatusCode)
+	assert.NotEqual(t, orig.ID, rerun.ID)
+	assert.Equal(t, orig.ID, rerun.RerunOf)
+	assert.Equal(t, domen.StatusPending, rerun.Status)
+
+	time.Sleep(400 * time.Millisecond)
+	_, second := rerunTask(t, server.URL+"/"+rerun.ID)
+
+	// Исходная задача не изменилась
+	getResp, err := http.Get(server.URL + "/" + orig.ID)
+	require.NoError(t, err)
+	defer getResp.Body.Close()
+	var got domen.Task
+	require.NoError(t, json.NewDecoder(getResp.Body).Decode(&got))
+	assert.Equal(t, domen.StatusCompleted, got.Status)
+	assert.Empty(t, got.RerunOf)
+
+	lineageResp, err := http.Get(server.URL + "/" + orig.ID + "/reruns")
+	require.NoError(t, err)
+	defer lineageResp.Body.Close()
+	require.Equal(t, http.StatusOK, lineageResp.StatusCode)
+	var lineage []domen.Task
+	require.NoError(t, json.NewDecoder(lineageResp.Body).Decode(&lineage))
+	require.Len(t, lineage, 2)
+	assert.Equal(t, rerun.ID, lineage[0].ID)
+	assert.Equal(t, second.ID, lineage[1].ID)
+	assert.Equal(t, rerun.ID, lineage[1].RerunOf)
+}
+
+func TestTaskHandler_RerunNotFound(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	resp, _ := rerunTask(t, server.URL+"/missing")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	lineageResp, err := http.Get(server.URL + "/missing/reruns")
+	require.NoError(t, err)
+	lineageResp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, lineageResp.StatusCode)
+}
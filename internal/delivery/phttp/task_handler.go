@@ -3,8 +3,12 @@ package phttp
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gaz358/myprog/workmate/domen"
 	"github.com/gaz358/myprog/workmate/pkg/logger"
@@ -12,118 +16,156 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-type ErrorResponse struct {
-	Message string `json:"message" example:"something went wrong"`
-}
-
 var _ = domen.Task{}
 
 type Handler struct {
-	uc  *usecase.TaskUseCase
-	log logger.TypeOfLogger
+	uc *usecase.TaskUseCase
 }
 
 func NewHandler(uc *usecase.TaskUseCase) *Handler {
-	l := logger.Global().Named("http")
-	return &Handler{
-		uc:  uc,
-		log: l,
-	}
+	return &Handler{uc: uc}
 }
 
 func (h *Handler) Routes() http.Handler {
 	r := chi.NewRouter()
-	r.Post("/", h.create)
-	r.Get("/{id}", h.get)
-	r.Get("/all", h.list)
-
-	r.Delete("/{id}", h.delete)
-	r.Put("/{id}/cancel", h.cancel)
+	r.Group(func(r chi.Router) {
+		r.Use(defaultTimeout)
+		r.With(canWrite).Post("/", h.create)
+		r.With(canRead).Get("/{id}", h.get)
+		r.With(canWrite).Delete("/{id}", h.delete)
+		r.With(canCancel).Put("/{id}/cancel", h.cancel)
+		r.With(canRead).Get("/{id}/history", h.history)
+		r.With(canWrite).Post("/{id}/rerun", h.rerun)
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(bulkTimeout)
+		r.With(canWrite).Post("/batch", h.createBatch)
+		r.With(canCancel).Post("/bulk-cancel", h.bulkCancel)
+		r.With(canWrite).Post("/bulk-delete", h.bulkDelete)
+		r.With(canRead).Get("/all", h.list)
+		r.With(canRead).Get("/{id}/reruns", h.reruns)
+	})
 	r.Get("/health", h.Health) // health на корне API
 
 	return r
 }
 
+// CreateTaskRequest — тело POST /tasks. Тело можно не передавать,
+// тогда создаётся задача типа default без payload.
+type CreateTaskRequest struct {
+	// example: default
+	Type    string          `json:"type,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object"`
+}
+
+// RerunRequest — необязательное тело POST /tasks/{id}/rerun.
+type RerunRequest struct {
+	// Replaces the payload of the original task; checked against the schema of its type
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object"`
+}
+
+// decodeRerun читает необязательное тело запроса повтора.
+func decodeRerun(r *http.Request) (json.RawMessage, error) {
+	var req RerunRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return req.Payload, nil
+}
+
 // @Summary      Создать новую задачу
-// @Description  Инициализирует задачу со статусом Pending и возвращает её с сгенерированным ID
+// @Description  Инициализирует задачу со статусом Pending и возвращает её с сгенерированным ID. Payload проверяется по JSON Schema типа задачи
 // @Tags         tasks
+// @Security     ApiKey
+// @Accept       json
 // @Produce      json
-// @Success      200  {object}  domen.Task         "Задача успешно создана"
-// @Failure      500  {object}  ErrorResponse  "Внутренняя ошибка сервера"
-// @Router       /tasks [post]
+// @Param        request  body      CreateTaskRequest  false  "Тип и параметры задачи"
+// @Success      200  {object}  TaskV1             "Задача успешно создана"
+// @Header       200  {string}  ETag               "Версия задачи"
+// @Failure      400  {object}  Problem  "Неизвестный тип или payload не прошёл схему"
+// @Failure      403  {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      429  {object}  Problem  "Превышена квота арендатора"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка сервера"
+// @Router       /v1/tasks [post]
 func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
-	h.log.Infow("create task request", "method", r.Method, "path", r.URL.Path)
+	ctx := r.Context()
+	lg := logger.FromContext(ctx)
 
-	task, err := h.uc.CreateTask()
+	var req CreateTaskRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, err)
+		return
+	}
+
+	task, err := h.uc.CreateTask(ctx, req.Type, req.Payload)
 	if err != nil {
-		h.log.Errorw("failed to create task", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	h.log.Infow("task created", "id", task.ID)
-	writeJSON(w, task)
+	lg.Infow("task created", "id", task.ID, "type", task.Type)
+	setETag(w, task)
+	writeJSON(w, newTaskV1(task))
 }
 
 // @Summary      Получить задачу по ID
 // @Description  Возвращает задачу по её идентификатору
 // @Tags         tasks
+// @Security     ApiKey
 // @Produce      json
 // @Param        id   path      string            true  "ID задачи"
-// @Success      200  {object}  domen.Task        "Задача найдена"
-// @Failure      404  {object}  phttp.ErrorResponse  "Задача не найдена"
-// @Failure      500  {object}  phttp.ErrorResponse  "Внутренняя ошибка сервера"
-// @Router       /tasks/{id} [get]
+// @Success      200  {object}  TaskV1            "Задача найдена"
+// @Header       200  {string}  ETag              "Версия задачи"
+// @Failure      403  {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      404  {object}  Problem  "Задача не найдена"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка сервера"
+// @Router       /v1/tasks/{id} [get]
 func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lg := logger.FromContext(ctx)
 	id := chi.URLParam(r, "id")
-	h.log.Infow("get task request", "method", r.Method, "path", r.URL.Path, "id", id)
 
-	task, err := h.uc.GetTask(id)
+	task, err := h.uc.GetTask(ctx, id)
 	if err != nil {
-		if errors.Is(err, domen.ErrNotFound) {
-			h.log.Warnw("task not found", "id", id)
-			w.WriteHeader(http.StatusNotFound)
-			writeJSON(w, ErrorResponse{Message: "task not found"})
-			return
-		}
-
-		h.log.Errorw("failed to get task", "id", id, "error", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		writeJSON(w, ErrorResponse{Message: err.Error()})
+		writeError(w, r, fmt.Errorf("task %s: %w", id, err))
 		return
 	}
 
-	h.log.Infow("task retrieved", "id", task.ID)
-	writeJSON(w, task)
+	lg.Infow("task retrieved", "id", task.ID)
+	setETag(w, task)
+	writeJSON(w, newTaskV1(task))
 }
 
 // @Summary      Удалить задачу по ID
 // @Description  Удаляет задачу из системы по её идентификатору
 // @Tags         tasks
-// @Param        id   path      string            true  "ID задачи"
+// @Security     ApiKey
+// @Param        id        path      string  true   "ID задачи"
+// @Param        If-Match  header    string  false  "Ожидаемая версия задачи (ETag)"
 // @Success      204  "No Content"
-// @Failure      500  {object}  phttp.ErrorResponse  "Внутренняя ошибка сервера"
-// @Router       /tasks/{id} [delete]
+// @Failure      403  {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      404  {object}  Problem  "Задача не найдена"
+// @Failure      412  {object}  Problem  "Версия задачи не совпадает с If-Match"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка сервера"
+// @Router       /v1/tasks/{id} [delete]
 func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lg := logger.FromContext(ctx)
 	id := chi.URLParam(r, "id")
-	h.log.Infow("delete task request", "method", r.Method, "path", r.URL.Path, "id", id)
 
-	err := h.uc.DeleteTask(id)
+	version, err := parseIfMatch(r)
 	if err != nil {
-		if errors.Is(err, domen.ErrNotFound) {
-			h.log.Warnw("task not found", "id", id)
-			w.WriteHeader(http.StatusNotFound)
-			writeJSON(w, ErrorResponse{Message: "task not found"})
-			return
-		}
+		writeError(w, r, err)
+		return
+	}
 
-		h.log.Errorw("failed to delete task", "id", id, "error", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		writeJSON(w, ErrorResponse{Message: err.Error()})
+	err = h.uc.DeleteTask(ctx, id, version)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("task %s: %w", id, err))
 		return
 	}
 
-	h.log.Infow("task deleted", "id", id)
+	lg.Infow("task deleted", "id", id)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -132,18 +174,48 @@ func writeJSON(w http.ResponseWriter, v interface{}) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// decodeJSON читает тело запроса; ошибка разбора — domen.ErrInvalidArgument.
+// Для пустого тела ошибка также оборачивает io.EOF.
+func decodeJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid request body: %w", domen.ErrInvalidArgument, err)
+	}
+	return nil
+}
+
+// setETag выставляет ETag с версией задачи, например "3".
+func setETag(w http.ResponseWriter, task *domen.Task) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(task.Version, 10)))
+}
+
+// parseIfMatch возвращает версию из заголовка If-Match.
+// Отсутствующий заголовок и "*" дают 0 (версия не проверяется).
+func parseIfMatch(r *http.Request) (int64, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return 0, nil
+	}
+	v = strings.Trim(strings.TrimPrefix(v, "W/"), `"`)
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("%w: invalid If-Match header %q", domen.ErrPreconditionFailed, r.Header.Get("If-Match"))
+	}
+	return version, nil
+}
+
 // @Summary      Получить список всех задач
 // @Tags         tasks
+// @Security     ApiKey
 // @Produce      json
 // @Success      200  {array}  domen.TaskListItem
-// @Failure      500  {object}  ErrorResponse
-// @Router       /tasks/all [get]
+// @Failure      500  {object}  Problem
+// @Router       /v1/tasks/all [get]
 func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
-	tasks, err := h.uc.ListTasks()
+	ctx := r.Context()
+
+	tasks, err := h.uc.ListTasks(ctx)
 	if err != nil {
-		h.log.Errorw("failed to list tasks", "error", err)
-		writeJSON(w, ErrorResponse{Message: err.Error()})
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -165,35 +237,142 @@ func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
 // @Summary      Отменить задачу
 // @Description  Прерывает выполнение задачи, если она ещё не завершена
 // @Tags         tasks
-// @Param        id   path      string  true  "ID задачи"
+// @Security     ApiKey
+// @Param        id        path      string  true   "ID задачи"
+// @Param        If-Match  header    string  false  "Ожидаемая версия задачи (ETag)"
 // @Success      200  {object}  map[string]string  "Задача отменена"
-// @Failure      404  {object}  ErrorResponse       "Задача не найдена"
-// @Failure      500  {object}  ErrorResponse       "Внутренняя ошибка"
-// @Router       /tasks/{id}/cancel [put]
+// @Header       200  {string}  ETag               "Новая версия задачи"
+// @Failure      403  {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      404  {object}  Problem  "Задача не найдена"
+// @Failure      409  {object}  Problem  "Задача уже завершена"
+// @Failure      412  {object}  Problem  "Версия задачи не совпадает с If-Match"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v1/tasks/{id}/cancel [put]
 func (h *Handler) cancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lg := logger.FromContext(ctx)
 	id := chi.URLParam(r, "id")
-	h.log.Infow("cancel task request", "method", r.Method, "path", r.URL.Path, "id", id)
 
-	err := h.uc.CancelTask(id)
+	version, err := parseIfMatch(r)
 	if err != nil {
-		if errors.Is(err, domen.ErrNotFound) {
-			h.log.Warnw("task not found", "id", id)
-			w.WriteHeader(http.StatusNotFound)
-			writeJSON(w, ErrorResponse{Message: "task not found"})
-			return
-		}
-		h.log.Errorw("failed to cancel task", "id", id, "error", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		writeJSON(w, ErrorResponse{Message: err.Error()})
+		writeError(w, r, err)
 		return
 	}
 
-	h.log.Infow("task canceled", "id", id)
+	task, err := h.uc.CancelTask(ctx, id, version)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("task %s: %w", id, err))
+		return
+	}
+
+	lg.Infow("task canceled", "id", id)
+	setETag(w, task)
 	writeJSON(w, map[string]string{"status": "canceled"})
 }
 
+// @Summary      История статусов задачи
+// @Description  Возвращает все переходы статуса задачи с временем и причиной
+// @Tags         tasks
+// @Security     ApiKey
+// @Produce      json
+// @Param        id   path      string  true  "ID задачи"
+// @Success      200  {array}   domen.Transition
+// @Failure      403  {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      404  {object}  Problem  "Задача не найдена"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v1/tasks/{id}/history [get]
+func (h *Handler) history(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	history, err := h.uc.GetTaskHistory(ctx, id)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("task %s: %w", id, err))
+		return
+	}
+
+	writeJSON(w, history)
+}
+
+// @Summary      Повторить задачу
+// @Description  Создаёт и запускает новую задачу, связанную с завершённой задачей через rerun_of. Исходная задача не меняется. Payload из тела заменяет payload исходной задачи и проверяется по схеме её типа
+// @Tags         tasks
+// @Security     ApiKey
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string        true   "ID исходной задачи"
+// @Param        request  body      RerunRequest  false  "Новый payload"
+// @Success      200  {object}  TaskV1         "Новая задача"
+// @Header       200  {string}  ETag           "Версия задачи"
+// @Failure      400  {object}  Problem  "Payload не прошёл схему типа"
+// @Failure      403  {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      404  {object}  Problem  "Задача не найдена"
+// @Failure      409  {object}  Problem  "Задача ещё не завершена"
+// @Failure      429  {object}  Problem  "Превышена квота арендатора"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v1/tasks/{id}/rerun [post]
+func (h *Handler) rerun(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lg := logger.FromContext(ctx)
+	id := chi.URLParam(r, "id")
+
+	payload, err := decodeRerun(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	task, err := h.uc.RerunTask(ctx, id, payload)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("task %s: %w", id, err))
+		return
+	}
+
+	lg.Infow("task rerun created", "id", task.ID, "rerun_of", id)
+	setETag(w, task)
+	writeJSON(w, newTaskV1(task))
+}
+
+// @Summary      Повторы задачи
+// @Description  Возвращает все повторы задачи, включая повторы повторов, в порядке создания
+// @Tags         tasks
+// @Security     ApiKey
+// @Produce      json
+// @Param        id   path      string  true  "ID исходной задачи"
+// @Success      200  {array}   TaskV1
+// @Failure      403  {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      404  {object}  Problem  "Задача не найдена"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v1/tasks/{id}/reruns [get]
+func (h *Handler) reruns(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	tasks, err := h.uc.ListReruns(ctx, id)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("task %s: %w", id, err))
+		return
+	}
+
+	out := make([]TaskV1, len(tasks))
+	for i, t := range tasks {
+		out[i] = newTaskV1(t)
+	}
+	writeJSON(w, out)
+}
+
+// @Summary      Типы задач
+// @Description  Возвращает зарегистрированные типы задач с JSON Schema их payload
+// @Tags         task-types
+// @Security     ApiKey
+// @Produce      json
+// @Success      200  {array}  domen.TaskType
+// @Router       /v1/task-types [get]
+func (h *Handler) TaskTypes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.uc.TaskTypes())
+}
+
 // @Summary      Healthcheck
-// @Description  Проверка доступности сервиса
+// @Description  Всегда отвечает ok. Устарел: используйте /livez и /readyz
 // @Tags         health
 // @Produce      plain
 // @Success      200 {string} string "ok"
@@ -0,0 +1,308 @@
+package phttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reportSchema = `{
+	"type": "object",
+	"required": ["format", "pages"],
+	"properties": {
+		"format": {"enum": ["pdf", "csv"]},
+		"pages": {"type": "integer", "minimum": 1}
+	},
+	"additionalProperties": false
+}`
+
+func setupTypedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	uc := usecase.NewTaskUseCase(memory.NewInMemoryRepo(), 200*time.Millisecond)
+	require.NoError(t, uc.RegisterTaskType(domen.TaskType{
+		Name:        "report",
+		Description: "Build a report",
+		Schema:      json.RawMessage(reportSchema),
+	}))
+	h := NewHandler(uc)
+	batches := usecase.NewBatchUseCase(uc, memory.NewBatchRepo())
+	r := chi.NewRouter()
+	r.Mount("/tasks", h.Routes())
+	r.Get("/task-types", h.TaskTypes)
+	r.Mount("/batches", NewBatchHandler(batches).Routes())
+	r.Mount("/v2", NewV2Handler(uc, batches).Routes())
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func createTyped(t *testing.T, server *httptest.Server, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(server.URL+"/tasks/", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	return resp
+}
+
+func TestTaskHandler_CreateTyped(t *testing.T) {
+	server := setupTypedServer(t)
+
+	resp := createTyped(t, server, `{"type":"report","payload":{"format":"pdf","pages":3}}`)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var task domen.Task
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&task))
+	assert.Equal(t, "report", task.Type)
+	assert.JSONEq(t, `{"format":"pdf","pages":3}`, string(task.Payload))
+
+	// Без тела создаётся задача типа default
+	plain := createTyped(t, server, "")
+	defer plain.Body.Close()
+	require.Equal(t, http.StatusOK, plain.StatusCode)
+	var def domen.Task
+	require.NoError(t, json.NewDecoder(plain.Body).Decode(&def))
+	assert.Equal(t, domen.DefaultTaskType, def.Type)
+}
+
+func TestTaskHandler_CreateInvalidPayload(t *testing.T) {
+	server := setupTypedServer(t)
+
+	cases := []struct {
+		name   string
+		body   string
+		fields []string
+	}{
+		{"unknown type", `{"type":"nope"}`, []string{"/type"}},
+		{"missing required", `{"type":"report","payload":{"format":"pdf"}}`, []string{"/payload"}},
+		{"wrong fields", `{"type":"report","payload":{"format":"doc","pages":0}}`, []string{"/payload/format", "/payload/pages"}},
+		{"extra field", `{"type":"report","payload":{"format":"csv","pages":1,"x":1}}`, []string{"/payload"}},
+		{"payload not object", `{"payload":[1]}`, []string{"/payload"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp := createTyped(t, server, c.body)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			var p Problem
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+			assert.Equal(t, CodeInvalidArgument, p.Code)
+
+			fields := make([]string, len(p.Errors))
+			for i, e := range p.Errors {
+				fields[i] = e.Field
+				assert.NotEmpty(t, e.Message)
+			}
+			assert.ElementsMatch(t, c.fields, fields)
+		})
+	}
+}
+
+func TestTaskHandler_CreateManyTyped(t *testing.T) {
+	server := setupTypedServer(t)
+
+	// Каждая ручка возвращает ID созданных задач в своём формате
+	endpoints := []struct {
+		path   string
+		status int
+		ids    func(body []byte) []string
+	}{
+		{"/tasks/batch", http.StatusOK, func(body []byte) []string {
+			var resp BulkResponse
+			require.NoError(t, json.Unmarshal(body, &resp))
+			ids := make([]string, len(resp.Results))
+			for i, res := range resp.Results {
+				ids[i] = res.ID
+			}
+			return ids
+		}},
+		{"/batches/", http.StatusOK, func(body []byte) []string {
+			var batch domen.Batch
+			require.NoError(t, json.Unmarshal(body, &batch))
+			return batch.TaskIDs
+		}},
+		{"/v2/tasks/batch", http.StatusOK, func(body []byte) []string {
+			var resp struct {
+				Data []BulkItemV2 `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(body, &resp))
+			ids := make([]string, len(resp.Data))
+			for i, item := range resp.Data {
+				require.True(t, item.OK)
+				ids[i] = item.ID
+			}
+			return ids
+		}},
+		{"/v2/batches", http.StatusCreated, func(body []byte) []string {
+			var resp struct {
+				Data BatchV2 `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(body, &resp))
+			return resp.Data.TaskIDs
+		}},
+	}
+	for _, e := range endpoints {
+		t.Run(e.path, func(t *testing.T) {
+			resp, err := http.Post(server.URL+e.path, "application/json",
+				strings.NewReader(`{"count":2,"type":"report","payload":{"format":"pdf","pages":3}}`))
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			require.NoError(t, err)
+			require.Equal(t, e.status, resp.StatusCode, string(body))
+
+			ids := e.ids(body)
+			require.Len(t, ids, 2)
+			for _, id := range ids {
+				resp, err := http.Get(server.URL + "/tasks/" + id)
+				require.NoError(t, err)
+				var task domen.Task
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&task))
+				resp.Body.Close()
+				assert.Equal(t, "report", task.Type)
+				assert.JSONEq(t, `{"format":"pdf","pages":3}`, string(task.Payload))
+			}
+
+			// Ошибки схемы — тот же список полей, что и при создании одной задачи
+			resp, err = http.Post(server.URL+e.path, "application/json",
+				strings.NewReader(`{"count":2,"type":"report","payload":{"format":"doc","pages":1}}`))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			var p Problem
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+			require.Len(t, p.Errors, 1)
+			assert.Equal(t, "/payload/format", p.Errors[0].Field)
+		})
+	}
+
+	// Ни одна задача с некорректным payload не создана
+	resp, err := http.Get(server.URL + "/tasks/all")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var tasks []domen.Task
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tasks))
+	assert.Len(t, tasks, 8)
+}
+
+func TestTaskHandler_BulkByQueue(t *testing.T) {
+	server := setupTypedServer(t)
+
+	var reports []string
+	for i := 0; i < 2; i++ {
+		resp := createTyped(t, server, `{"type":"report","payload":{"format":"pdf","pages":1}}`)
+		var task domen.Task
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&task))
+		resp.Body.Close()
+		reports = append(reports, task.ID)
+	}
+	resp := createTyped(t, server, "")
+	resp.Body.Close()
+
+	resp = postJSON(t, server.URL+"/tasks/bulk-cancel", BulkRequest{Filter: &BulkFilter{Queues: []string{"report"}}})
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var bulk BulkResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&bulk))
+	got := make([]string, len(bulk.Results))
+	for i, res := range bulk.Results {
+		got[i] = res.ID
+	}
+	assert.ElementsMatch(t, reports, got)
+	assert.Equal(t, 2, bulk.Succeeded)
+}
+
+func TestTaskHandler_RerunKeepsPayload(t *testing.T) {
+	server := setupTypedServer(t)
+
+	resp := createTyped(t, server, `{"type":"report","payload":{"format":"csv","pages":1}}`)
+	defer resp.Body.Close()
+	var orig domen.Task
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&orig))
+
+	// Ждём завершения задачи (длительность в тестах 200ms)
+	time.Sleep(400 * time.Millisecond)
+
+	_, rerun := rerunTask(t, server.URL+"/tasks/"+orig.ID)
+	assert.Equal(t, "report", rerun.Type)
+	assert.JSONEq(t, string(orig.Payload), string(rerun.Payload))
+}
+
+func TestTaskHandler_RerunPayloadOverride(t *testing.T) {
+	server := setupTypedServer(t)
+
+	resp := createTyped(t, server, `{"type":"report","payload":{"format":"csv","pages":1}}`)
+	defer resp.Body.Close()
+	var orig domen.Task
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&orig))
+	time.Sleep(400 * time.Millisecond)
+
+	rerun := func(url, body string) (*http.Response, domen.Task) {
+		t.Helper()
+		resp, err := http.Post(url, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var task domen.Task
+		if resp.StatusCode == http.StatusBadRequest {
+			var p Problem
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+			require.Len(t, p.Errors, 1)
+			assert.Equal(t, "/payload/pages", p.Errors[0].Field)
+			return resp, task
+		}
+		if strings.Contains(url, "/v2/") {
+			var env struct {
+				Data TaskV2 `json:"data"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&env))
+			return resp, domen.Task{ID: env.Data.ID, Type: env.Data.Type, Payload: env.Data.Payload, RerunOf: env.Data.RerunOf}
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&task))
+		return resp, task
+	}
+
+	for _, url := range []string{server.URL + "/tasks/" + orig.ID + "/rerun", server.URL + "/v2/tasks/" + orig.ID + "/rerun"} {
+		resp, task := rerun(url, `{"payload":{"format":"pdf","pages":7}}`)
+		require.Less(t, resp.StatusCode, 300, url)
+		assert.Equal(t, orig.ID, task.RerunOf)
+		assert.Equal(t, "report", task.Type)
+		assert.JSONEq(t, `{"format":"pdf","pages":7}`, string(task.Payload))
+
+		// Новый payload проверяется по схеме типа исходной задачи
+		resp, _ = rerun(url, `{"payload":{"format":"pdf","pages":0}}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, url)
+
+		// Пустое тело и тело без payload оставляют payload исходной задачи
+		for _, body := range []string{"", "{}"} {
+			resp, task = rerun(url, body)
+			require.Less(t, resp.StatusCode, 300, url)
+			assert.JSONEq(t, string(orig.Payload), string(task.Payload))
+		}
+	}
+}
+
+func TestTaskHandler_TaskTypes(t *testing.T) {
+	server := setupTypedServer(t)
+
+	resp, err := http.Get(server.URL + "/task-types")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var types []domen.TaskType
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&types))
+	require.Len(t, types, 2)
+	assert.Equal(t, domen.DefaultTaskType, types[0].Name)
+	assert.Equal(t, "report", types[1].Name)
+	assert.Equal(t, "Build a report", types[1].Description)
+	assert.JSONEq(t, reportSchema, string(types[1].Schema))
+}
@@ -0,0 +1,185 @@
+package phttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/internal/auth"
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Ключи арендаторов: team-a и team-b со всеми правами на задачи.
+const (
+	teamAKey = "team-a-secret"
+	teamBKey = "team-b-secret"
+)
+
+func setupTenantServer(t *testing.T, quotas usecase.Quotas) *httptest.Server {
+	t.Helper()
+	return setupTenantServerWithRepo(t, memory.NewInMemoryRepo(), quotas)
+}
+
+func setupTenantServerWithRepo(t *testing.T, repo domen.TaskRepository, quotas usecase.Quotas) *httptest.Server {
+	t.Helper()
+	scopes := []string{domen.ScopeTasksRead, domen.ScopeTasksWrite, domen.ScopeTasksCancel}
+	keys, err := auth.NewKeyStore([]auth.Key{
+		{ID: "ci-a", SHA256: auth.HashKey(teamAKey), Scopes: scopes, Tenant: "team-a"},
+		{ID: "ci-b", SHA256: auth.HashKey(teamBKey), Scopes: scopes, Tenant: "team-b"},
+	})
+	require.NoError(t, err)
+
+	uc := usecase.NewTaskUseCase(repo, time.Hour)
+	uc.SetQuotas(quotas)
+	batchUC := usecase.NewBatchUseCase(uc, memory.NewBatchRepo())
+	r := chi.NewRouter()
+	r.Route("/v1", func(r chi.Router) {
+		r.Use(Authenticate(keys))
+		MountV1(r, NewHandler(uc), NewBatchHandler(batchUC))
+	})
+	r.With(Authenticate(keys)).Mount("/v2", NewV2Handler(uc, batchUC).Routes())
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTenant_Isolation(t *testing.T) {
+	server := setupTenantServer(t, usecase.Quotas{})
+
+	resp, body := doWithKey(t, http.MethodPost, server.URL+"/v2/tasks", teamAKey, "")
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	var created struct {
+		Data TaskV2 `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &created))
+	task := created.Data
+	assert.Equal(t, "team-a", task.TenantID)
+
+	resp, body = doWithKey(t, http.MethodPost, server.URL+"/v2/batches", teamAKey, `{"count": 2}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	var batch struct {
+		Data BatchV2 `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &batch))
+
+	// Чужие задачи и пакеты для team-b не существуют
+	for _, c := range []struct{ method, path string }{
+		{http.MethodGet, "/v2/tasks/" + task.ID},
+		{http.MethodGet, "/v1/tasks/" + task.ID},
+		{http.MethodPost, "/v2/tasks/" + task.ID + "/cancel"},
+		{http.MethodDelete, "/v2/tasks/" + task.ID},
+		{http.MethodGet, "/v2/batches/" + batch.Data.ID},
+		{http.MethodPost, "/v2/batches/" + batch.Data.ID + "/cancel"},
+	} {
+		resp, body := doWithKey(t, c.method, server.URL+c.path, teamBKey, "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, "%s %s: %s", c.method, c.path, body)
+	}
+
+	resp, body = doWithKey(t, http.MethodGet, server.URL+"/v2/tasks", teamBKey, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list struct {
+		Data []TaskV2 `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &list))
+	assert.Empty(t, list.Data)
+
+	resp, body = doWithKey(t, http.MethodGet, server.URL+"/v1/tasks/all", teamBKey, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotContains(t, string(body), task.ID)
+
+	// Массовая отмена по ID не задевает чужие задачи
+	resp, body = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/bulk-cancel", teamBKey, `{"ids": ["`+task.ID+`"]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var bulk struct {
+		Data []BulkItemV2 `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &bulk))
+	require.Len(t, bulk.Data, 1)
+	require.NotNil(t, bulk.Data[0].Error)
+	assert.Equal(t, CodeNotFound, bulk.Data[0].Error.Code)
+
+	// Свои задачи арендатор видит: одна одиночная и две из пакета
+	resp, body = doWithKey(t, http.MethodGet, server.URL+"/v2/tasks", teamAKey, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.Unmarshal(body, &list))
+	assert.Len(t, list.Data, 3)
+	resp, _ = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/"+task.ID+"/cancel", teamAKey, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestTenant_Quotas(t *testing.T) {
+	server := setupTenantServer(t, usecase.Quotas{
+		Tenants: map[string]usecase.Quota{
+			"team-a": {MaxPending: 1, MaxRunning: 1},
+			"team-b": {PerMinute: 3},
+		},
+	})
+	create := func(key string) int {
+		resp, _ := doWithKey(t, http.MethodPost, server.URL+"/v2/tasks", key, "")
+		return resp.StatusCode
+	}
+	statuses := func(key string) []domen.Status {
+		resp, body := doWithKey(t, http.MethodGet, server.URL+"/v2/tasks", key, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var list struct {
+			Data []TaskV2 `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &list))
+		out := make([]domen.Status, len(list.Data))
+		for i, task := range list.Data {
+			out[i] = task.Status
+		}
+		return out
+	}
+
+	// Первая задача team-a выполняется, вторая ждёт свободного места в PENDING
+	require.Equal(t, http.StatusCreated, create(teamAKey))
+	require.Eventually(t, func() bool {
+		s := statuses(teamAKey)
+		return len(s) == 1 && s[0] == domen.StatusRunning
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, http.StatusCreated, create(teamAKey))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, []domen.Status{domen.StatusRunning, domen.StatusPending}, statuses(teamAKey),
+		"MaxRunning держит вторую задачу в очереди")
+
+	// Очередь team-a заполнена
+	resp, body := doWithKey(t, http.MethodPost, server.URL+"/v2/tasks", teamAKey, "")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	var p Problem
+	require.NoError(t, json.Unmarshal(body, &p))
+	assert.Equal(t, CodeRateLimited, p.Code)
+
+	// Квота team-b — 3 задачи в минуту, пакет целиком в неё не влезает
+	resp, _ = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/batch", teamBKey, `{"count": 4}`)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	resp, _ = doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/batch", teamBKey, `{"count": 3}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, http.StatusTooManyRequests, create(teamBKey))
+}
+
+func TestTenant_QuotaRefund(t *testing.T) {
+	// Каждое второе сохранение падает: несозданные задачи не должны съедать квоту
+	repo := &flakyRepo{TaskRepository: memory.NewInMemoryRepo(), failAt: 2}
+	server := setupTenantServerWithRepo(t, repo, usecase.Quotas{
+		Tenants: map[string]usecase.Quota{"team-b": {PerMinute: 3}},
+	})
+	create := func() int {
+		resp, _ := doWithKey(t, http.MethodPost, server.URL+"/v2/tasks", teamBKey, "")
+		return resp.StatusCode
+	}
+
+	// Из трёх задач пакета сохраняются две, одна единица квоты возвращается
+	resp, body := doWithKey(t, http.MethodPost, server.URL+"/v2/tasks/batch", teamBKey, `{"count": 3}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, http.StatusInternalServerError, create())
+	assert.Equal(t, http.StatusCreated, create())
+	assert.Equal(t, http.StatusTooManyRequests, create())
+}
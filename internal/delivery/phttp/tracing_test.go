@@ -0,0 +1,101 @@
+package phttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/internal/tracing"
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanByName(spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, s := range spans {
+		if s.Name() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestTrace(t *testing.T) {
+	// Глобальный провайдер ставится один раз: трейсеры, полученные до
+	// первого SetTracerProvider, к следующим не переключаются
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	repo := memory.NewInMemoryRepo()
+	uc := usecase.NewTaskUseCase(tracing.InstrumentRepository(repo), 50*time.Millisecond)
+	r := chi.NewRouter()
+	r.Use(Trace)
+	r.Route("/v1", func(r chi.Router) {
+		MountV1(r, NewHandler(uc), NewBatchHandler(usecase.NewBatchUseCase(uc, memory.NewBatchRepo())))
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/tasks/", nil)
+	require.NoError(t, err)
+	req.Header.Set("traceparent", parent)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var task domen.Task
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&task))
+
+	var run sdktrace.ReadOnlySpan
+	require.Eventually(t, func() bool {
+		run = spanByName(recorder.Ended(), "task.run")
+		return run != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	spans := recorder.Ended()
+
+	// Серверный span продолжает входящий трейс и назван по маршруту
+	server1 := spanByName(spans, "POST /v1/tasks")
+	require.NotNil(t, server1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server1.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server1.Parent().SpanID().String())
+
+	// Use case вложен в запрос, репозиторий — в use case
+	create := spanByName(spans, "TaskUseCase.CreateTask")
+	require.NotNil(t, create)
+	assert.Equal(t, server1.SpanContext().SpanID(), create.Parent().SpanID())
+	repoCreate := spanByName(spans, "repository.Create")
+	require.NotNil(t, repoCreate)
+	assert.Equal(t, create.SpanContext().SpanID(), repoCreate.Parent().SpanID())
+
+	// Выполнение — отдельный трейс со ссылкой на создавший задачу span
+	assert.NotEqual(t, server1.SpanContext().TraceID(), run.SpanContext().TraceID())
+	require.Len(t, run.Links(), 1)
+	assert.Equal(t, create.SpanContext().SpanID(), run.Links()[0].SpanContext.SpanID())
+	assert.Equal(t, create.SpanContext().TraceID(), run.Links()[0].SpanContext.TraceID())
+
+	// Контекст трейса сохранён в задаче
+	stored, err := repo.Get(context.Background(), task.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.TraceContext, "00-4bf92f3577b34da6a3ce929d0e0e4736-"), stored.TraceContext)
+
+	// Обращения к репозиторию при выполнении попадают в трейс выполнения
+	var runUpdates int
+	for _, s := range spans {
+		if s.Name() == "repository.Update" && s.SpanContext().TraceID() == run.SpanContext().TraceID() {
+			runUpdates++
+		}
+	}
+	assert.Equal(t, 2, runUpdates)
+}
@@ -0,0 +1,194 @@
+package phttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тесты совместимости v1: фиксируют форму ответов, на которую опираются
+// существующие клиенты. Если тест упал — менять нужно /v2, а не ожидания здесь.
+
+func setupVersionedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	uc := usecase.NewTaskUseCase(memory.NewInMemoryRepo(), 200*time.Millisecond)
+	batchUC := usecase.NewBatchUseCase(uc, memory.NewBatchRepo())
+	tasks, batches := NewHandler(uc), NewBatchHandler(batchUC)
+
+	r := chi.NewRouter()
+	r.Route("/v1", func(r chi.Router) { MountV1(r, tasks, batches) })
+	r.Mount("/v2", NewV2Handler(uc, batchUC).Routes())
+	r.Group(func(r chi.Router) {
+		r.Use(Deprecated("/v1"))
+		MountV1(r, tasks, batches)
+	})
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func doRaw(t *testing.T, method, url, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, data
+}
+
+func keysOf(t *testing.T, data []byte) []string {
+	t.Helper()
+	var m map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &m), string(data))
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestV1_Compatibility(t *testing.T) {
+	server := setupVersionedServer(t)
+	base := server.URL + "/v1"
+
+	// Пустой список — null, а не []
+	resp, body := doRaw(t, http.MethodGet, base+"/tasks/all", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "null", strings.TrimSpace(string(body)))
+
+	// Создание: 200 и задача без обёртки, нулевые времена не опускаются
+	resp, body = doRaw(t, http.MethodPost, base+"/tasks/", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+	assert.Equal(t, []string{"created_at", "ended_at", "id", "started_at", "status", "type", "version"}, keysOf(t, body))
+	var created struct {
+		ID        string `json:"id"`
+		Status    string `json:"status"`
+		StartedAt string `json:"started_at"`
+	}
+	require.NoError(t, json.Unmarshal(body, &created))
+	assert.Equal(t, "PENDING", created.Status)
+	assert.Equal(t, "0001-01-01T00:00:00Z", created.StartedAt)
+
+	// Отмена: фиксированный ответ вместо задачи
+	_, body = doRaw(t, http.MethodPost, base+"/tasks/", "")
+	var second struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(body, &second))
+	resp, body = doRaw(t, http.MethodPut, base+"/tasks/"+second.ID+"/cancel", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"status":"canceled"}`, string(body))
+
+	time.Sleep(400 * time.Millisecond)
+
+	// Список: только id, status и duration у завершённых задач
+	_, body = doRaw(t, http.MethodGet, base+"/tasks/all", "")
+	var items []json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &items))
+	require.Len(t, items, 2)
+	for _, item := range items {
+		keys := keysOf(t, item)
+		if strings.Contains(string(item), created.ID) {
+			assert.Equal(t, []string{"duration", "id", "status"}, keys)
+		} else {
+			assert.Equal(t, []string{"id", "status"}, keys)
+		}
+	}
+
+	// История: массив переходов без обёртки
+	_, body = doRaw(t, http.MethodGet, base+"/tasks/"+created.ID+"/history", "")
+	var history []json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &history))
+	require.Len(t, history, 3)
+	assert.Equal(t, []string{"at", "reason", "to"}, keysOf(t, history[0]))
+	assert.Equal(t, []string{"at", "from", "reason", "to"}, keysOf(t, history[1]))
+
+	// Пакетные операции
+	resp, body = doRaw(t, http.MethodPost, base+"/tasks/bulk-delete", `{"ids":["`+second.ID+`","missing"]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"succeeded":1,"failed":1,"results":[
+		{"id":"`+second.ID+`"},
+		{"id":"missing","code":"not_found","error":"not found"}
+	]}`, string(body))
+
+	// Пакет задач
+	resp, body = doRaw(t, http.MethodPost, base+"/batches/", `{"count":2}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"created_at", "finished_at", "id", "task_ids"}, keysOf(t, body))
+	var batch struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(body, &batch))
+	_, body = doRaw(t, http.MethodGet, base+"/batches/"+batch.ID, "")
+	assert.Equal(t, []string{"counts", "created_at", "done", "finished_at", "id", "progress", "task_ids", "total"}, keysOf(t, body))
+
+	// Ошибки — problem+json
+	resp, _ = doRaw(t, http.MethodGet, base+"/tasks/missing", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, problemContentType, resp.Header.Get("Content-Type"))
+
+	// С аутентификацией форма та же: created_by есть только в /v2
+	authed := setupPolicyServer(t).URL + "/v1"
+	taskKeys := []string{"created_at", "ended_at", "id", "payload", "started_at", "status", "type", "version"}
+	resp, body = doWithKey(t, http.MethodPost, authed+"/tasks/", aliceKey, `{"payload":{"n":1}}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, taskKeys, keysOf(t, body))
+	var orig struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(body, &orig))
+	resp, body = doWithKey(t, http.MethodGet, authed+"/tasks/"+orig.ID, aliceKey, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, taskKeys, keysOf(t, body))
+
+	// Повтор добавляет только rerun_of
+	resp, _ = doWithKey(t, http.MethodPut, authed+"/tasks/"+orig.ID+"/cancel", aliceKey, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, body = doWithKey(t, http.MethodPost, authed+"/tasks/"+orig.ID+"/rerun", aliceKey, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	rerunKeys := []string{"created_at", "ended_at", "id", "payload", "rerun_of", "started_at", "status", "type", "version"}
+	assert.Equal(t, rerunKeys, keysOf(t, body))
+	_, body = doWithKey(t, http.MethodGet, authed+"/tasks/"+orig.ID+"/reruns", aliceKey, "")
+	var reruns []json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &reruns))
+	require.Len(t, reruns, 1)
+	assert.Equal(t, rerunKeys, keysOf(t, reruns[0]))
+}
+
+func TestV1_UnversionedAlias(t *testing.T) {
+	server := setupVersionedServer(t)
+
+	resp, body := doRaw(t, http.MethodPost, server.URL+"/tasks/", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get("Deprecation"))
+	assert.Equal(t, `</v1/tasks/>; rel="successor-version"`, resp.Header.Get("Link"))
+	var task struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(body, &task))
+
+	// Старый и новый пути видят одни и те же данные
+	resp, _ = doRaw(t, http.MethodGet, server.URL+"/v1/tasks/"+task.ID, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Deprecation"))
+
+	resp, _ = doRaw(t, http.MethodGet, server.URL+"/task-types", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get("Deprecation"))
+}
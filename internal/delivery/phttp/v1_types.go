@@ -0,0 +1,56 @@
+package phttp
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// TaskV1 — задача в API v1 в том виде, в каком v1 был заморожен. Поля,
+// появившиеся в domen.Task позже (created_by и т. п.), сюда не попадают:
+// они есть только в /v2.
+type TaskV1 struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+
+	// Duration of the task execution
+	Duration string `json:"duration,omitempty" example:"3m0s"`
+
+	Status domen.Status `json:"status"`
+	Result string       `json:"result,omitempty"`
+
+	// Type is the registered task type, see GET /task-types
+	Type string `json:"type,omitempty" example:"default"`
+
+	// Payload is validated against the JSON Schema of the task type
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object"`
+
+	// Version is incremented on every update and is used for optimistic locking
+	Version int64 `json:"version" example:"1"`
+
+	// BatchID is the batch the task belongs to, empty for standalone tasks
+	BatchID string `json:"batch_id,omitempty"`
+
+	// RerunOf is the ID of the task this one re-runs, empty for original tasks
+	RerunOf string `json:"rerun_of,omitempty"`
+}
+
+func newTaskV1(t *domen.Task) TaskV1 {
+	return TaskV1{
+		ID:        t.ID,
+		CreatedAt: t.CreatedAt,
+		StartedAt: t.StartedAt,
+		EndedAt:   t.EndedAt,
+		Duration:  t.Duration,
+		Status:    t.Status,
+		Result:    t.Result,
+		Type:      t.Type,
+		Payload:   t.Payload,
+		Version:   t.Version,
+		BatchID:   t.BatchID,
+		RerunOf:   t.RerunOf,
+	}
+}
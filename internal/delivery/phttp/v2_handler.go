@@ -0,0 +1,475 @@
+package phttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+// V2Handler обслуживает API v2. Поверх тех же use case он отдаёт
+// типизированные DTO в Envelope и единообразные коды ответов:
+// 201 с Location при создании, 204 при удалении.
+type V2Handler struct {
+	tasks   *usecase.TaskUseCase
+	batches *usecase.BatchUseCase
+}
+
+func NewV2Handler(tasks *usecase.TaskUseCase, batches *usecase.BatchUseCase) *V2Handler {
+	return &V2Handler{tasks: tasks, batches: batches}
+}
+
+func (h *V2Handler) Routes() http.Handler {
+	r := chi.NewRouter()
+	r.Route("/tasks", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(defaultTimeout)
+			r.With(canWrite).Post("/", h.createTask)
+			r.With(canRead).Get("/{id}", h.getTask)
+			r.With(canWrite).Delete("/{id}", h.deleteTask)
+			r.With(canCancel).Post("/{id}/cancel", h.cancelTask)
+			r.With(canRead).Get("/{id}/history", h.taskHistory)
+			r.With(canWrite).Post("/{id}/rerun", h.rerunTask)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(bulkTimeout)
+			r.With(canRead).Get("/", h.listTasks)
+			r.With(canWrite).Post("/batch", h.createTasks)
+			r.With(canCancel).Post("/bulk-cancel", h.cancelTasks)
+			r.With(canWrite).Post("/bulk-delete", h.deleteTasks)
+			r.With(canRead).Get("/{id}/reruns", h.taskReruns)
+		})
+	})
+	r.Route("/batches", func(r chi.Router) {
+		r.Use(bulkTimeout)
+		r.With(canWrite).Post("/", h.createBatch)
+		r.With(canRead).Get("/{id}", h.getBatch)
+		r.With(canCancel).Post("/{id}/cancel", h.cancelBatch)
+	})
+	r.With(canRead).Get("/task-types", h.taskTypes)
+	return r
+}
+
+func writeData(w http.ResponseWriter, status int, data, meta interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(Envelope{Data: data, Meta: meta})
+}
+
+// @Summary      Создать задачу
+// @Description  Создаёт задачу указанного типа. Payload проверяется по JSON Schema типа задачи
+// @Tags         v2
+// @Security     ApiKey
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CreateTaskRequest  false  "Тип и параметры задачи"
+// @Success      201  {object}  Envelope{data=TaskV2}
+// @Header       201  {string}  Location  "Адрес задачи"
+// @Header       201  {string}  ETag      "Версия задачи"
+// @Failure      400  {object}  Problem   "Неизвестный тип или payload не прошёл схему"
+// @Failure      403  {object}  Problem   "Действие запрещено политикой доступа"
+// @Failure      429  {object}  Problem   "Превышена квота арендатора"
+// @Failure      500  {object}  Problem   "Внутренняя ошибка"
+// @Router       /v2/tasks [post]
+func (h *V2Handler) createTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lg := logger.FromContext(ctx)
+
+	var req CreateTaskRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, err)
+		return
+	}
+
+	task, err := h.tasks.CreateTask(ctx, req.Type, req.Payload)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	lg.Infow("task created", "id", task.ID, "type", task.Type)
+	w.Header().Set("Location", "/v2/tasks/"+task.ID)
+	setETag(w, task)
+	writeData(w, http.StatusCreated, newTaskV2(task, time.Now()), nil)
+}
+
+// @Summary      Список задач
+// @Tags         v2
+// @Security     ApiKey
+// @Produce      json
+// @Success      200  {object}  Envelope{data=[]TaskV2,meta=ListMeta}
+// @Failure      500  {object}  Problem
+// @Router       /v2/tasks [get]
+func (h *V2Handler) listTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tasks, err := h.tasks.ListTasks(ctx)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, http.StatusOK, newTasksV2(tasks, time.Now()), ListMeta{Count: len(tasks)})
+}
+
+// @Summary      Получить задачу
+// @Tags         v2
+// @Security     ApiKey
+// @Produce      json
+// @Param        id   path      string  true  "ID задачи"
+// @Success      200  {object}  Envelope{data=TaskV2}
+// @Header       200  {string}  ETag     "Версия задачи"
+// @Failure      403  {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      404  {object}  Problem  "Задача не найдена"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v2/tasks/{id} [get]
+func (h *V2Handler) getTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	task, err := h.tasks.GetTask(ctx, id)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("task %s: %w", id, err))
+		return
+	}
+	setETag(w, task)
+	writeData(w, http.StatusOK, newTaskV2(task, time.Now()), nil)
+}
+
+// @Summary      Удалить задачу
+// @Tags         v2
+// @Security     ApiKey
+// @Param        id        path      string  true   "ID задачи"
+// @Param        If-Match  header    string  false  "Ожидаемая версия задачи (ETag)"
+// @Success      204  "No Content"
+// @Failure      403  {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      404  {object}  Problem  "Задача не найдена"
+// @Failure      412  {object}  Problem  "Версия задачи не совпадает с If-Match"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v2/tasks/{id} [delete]
+func (h *V2Handler) deleteTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lg := logger.FromContext(ctx)
+	id := chi.URLParam(r, "id")
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.tasks.DeleteTask(ctx, id, version); err != nil {
+		writeError(w, r, fmt.Errorf("task %s: %w", id, err))
+		return
+	}
+
+	lg.Infow("task deleted", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Отменить задачу
+// @Description  Прерывает выполнение задачи и возвращает её новое состояние
+// @Tags         v2
+// @Security     ApiKey
+// @Produce      json
+// @Param        id        path      string  true   "ID задачи"
+// @Param        If-Match  header    string  false  "Ожидаемая версия задачи (ETag)"
+// @Success      200  {object}  Envelope{data=TaskV2}
+// @Header       200  {string}  ETag     "Новая версия задачи"
+// @Failure      403  {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      404  {object}  Problem  "Задача не найдена"
+// @Failure      409  {object}  Problem  "Задача уже завершена"
+// @Failure      412  {object}  Problem  "Версия задачи не совпадает с If-Match"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v2/tasks/{id}/cancel [post]
+func (h *V2Handler) cancelTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lg := logger.FromContext(ctx)
+	id := chi.URLParam(r, "id")
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	task, err := h.tasks.CancelTask(ctx, id, version)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("task %s: %w", id, err))
+		return
+	}
+
+	lg.Infow("task canceled", "id", id)
+	setETag(w, task)
+	writeData(w, http.StatusOK, newTaskV2(task, time.Now()), nil)
+}
+
+// @Summary      История статусов задачи
+// @Tags         v2
+// @Security     ApiKey
+// @Produce      json
+// @Param        id   path      string  true  "ID задачи"
+// @Success      200  {object}  Envelope{data=[]TransitionV2,meta=ListMeta}
+// @Failure      403  {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      404  {object}  Problem  "Задача не найдена"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v2/tasks/{id}/history [get]
+func (h *V2Handler) taskHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	history, err := h.tasks.GetTaskHistory(ctx, id)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("task %s: %w", id, err))
+		return
+	}
+	writeData(w, http.StatusOK, newTransitionsV2(history), ListMeta{Count: len(history)})
+}
+
+// @Summary      Повторить задачу
+// @Description  Создаёт и запускает повтор завершённой задачи с тем же типом. Payload берётся из тела, а без него — из исходной задачи; новый payload проверяется по схеме типа
+// @Tags         v2
+// @Security     ApiKey
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string        true   "ID исходной задачи"
+// @Param        request  body      RerunRequest  false  "Новый payload"
+// @Success      201  {object}  Envelope{data=TaskV2}
+// @Header       201  {string}  Location  "Адрес новой задачи"
+// @Failure      400  {object}  Problem   "Payload не прошёл схему типа"
+// @Failure      403  {object}  Problem   "Действие запрещено политикой доступа"
+// @Failure      404  {object}  Problem   "Задача не найдена"
+// @Failure      409  {object}  Problem   "Задача ещё не завершена"
+// @Failure      429  {object}  Problem   "Превышена квота арендатора"
+// @Failure      500  {object}  Problem   "Внутренняя ошибка"
+// @Router       /v2/tasks/{id}/rerun [post]
+func (h *V2Handler) rerunTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lg := logger.FromContext(ctx)
+	id := chi.URLParam(r, "id")
+
+	payload, err := decodeRerun(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	task, err := h.tasks.RerunTask(ctx, id, payload)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("task %s: %w", id, err))
+		return
+	}
+
+	lg.Infow("task rerun created", "id", task.ID, "rerun_of", id)
+	w.Header().Set("Location", "/v2/tasks/"+task.ID)
+	setETag(w, task)
+	writeData(w, http.StatusCreated, newTaskV2(task, time.Now()), nil)
+}
+
+// @Summary      Повторы задачи
+// @Tags         v2
+// @Security     ApiKey
+// @Produce      json
+// @Param        id   path      string  true  "ID исходной задачи"
+// @Success      200  {object}  Envelope{data=[]TaskV2,meta=ListMeta}
+// @Failure      403  {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      404  {object}  Problem  "Задача не найдена"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v2/tasks/{id}/reruns [get]
+func (h *V2Handler) taskReruns(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	tasks, err := h.tasks.ListReruns(ctx, id)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("task %s: %w", id, err))
+		return
+	}
+	writeData(w, http.StatusOK, newTasksV2(tasks, time.Now()), ListMeta{Count: len(tasks)})
+}
+
+// @Summary      Создать несколько задач
+// @Description  Создаёт count задач (не больше 1000) типа type с параметрами payload и возвращает результат по каждой. Payload проверяется по JSON Schema типа задачи
+// @Tags         v2
+// @Security     ApiKey
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BatchCreateRequest  true  "Число, тип и параметры задач"
+// @Success      200      {object}  Envelope{data=[]BulkItemV2,meta=BulkMeta}
+// @Failure      400      {object}  Problem  "Некорректный запрос"
+// @Failure      403      {object}  Problem  "Действие запрещено политикой доступа"
+// @Failure      429      {object}  Problem  "Превышена квота арендатора"
+// @Failure      500      {object}  Problem  "Внутренняя ошибка"
+// @Router       /v2/tasks/batch [post]
+func (h *V2Handler) createTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req BatchCreateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	results, err := h.tasks.CreateTasks(ctx, req.Count, req.spec())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.writeBulk(w, r, results)
+}
+
+// @Summary      Отменить несколько задач
+// @Description  Отменяет задачи по списку ID или по фильтру (не больше 1000) и возвращает результат по каждой
+// @Tags         v2
+// @Security     ApiKey
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BulkRequest  true  "Список ID или фильтр"
+// @Success      200      {object}  Envelope{data=[]BulkItemV2,meta=BulkMeta}
+// @Failure      400      {object}  Problem  "Некорректный запрос"
+// @Failure      500      {object}  Problem  "Внутренняя ошибка"
+// @Router       /v2/tasks/bulk-cancel [post]
+func (h *V2Handler) cancelTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sel, err := decodeSelector(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	results, err := h.tasks.CancelTasks(ctx, sel)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.writeBulk(w, r, results)
+}
+
+// @Summary      Удалить несколько задач
+// @Description  Удаляет задачи по списку ID или по фильтру (не больше 1000) и возвращает результат по каждой
+// @Tags         v2
+// @Security     ApiKey
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BulkRequest  true  "Список ID или фильтр"
+// @Success      200      {object}  Envelope{data=[]BulkItemV2,meta=BulkMeta}
+// @Failure      400      {object}  Problem  "Некорректный запрос"
+// @Failure      500      {object}  Problem  "Внутренняя ошибка"
+// @Router       /v2/tasks/bulk-delete [post]
+func (h *V2Handler) deleteTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sel, err := decodeSelector(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	results, err := h.tasks.DeleteTasks(ctx, sel)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.writeBulk(w, r, results)
+}
+
+func (h *V2Handler) writeBulk(w http.ResponseWriter, r *http.Request, results []usecase.BulkResult) {
+	items, meta := newBulkV2(results, time.Now())
+	logger.FromContext(r.Context()).Infow("bulk operation finished", "succeeded", meta.Succeeded, "failed", meta.Failed)
+	writeData(w, http.StatusOK, items, meta)
+}
+
+// @Summary      Создать пакет задач
+// @Description  Создаёт пакет из count задач (не больше 1000) типа type с параметрами payload и запускает их. Payload проверяется по JSON Schema типа задачи
+// @Tags         v2
+// @Security     ApiKey
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BatchCreateRequest  true  "Число, тип и параметры задач"
+// @Success      201      {object}  Envelope{data=BatchV2}
+// @Header       201      {string}  Location  "Адрес пакета"
+// @Failure      400      {object}  Problem   "Некорректный запрос"
+// @Failure      403      {object}  Problem   "Действие запрещено политикой доступа"
+// @Failure      429      {object}  Problem   "Превышена квота арендатора"
+// @Failure      500      {object}  Problem   "Внутренняя ошибка"
+// @Router       /v2/batches [post]
+func (h *V2Handler) createBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lg := logger.FromContext(ctx)
+
+	var req BatchCreateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	batch, err := h.batches.CreateBatch(ctx, req.Count, req.spec())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	lg.Infow("batch created", "id", batch.ID, "tasks", len(batch.TaskIDs))
+	progress, err := h.batches.GetBatch(ctx, batch.ID)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("batch %s: %w", batch.ID, err))
+		return
+	}
+	w.Header().Set("Location", "/v2/batches/"+batch.ID)
+	writeData(w, http.StatusCreated, newBatchV2(progress), nil)
+}
+
+// @Summary      Получить пакет задач
+// @Description  Возвращает пакет с числом задач по статусам и общим прогрессом
+// @Tags         v2
+// @Security     ApiKey
+// @Produce      json
+// @Param        id   path      string  true  "ID пакета"
+// @Success      200  {object}  Envelope{data=BatchV2}
+// @Failure      403  {object}  Problem  "Политика доступа запрещает читать задачи пакета"
+// @Failure      404  {object}  Problem  "Пакет не найден"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v2/batches/{id} [get]
+func (h *V2Handler) getBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	progress, err := h.batches.GetBatch(ctx, id)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("batch %s: %w", id, err))
+		return
+	}
+	writeData(w, http.StatusOK, newBatchV2(progress), nil)
+}
+
+// @Summary      Отменить пакет задач
+// @Description  Отменяет все незавершённые задачи пакета и возвращает результат по каждой
+// @Tags         v2
+// @Security     ApiKey
+// @Produce      json
+// @Param        id   path      string  true  "ID пакета"
+// @Success      200  {object}  Envelope{data=[]BulkItemV2,meta=BulkMeta}
+// @Failure      404  {object}  Problem  "Пакет не найден"
+// @Failure      500  {object}  Problem  "Внутренняя ошибка"
+// @Router       /v2/batches/{id}/cancel [post]
+func (h *V2Handler) cancelBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	results, err := h.batches.CancelBatch(ctx, id)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("batch %s: %w", id, err))
+		return
+	}
+	h.writeBulk(w, r, results)
+}
+
+// @Summary      Типы задач
+// @Tags         v2
+// @Security     ApiKey
+// @Produce      json
+// @Success      200  {object}  Envelope{data=[]domen.TaskType,meta=ListMeta}
+// @Router       /v2/task-types [get]
+func (h *V2Handler) taskTypes(w http.ResponseWriter, r *http.Request) {
+	types := h.tasks.TaskTypes()
+	writeData(w, http.StatusOK, types, ListMeta{Count: len(types)})
+}
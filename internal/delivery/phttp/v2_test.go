@@ -0,0 +1,194 @@
+package phttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeEnvelope(t *testing.T, body []byte, data, meta interface{}) {
+	t.Helper()
+	var env struct {
+		Data json.RawMessage `json:"data"`
+		Meta json.RawMessage `json:"meta"`
+	}
+	require.NoError(t, json.Unmarshal(body, &env), string(body))
+	require.NoError(t, json.Unmarshal(env.Data, data))
+	if meta != nil {
+		require.NoError(t, json.Unmarshal(env.Meta, meta))
+	}
+}
+
+func TestV2_TaskLifecycle(t *testing.T) {
+	server := setupVersionedServer(t)
+	base := server.URL + "/v2"
+
+	resp, body := doRaw(t, http.MethodPost, base+"/tasks/", `{"payload":{"n":1}}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created TaskV2
+	decodeEnvelope(t, body, &created, nil)
+	assert.Equal(t, "/v2/tasks/"+created.ID, resp.Header.Get("Location"))
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+	assert.Equal(t, domen.DefaultTaskType, created.Type)
+	assert.JSONEq(t, `{"n":1}`, string(created.Payload))
+	assert.Nil(t, created.StartedAt)
+	assert.Nil(t, created.EndedAt)
+	assert.Equal(t, "0s", created.Duration)
+	assert.Zero(t, created.DurationMS)
+
+	time.Sleep(400 * time.Millisecond)
+
+	resp, body = doRaw(t, http.MethodGet, base+"/tasks/"+created.ID, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var done TaskV2
+	decodeEnvelope(t, body, &done, nil)
+	assert.Equal(t, domen.StatusCompleted, done.Status)
+	require.NotNil(t, done.StartedAt)
+	require.NotNil(t, done.EndedAt)
+	assert.GreaterOrEqual(t, done.DurationMS, int64(200))
+	d, err := time.ParseDuration(done.Duration)
+	require.NoError(t, err)
+	assert.Equal(t, d.Milliseconds(), done.DurationMS)
+
+	// Завершённую задачу отменить нельзя
+	resp, _ = doRaw(t, http.MethodPost, base+"/tasks/"+created.ID+"/cancel", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, problemContentType, resp.Header.Get("Content-Type"))
+
+	resp, body = doRaw(t, http.MethodGet, base+"/tasks/"+created.ID+"/history", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var history []TransitionV2
+	var meta ListMeta
+	decodeEnvelope(t, body, &history, &meta)
+	require.Len(t, history, 3)
+	assert.Equal(t, 3, meta.Count)
+	assert.Nil(t, history[0].From)
+	require.NotNil(t, history[1].From)
+	assert.Equal(t, domen.StatusPending, *history[1].From)
+
+	resp, body = doRaw(t, http.MethodPost, base+"/tasks/"+created.ID+"/rerun", "")
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var rerun TaskV2
+	decodeEnvelope(t, body, &rerun, nil)
+	assert.Equal(t, created.ID, rerun.RerunOf)
+	assert.Equal(t, "/v2/tasks/"+rerun.ID, resp.Header.Get("Location"))
+
+	resp, body = doRaw(t, http.MethodGet, base+"/tasks/"+created.ID+"/reruns", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var reruns []TaskV2
+	decodeEnvelope(t, body, &reruns, &meta)
+	require.Len(t, reruns, 1)
+	assert.Equal(t, rerun.ID, reruns[0].ID)
+
+	resp, _ = doRaw(t, http.MethodDelete, base+"/tasks/"+created.ID, "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = doRaw(t, http.MethodGet, base+"/tasks/"+created.ID, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestV2_CancelAndList(t *testing.T) {
+	server := setupVersionedServer(t)
+	base := server.URL + "/v2"
+
+	// Пустой список — [] с count 0
+	_, body := doRaw(t, http.MethodGet, base+"/tasks/", "")
+	assert.JSONEq(t, `{"data":[],"meta":{"count":0}}`, string(body))
+
+	_, body = doRaw(t, http.MethodPost, base+"/tasks/", "")
+	var task TaskV2
+	decodeEnvelope(t, body, &task, nil)
+
+	time.Sleep(50 * time.Millisecond)
+	resp, body := doRaw(t, http.MethodPost, base+"/tasks/"+task.ID+"/cancel", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var canceled TaskV2
+	decodeEnvelope(t, body, &canceled, nil)
+	assert.Equal(t, domen.StatusCancelled, canceled.Status)
+	assert.Equal(t, `"3"`, resp.Header.Get("ETag"))
+	// Длительность отменённой задачи считается до момента отмены
+	assert.Greater(t, canceled.DurationMS, int64(0))
+
+	time.Sleep(300 * time.Millisecond)
+	_, body = doRaw(t, http.MethodGet, base+"/tasks/", "")
+	var list []TaskV2
+	var meta ListMeta
+	decodeEnvelope(t, body, &list, &meta)
+	require.Len(t, list, 1)
+	assert.Equal(t, 1, meta.Count)
+	assert.Equal(t, domen.StatusCancelled, list[0].Status)
+	assert.Equal(t, canceled.DurationMS, list[0].DurationMS)
+}
+
+func TestV2_Bulk(t *testing.T) {
+	server := setupVersionedServer(t)
+	base := server.URL + "/v2"
+
+	resp, body := doRaw(t, http.MethodPost, base+"/tasks/batch", `{"count":2}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var created []BulkItemV2
+	var meta BulkMeta
+	decodeEnvelope(t, body, &created, &meta)
+	assert.Equal(t, BulkMeta{Count: 2, Succeeded: 2}, meta)
+	require.Len(t, created, 2)
+	require.NotNil(t, created[0].Task)
+	assert.True(t, created[0].OK)
+
+	resp, body = doRaw(t, http.MethodPost, base+"/tasks/bulk-cancel", `{"ids":["`+created[0].ID+`","missing"]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var canceled []BulkItemV2
+	decodeEnvelope(t, body, &canceled, &meta)
+	assert.Equal(t, BulkMeta{Count: 2, Succeeded: 1, Failed: 1}, meta)
+	assert.Equal(t, domen.StatusCancelled, canceled[0].Task.Status)
+	assert.False(t, canceled[1].OK)
+	require.NotNil(t, canceled[1].Error)
+	assert.Equal(t, CodeNotFound, canceled[1].Error.Code)
+
+	resp, _ = doRaw(t, http.MethodPost, base+"/tasks/bulk-delete", `{}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestV2_Batches(t *testing.T) {
+	server := setupVersionedServer(t)
+	base := server.URL + "/v2"
+
+	resp, body := doRaw(t, http.MethodPost, base+"/batches/", `{"count":3}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var batch BatchV2
+	decodeEnvelope(t, body, &batch, nil)
+	assert.Equal(t, "/v2/batches/"+batch.ID, resp.Header.Get("Location"))
+	assert.Equal(t, 3, batch.Total)
+	assert.Less(t, batch.Progress, 1.0)
+	assert.Nil(t, batch.FinishedAt)
+
+	resp, body = doRaw(t, http.MethodPost, base+"/batches/"+batch.ID+"/cancel", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var items []BulkItemV2
+	var meta BulkMeta
+	decodeEnvelope(t, body, &items, &meta)
+	assert.Equal(t, 3, meta.Count)
+
+	_, body = doRaw(t, http.MethodGet, base+"/batches/"+batch.ID, "")
+	decodeEnvelope(t, body, &batch, nil)
+	assert.Equal(t, 3, batch.Done)
+	assert.Equal(t, 3, batch.Counts[domen.StatusCancelled])
+
+	resp, _ = doRaw(t, http.MethodGet, base+"/batches/missing", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestV2_TaskTypes(t *testing.T) {
+	server := setupVersionedServer(t)
+
+	_, body := doRaw(t, http.MethodGet, server.URL+"/v2/task-types", "")
+	var types []domen.TaskType
+	var meta ListMeta
+	decodeEnvelope(t, body, &types, &meta)
+	require.Len(t, types, 1)
+	assert.Equal(t, 1, meta.Count)
+	assert.Equal(t, domen.DefaultTaskType, types[0].Name)
+}
@@ -0,0 +1,193 @@
+package phttp
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/usecase"
+)
+
+// Envelope — обёртка всех успешных ответов /v2: данные лежат в data,
+// сведения о выборке — в meta. Ошибки, как и в v1, отдаются в Problem.
+type Envelope struct {
+	Data interface{} `json:"data"`
+	Meta interface{} `json:"meta,omitempty"`
+}
+
+// ListMeta — meta списков.
+type ListMeta struct {
+	Count int `json:"count" example:"2"`
+}
+
+// BulkMeta — meta пакетных операций.
+type BulkMeta struct {
+	Count     int `json:"count" example:"3"`
+	Succeeded int `json:"succeeded" example:"2"`
+	Failed    int `json:"failed" example:"1"`
+}
+
+// TaskV2 — задача в API v2. Время, которого ещё не было, равно null,
+// длительность отдаётся и строкой, и в миллисекундах.
+type TaskV2 struct {
+	ID      string          `json:"id" example:"3f0c7f9e-6a43-4a57-9d6b-6f1f4c0b2a11"`
+	Type    string          `json:"type" example:"default"`
+	Status  domen.Status    `json:"status" example:"RUNNING"`
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object"`
+	Result  string          `json:"result,omitempty" example:"OK"`
+	Version int64           `json:"version" example:"2"`
+
+	CreatedAt time.Time  `json:"created_at"`
+	StartedAt *time.Time `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+
+	// Duration — время выполнения: до конца задачи или до момента ответа,
+	// если задача ещё идёт. До старта равно нулю.
+	Duration   string `json:"duration" example:"3m0s"`
+	DurationMS int64  `json:"duration_ms" example:"180000"`
+
+	BatchID string `json:"batch_id,omitempty"`
+	RerunOf string `json:"rerun_of,omitempty"`
+
+	// CreatedBy — ID ключа или пользователя, создавшего задачу
+	CreatedBy string `json:"created_by,omitempty" example:"ci-pipeline"`
+	// TenantID — арендатор, которому принадлежит задача
+	TenantID string `json:"tenant_id,omitempty" example:"team-a"`
+}
+
+func newTaskV2(t *domen.Task, now time.Time) TaskV2 {
+	out := TaskV2{
+		ID:        t.ID,
+		Type:      t.Type,
+		Status:    t.Status,
+		Payload:   t.Payload,
+		Result:    t.Result,
+		Version:   t.Version,
+		CreatedAt: t.CreatedAt,
+		StartedAt: optionalTime(t.StartedAt),
+		EndedAt:   optionalTime(t.EndedAt),
+		BatchID:   t.BatchID,
+		RerunOf:   t.RerunOf,
+		CreatedBy: t.CreatedBy,
+		TenantID:  t.TenantID,
+	}
+	var d time.Duration
+	if !t.StartedAt.IsZero() {
+		end := t.EndedAt
+		switch {
+		case !end.IsZero():
+		case !t.Status.IsTerminal():
+			end = now
+		case len(t.History) > 0:
+			// Отменённая задача не получает EndedAt: берём время перехода
+			end = t.History[len(t.History)-1].At
+		default:
+			end = t.StartedAt
+		}
+		d = end.Sub(t.StartedAt)
+	}
+	out.Duration = d.String()
+	out.DurationMS = d.Milliseconds()
+	return out
+}
+
+func newTasksV2(tasks []*domen.Task, now time.Time) []TaskV2 {
+	out := make([]TaskV2, len(tasks))
+	for i, t := range tasks {
+		out[i] = newTaskV2(t, now)
+	}
+	return out
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// TransitionV2 — переход статуса задачи в API v2.
+type TransitionV2 struct {
+	From   *domen.Status `json:"from" example:"PENDING"`
+	To     domen.Status  `json:"to" example:"RUNNING"`
+	At     time.Time     `json:"at"`
+	Reason string        `json:"reason,omitempty" example:"started"`
+}
+
+func newTransitionsV2(history []domen.Transition) []TransitionV2 {
+	out := make([]TransitionV2, len(history))
+	for i, h := range history {
+		out[i] = TransitionV2{To: h.To, At: h.At, Reason: h.Reason}
+		if h.From != "" {
+			from := h.From
+			out[i].From = &from
+		}
+	}
+	return out
+}
+
+// BatchV2 — пакет задач с прогрессом в API v2.
+type BatchV2 struct {
+	ID         string               `json:"id"`
+	CreatedAt  time.Time            `json:"created_at"`
+	FinishedAt *time.Time           `json:"finished_at"`
+	TaskIDs    []string             `json:"task_ids"`
+	Counts     map[domen.Status]int `json:"counts"`
+	Missing    int                  `json:"missing"`
+	Total      int                  `json:"total" example:"10"`
+	Done       int                  `json:"done" example:"4"`
+	Progress   float64              `json:"progress" example:"0.4"`
+}
+
+func newBatchV2(p *domen.BatchProgress) BatchV2 {
+	counts := p.Counts
+	if counts == nil {
+		counts = map[domen.Status]int{}
+	}
+	return BatchV2{
+		ID:         p.ID,
+		CreatedAt:  p.CreatedAt,
+		FinishedAt: optionalTime(p.FinishedAt),
+		TaskIDs:    p.TaskIDs,
+		Counts:     counts,
+		Missing:    p.Missing,
+		Total:      p.Total,
+		Done:       p.Done,
+		Progress:   p.Progress,
+	}
+}
+
+// BulkItemV2 — результат пакетной операции для одной задачи в API v2.
+type BulkItemV2 struct {
+	ID    string       `json:"id"`
+	OK    bool         `json:"ok"`
+	Task  *TaskV2      `json:"task,omitempty"`
+	Error *ItemErrorV2 `json:"error,omitempty"`
+}
+
+// ItemErrorV2 — ошибка одного элемента пакетной операции; code тот же, что в Problem.
+type ItemErrorV2 struct {
+	Code   string `json:"code" example:"invalid_transition"`
+	Detail string `json:"detail" example:"invalid status transition COMPLETED -> CANCELED"`
+}
+
+func newBulkV2(results []usecase.BulkResult, now time.Time) ([]BulkItemV2, BulkMeta) {
+	items := make([]BulkItemV2, len(results))
+	meta := BulkMeta{Count: len(results)}
+	for i, res := range results {
+		item := BulkItemV2{ID: res.ID, OK: res.Err == nil}
+		if res.Task != nil {
+			t := newTaskV2(res.Task, now)
+			item.Task = &t
+		}
+		if res.Err != nil {
+			p := problemFor(res.Err)
+			item.Error = &ItemErrorV2{Code: p.Code, Detail: p.Detail}
+			meta.Failed++
+		} else {
+			meta.Succeeded++
+		}
+		items[i] = item
+	}
+	return items, meta
+}
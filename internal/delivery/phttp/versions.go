@@ -0,0 +1,28 @@
+package phttp
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// MountV1 регистрирует в r замороженный API v1: /tasks, /batches и
+// /task-types. Форма ответов v1 не меняется; её стерегут тесты в v1_test.go,
+// новые возможности добавляются в /v2.
+func MountV1(r chi.Router, tasks *Handler, batches *BatchHandler) {
+	r.Mount("/tasks", tasks.Routes())
+	r.Mount("/batches", batches.Routes())
+	r.With(canRead).Get("/task-types", tasks.TaskTypes)
+}
+
+// Deprecated помечает ответы устаревших путей заголовками Deprecation и
+// Link на тот же путь под префиксом successor, например /v1.
+func Deprecated(successor string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successor+r.URL.Path+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
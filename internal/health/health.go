@@ -0,0 +1,220 @@
+// Package health собирает проверки компонентов сервиса для liveness- и
+// readiness-проб.
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCheckTimeout ограничивает время одной проверки.
+const DefaultCheckTimeout = 2 * time.Second
+
+// Состояния сервиса для readiness.
+const (
+	StateStarting = "starting"
+	StateReady    = "ready"
+	StateDraining = "draining"
+)
+
+// Статусы проверок и отчёта.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check проверяет компонент; nil — компонент исправен. Check должен
+// уважать ctx: по истечении таймаута проверка считается проваленной.
+type Check func(ctx context.Context) error
+
+// CheckResult — результат одной проверки.
+type CheckResult struct {
+	Name      string `json:"name" example:"repository"`
+	Status    string `json:"status" example:"ok"`
+	LatencyMS int64  `json:"latency_ms" example:"1"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report — результат пробы. State заполняется только для readiness.
+type Report struct {
+	Status string        `json:"status" example:"ok"`
+	State  string        `json:"state,omitempty" example:"ready"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// OK сообщает, прошла ли проба.
+func (r Report) OK() bool { return r.Status == StatusOK }
+
+// Registry хранит проверки компонентов и состояние готовности сервиса.
+// Liveness-проверки говорят, что процесс нужно перезапустить; readiness —
+// что на него пока не стоит направлять запросы. Liveness-проверки входят
+// и в readiness.
+//
+// Новый Registry находится в состоянии starting: сервис готов только
+// после MarkReady и перестаёт быть готовым после Drain.
+type Registry struct {
+	// Timeout ограничивает каждую проверку; 0 — DefaultCheckTimeout.
+	Timeout time.Duration
+
+	mu        sync.RWMutex
+	liveness  map[string]Check
+	readiness map[string]Check
+	state     string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		liveness:  make(map[string]Check),
+		readiness: make(map[string]Check),
+		state:     StateStarting,
+	}
+}
+
+// RegisterLiveness добавляет проверку, провал которой означает, что процесс
+// не восстановится сам. Повторная регистрация имени заменяет проверку.
+func (r *Registry) RegisterLiveness(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.liveness[name] = check
+}
+
+// RegisterReadiness добавляет проверку зависимости, без которой сервис
+// не может обслуживать запросы. Повторная регистрация имени заменяет проверку.
+func (r *Registry) RegisterReadiness(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness[name] = check
+}
+
+// MarkReady завершает запуск: с этого момента readiness определяется
+// проверками. После Drain не действует.
+func (r *Registry) MarkReady() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == StateStarting {
+		r.state = StateReady
+	}
+}
+
+// Drain переводит сервис в состояние draining перед остановкой:
+// readiness проваливается, и балансировщик перестаёт слать новые запросы.
+func (r *Registry) Drain() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state = StateDraining
+}
+
+// State возвращает текущее состояние сервиса.
+func (r *Registry) State() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.state
+}
+
+// Live выполняет liveness-проверки.
+func (r *Registry) Live(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := copyChecks(r.liveness)
+	r.mu.RUnlock()
+	return r.run(ctx, checks, "")
+}
+
+// Ready выполняет liveness- и readiness-проверки. Вне состояния ready
+// проба проваливается, даже если все проверки прошли.
+func (r *Registry) Ready(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := copyChecks(r.liveness)
+	for name, c := range r.readiness {
+		checks[name] = c
+	}
+	state := r.state
+	r.mu.RUnlock()
+	return r.run(ctx, checks, state)
+}
+
+func copyChecks(src map[string]Check) map[string]Check {
+	dst := make(map[string]Check, len(src))
+	for name, c := range src {
+		dst[name] = c
+	}
+	return dst
+}
+
+// run выполняет проверки параллельно и собирает отчёт, упорядоченный по имени.
+func (r *Registry) run(ctx context.Context, checks map[string]Check, state string) Report {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+
+	results := make([]CheckResult, 0, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := runCheck(ctx, name, check, timeout)
+			mu.Lock()
+			results = append(results, res)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+
+	rep := Report{Status: StatusOK, State: state, Checks: results}
+	if state != "" && state != StateReady {
+		rep.Status = StatusFail
+	}
+	for _, res := range results {
+		if res.Status != StatusOK {
+			rep.Status = StatusFail
+		}
+	}
+	return rep
+}
+
+func runCheck(ctx context.Context, name string, check Check, timeout time.Duration) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	res := CheckResult{Name: name, Status: StatusOK, LatencyMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// ErrStopped — ошибка Tracker для процесса, завершившегося без ошибки.
+var ErrStopped = errors.New("stopped")
+
+// Tracker следит за фоновым процессом: после Stop его Check проваливается.
+// Подходит для liveness-проверки циклов вроде наблюдателя пакетов.
+type Tracker struct {
+	mu  sync.Mutex
+	err error
+}
+
+// Stop отмечает, что процесс завершился с ошибкой err (ErrStopped, если nil).
+func (t *Tracker) Stop(err error) {
+	if err == nil {
+		err = ErrStopped
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.err = err
+}
+
+// Check — health.Check процесса.
+func (t *Tracker) Check(context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
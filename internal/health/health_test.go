@@ -0,0 +1,91 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func okCheck(context.Context) error { return nil }
+
+func TestRegistry_ReadinessFollowsState(t *testing.T) {
+	reg := NewRegistry()
+	reg.RegisterReadiness("repository", okCheck)
+	ctx := context.Background()
+
+	// До MarkReady сервис не готов, хотя проверки проходят
+	rep := reg.Ready(ctx)
+	assert.False(t, rep.OK())
+	assert.Equal(t, StateStarting, rep.State)
+	require.Len(t, rep.Checks, 1)
+	assert.Equal(t, StatusOK, rep.Checks[0].Status)
+
+	reg.MarkReady()
+	rep = reg.Ready(ctx)
+	assert.True(t, rep.OK())
+	assert.Equal(t, StateReady, rep.State)
+
+	// Drain необратим: повторный MarkReady не возвращает готовность
+	reg.Drain()
+	reg.MarkReady()
+	rep = reg.Ready(ctx)
+	assert.False(t, rep.OK())
+	assert.Equal(t, StateDraining, rep.State)
+
+	// Liveness от состояния не зависит
+	assert.True(t, reg.Live(ctx).OK())
+	assert.Empty(t, reg.Live(ctx).State)
+}
+
+func TestRegistry_Checks(t *testing.T) {
+	reg := NewRegistry()
+	reg.Timeout = 50 * time.Millisecond
+	reg.MarkReady()
+
+	tracker := &Tracker{}
+	reg.RegisterLiveness("watcher", tracker.Check)
+	reg.RegisterReadiness("repository", Repository(memory.NewInMemoryRepo()))
+	reg.RegisterReadiness("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx := context.Background()
+
+	rep := reg.Ready(ctx)
+	assert.False(t, rep.OK())
+	require.Len(t, rep.Checks, 3)
+	// Проверки упорядочены по имени
+	assert.Equal(t, []string{"repository", "slow", "watcher"}, names(rep.Checks))
+	assert.Equal(t, StatusOK, rep.Checks[0].Status)
+	assert.Equal(t, StatusFail, rep.Checks[1].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), rep.Checks[1].Error)
+	assert.GreaterOrEqual(t, rep.Checks[1].LatencyMS, int64(50))
+
+	// Readiness-проверки в liveness не входят
+	live := reg.Live(ctx)
+	assert.True(t, live.OK())
+	assert.Equal(t, []string{"watcher"}, names(live.Checks))
+
+	tracker.Stop(errors.New("watch failed"))
+	live = reg.Live(ctx)
+	assert.False(t, live.OK())
+	assert.Equal(t, "watch failed", live.Checks[0].Error)
+
+	// Завершение без ошибки тоже провал: процесс должен работать всегда
+	stopped := &Tracker{}
+	stopped.Stop(nil)
+	assert.ErrorIs(t, stopped.Check(ctx), ErrStopped)
+}
+
+func names(checks []CheckResult) []string {
+	out := make([]string, 0, len(checks))
+	for _, c := range checks {
+		out = append(out, c.Name)
+	}
+	return out
+}
@@ -0,0 +1,17 @@
+package health
+
+import (
+	"context"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// Repository проверяет, что хранилище задач отвечает на запросы.
+// Передавайте репозиторий без обёрток метрик и трейсинга, чтобы пробы
+// не попадали в статистику запросов.
+func Repository(repo domen.TaskRepository) Check {
+	return func(ctx context.Context) error {
+		_, err := repo.Find(ctx, domen.TaskFilter{Limit: 1})
+		return err
+	}
+}
@@ -0,0 +1,102 @@
+// Package metrics собирает метрики сервиса в формате Prometheus.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "workmate"
+
+// Metrics владеет собственным реестром: в /metrics попадают только
+// метрики сервиса и стандартные метрики Go и процесса.
+type Metrics struct {
+	reg *prometheus.Registry
+
+	tasksCreated  *prometheus.CounterVec
+	tasksFinished *prometheus.CounterVec
+	taskRunTime   *prometheus.HistogramVec
+	taskWaitTime  *prometheus.HistogramVec
+	watchGaps     prometheus.Counter
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	repoDuration *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		reg: prometheus.NewRegistry(),
+		tasksCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tasks_created_total",
+			Help:      "Tasks created, by task type and tenant.",
+		}, []string{"type", "tenant"}),
+		tasksFinished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tasks_finished_total",
+			Help:      "Tasks that reached a terminal status (COMPLETED, FAILED, CANCELED), by task type, status and tenant.",
+		}, []string{"type", "status", "tenant"}),
+		taskRunTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "task_run_duration_seconds",
+			Help:      "Time from task start to its terminal status, by task type and status.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+		}, []string{"type", "status"}),
+		taskWaitTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "task_wait_duration_seconds",
+			Help:      "Time a task spent PENDING before it started, by task type.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"type"}),
+		watchGaps: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "task_watch_gaps_total",
+			Help:      "Times the task metrics watcher fell behind the change log. Created and finished totals for the gap are restored from a repository snapshot; tasks deleted during the gap are missed.",
+		}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests, by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by method, route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_operation_duration_seconds",
+			Help:      "Task repository operation latency, by operation and outcome (ok or error).",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"operation", "outcome"}),
+	}
+	m.reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.tasksCreated, m.tasksFinished, m.taskRunTime, m.taskWaitTime, m.watchGaps,
+		m.httpRequests, m.httpDuration,
+		m.repoDuration,
+	)
+	return m
+}
+
+// Handler отдаёт метрики в текстовом формате Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.reg, promhttp.HandlerOpts{Registry: m.reg, ErrorHandling: promhttp.ContinueOnError})
+}
+
+// ObserveHTTP учитывает обработанный запрос. route — шаблон маршрута,
+// а не путь, иначе ID задач раздуют число рядов.
+func (m *Metrics) ObserveHTTP(method, route string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
@@ -0,0 +1,244 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/gaz358/myprog/workmate/usecase"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleCount возвращает число наблюдений гистограммы name с метками labels.
+func sampleCount(t *testing.T, m *Metrics, name string, labels map[string]string) uint64 {
+	t.Helper()
+	families, err := m.reg.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+	next:
+		for _, metric := range f.GetMetric() {
+			for _, l := range metric.GetLabel() {
+				if v, ok := labels[l.GetName()]; ok && v != l.GetValue() {
+					continue next
+				}
+			}
+			return metric.GetHistogram().GetSampleCount()
+		}
+	}
+	return 0
+}
+
+func TestMetrics_TaskLifecycle(t *testing.T) {
+	repo := memory.NewInMemoryRepo()
+	m := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = m.Run(ctx, repo) }()
+	// Run подписывается на изменения асинхронно
+	time.Sleep(20 * time.Millisecond)
+
+	uc := usecase.NewTaskUseCase(m.InstrumentRepository(repo), 100*time.Millisecond)
+	_, err := uc.CreateTask(ctx, "", nil)
+	require.NoError(t, err)
+	canceled, err := uc.CreateTask(ctx, "", nil)
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	_, err = uc.CancelTask(ctx, canceled.ID, 0)
+	require.NoError(t, err)
+	time.Sleep(250 * time.Millisecond)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.tasksCreated.WithLabelValues("default", "default")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.tasksFinished.WithLabelValues("default", "COMPLETED", "default")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.tasksFinished.WithLabelValues("default", "CANCELED", "default")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.tasksFinished.WithLabelValues("default", "FAILED", "default")))
+
+	assert.Equal(t, uint64(2), sampleCount(t, m, "workmate_task_wait_duration_seconds", map[string]string{"type": "default"}))
+	assert.Equal(t, uint64(1), sampleCount(t, m, "workmate_task_run_duration_seconds", map[string]string{"status": "COMPLETED"}))
+	assert.Equal(t, uint64(1), sampleCount(t, m, "workmate_task_run_duration_seconds", map[string]string{"status": "CANCELED"}))
+
+	// Задачи арендатора считаются в его рядах
+	teamCtx := domen.WithPrincipal(ctx, &domen.Principal{ID: "ci", Tenant: "team-a"})
+	_, err = uc.CreateTask(teamCtx, "", nil)
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.tasksCreated.WithLabelValues("default", "team-a")))
+}
+
+// closingRepo отдаёт из Watch сразу закрытый канал, как репозиторий,
+// который не может прочитать журнал изменений.
+type closingRepo struct {
+	domen.TaskRepository
+	watches atomic.Int32
+}
+
+func (r *closingRepo) Watch(context.Context, domen.WatchFilter) (<-chan domen.TaskChange, error) {
+	r.watches.Add(1)
+	ch := make(chan domen.TaskChange)
+	close(ch)
+	return ch, nil
+}
+
+func TestMetrics_RunBackoff(t *testing.T) {
+	repo := &closingRepo{TaskRepository: memory.NewInMemoryRepo()}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	require.NoError(t, New().Run(ctx, repo))
+
+	// Паузы 100, 200 мс, ...: за полсекунды не больше четырёх подписок
+	assert.GreaterOrEqual(t, repo.watches.Load(), int32(2))
+	assert.LessOrEqual(t, repo.watches.Load(), int32(4))
+}
+
+// gapRepo по drop обрывает текущую подписку и отвечает на следующую
+// ErrRevisionCompacted, как если бы журнал успели подрезать.
+type gapRepo struct {
+	domen.TaskRepository
+	mu      sync.Mutex
+	stop    chan struct{}
+	compact bool
+}
+
+func (r *gapRepo) Watch(ctx context.Context, f domen.WatchFilter) (<-chan domen.TaskChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.compact && f.FromRevision > 0 {
+		r.compact = false
+		return nil, domen.ErrRevisionCompacted
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	changes, err := r.TaskRepository.Watch(ctx, f)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	out, stop := make(chan domen.TaskChange), make(chan struct{})
+	r.stop = stop
+	go func() {
+		defer cancel()
+		defer close(out)
+		for c := range changes {
+			// Изменения после drop до подписчика не доходят
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			select {
+			case out <- c:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (r *gapRepo) drop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.compact = true
+	close(r.stop)
+}
+
+func TestMetrics_WatchGap(t *testing.T) {
+	repo := &gapRepo{TaskRepository: memory.NewInMemoryRepo()}
+	m := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = m.Run(ctx, repo) }()
+	time.Sleep(20 * time.Millisecond)
+
+	create := func(id string) *domen.Task {
+		now := time.Now()
+		task := &domen.Task{ID: id, CreatedAt: now, Status: domen.StatusPending,
+			History: []domen.Transition{{To: domen.StatusPending, At: now}}}
+		require.NoError(t, repo.Create(ctx, task))
+		return task
+	}
+	finished := func() float64 {
+		return testutil.ToFloat64(m.tasksFinished.WithLabelValues("default", "COMPLETED", "default"))
+	}
+	created := func() float64 { return testutil.ToFloat64(m.tasksCreated.WithLabelValues("default", "default")) }
+
+	a := create("a")
+	require.Eventually(t, func() bool { return created() == 1 }, time.Second, 5*time.Millisecond)
+
+	// Пока Run отключён, создаётся b и завершается a: эти события из журнала вытеснены
+	repo.drop()
+	create("b")
+	a.Status = domen.StatusCompleted
+	a.History = append(a.History, domen.Transition{From: domen.StatusPending, To: domen.StatusCompleted, At: time.Now()})
+	require.NoError(t, repo.Update(ctx, a))
+
+	require.Eventually(t, func() bool { return testutil.ToFloat64(m.watchGaps) == 1 }, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool { return created() == 2 && finished() == 1 }, time.Second, 5*time.Millisecond)
+
+	// После пропуска счёт снова идёт по потоку, без двойного учёта
+	create("c")
+	require.Eventually(t, func() bool { return created() == 3 }, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 3.0, created())
+	assert.Equal(t, 1.0, finished())
+}
+
+func TestMetrics_TaskStates(t *testing.T) {
+	repo := memory.NewInMemoryRepo()
+	ctx := context.Background()
+	now := time.Now()
+	for i, s := range []domen.Status{domen.StatusPending, domen.StatusPending, domen.StatusRunning, domen.StatusCompleted} {
+		require.NoError(t, repo.Create(ctx, &domen.Task{ID: string(rune('a' + i)), CreatedAt: now, Status: s, Type: "report"}))
+	}
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "z", CreatedAt: now, Status: domen.StatusPending, Type: "report", TenantID: "team-a"}))
+	m := New()
+	m.RegisterTaskStates(repo)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	text := string(body)
+
+	assert.Contains(t, text, `workmate_tasks_queue_depth{tenant="default",type="report"} 2`)
+	assert.Contains(t, text, `workmate_tasks_running{tenant="default",type="report"} 1`)
+	assert.Contains(t, text, `workmate_tasks_queue_depth{tenant="team-a",type="report"} 1`)
+	// Ряды типа и арендатора по умолчанию есть и при нуле
+	assert.Contains(t, text, `workmate_tasks_queue_depth{tenant="default",type="default"} 0`)
+	assert.Contains(t, text, `workmate_tasks_running{tenant="default",type="default"} 0`)
+	assert.Contains(t, text, "go_goroutines")
+}
+
+func TestInstrumentRepository(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	// Пакетные операции сохраняются, если их поддерживает исходный репозиторий
+	repo := m.InstrumentRepository(memory.NewInMemoryRepo())
+	bulk, ok := repo.(domen.BulkRepository)
+	require.True(t, ok)
+	_, err := bulk.CreateMany(ctx, []*domen.Task{{ID: "1", CreatedAt: time.Now(), Status: domen.StatusPending}})
+	require.NoError(t, err)
+	_, err = repo.Get(ctx, "1")
+	require.NoError(t, err)
+	_, err = repo.Get(ctx, "missing")
+	require.ErrorIs(t, err, domen.ErrNotFound)
+
+	assert.Equal(t, uint64(1), sampleCount(t, m, "workmate_repository_operation_duration_seconds", map[string]string{"operation": "create_many", "outcome": "ok"}))
+	assert.Equal(t, uint64(1), sampleCount(t, m, "workmate_repository_operation_duration_seconds", map[string]string{"operation": "get", "outcome": "ok"}))
+	assert.Equal(t, uint64(1), sampleCount(t, m, "workmate_repository_operation_duration_seconds", map[string]string{"operation": "get", "outcome": "error"}))
+
+	// Репозиторий без пакетных операций не притворяется BulkRepository
+	_, ok = m.InstrumentRepository(plainRepo{}).(domen.BulkRepository)
+	assert.False(t, ok)
+}
+
+type plainRepo struct{ domen.TaskRepository }
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// InstrumentRepository оборачивает репозиторий и замеряет длительность
+// операций. Watch не замеряется: это долгая подписка. Если repo
+// реализует domen.BulkRepository, обёртка тоже его реализует.
+func (m *Metrics) InstrumentRepository(repo domen.TaskRepository) domen.TaskRepository {
+	r := &instrumentedRepo{repo: repo, m: m}
+	if bulk, ok := repo.(domen.BulkRepository); ok {
+		return &instrumentedBulkRepo{instrumentedRepo: r, bulk: bulk}
+	}
+	return r
+}
+
+type instrumentedRepo struct {
+	repo domen.TaskRepository
+	m    *Metrics
+}
+
+func (m *Metrics) observeRepo(op string, start time.Time, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	m.repoDuration.WithLabelValues(op, outcome).Observe(time.Since(start).Seconds())
+}
+
+func (r *instrumentedRepo) Create(ctx context.Context, t *domen.Task) (err error) {
+	defer func(start time.Time) { r.m.observeRepo("create", start, err) }(time.Now())
+	return r.repo.Create(ctx, t)
+}
+
+func (r *instrumentedRepo) Update(ctx context.Context, t *domen.Task) (err error) {
+	defer func(start time.Time) { r.m.observeRepo("update", start, err) }(time.Now())
+	return r.repo.Update(ctx, t)
+}
+
+func (r *instrumentedRepo) Delete(ctx context.Context, id string, version int64) (err error) {
+	defer func(start time.Time) { r.m.observeRepo("delete", start, err) }(time.Now())
+	return r.repo.Delete(ctx, id, version)
+}
+
+func (r *instrumentedRepo) Get(ctx context.Context, id string) (_ *domen.Task, err error) {
+	defer func(start time.Time) { r.m.observeRepo("get", start, err) }(time.Now())
+	return r.repo.Get(ctx, id)
+}
+
+func (r *instrumentedRepo) List(ctx context.Context) (_ []*domen.Task, err error) {
+	defer func(start time.Time) { r.m.observeRepo("list", start, err) }(time.Now())
+	return r.repo.List(ctx)
+}
+
+func (r *instrumentedRepo) Find(ctx context.Context, filter domen.TaskFilter) (_ []*domen.Task, err error) {
+	defer func(start time.Time) { r.m.observeRepo("find", start, err) }(time.Now())
+	return r.repo.Find(ctx, filter)
+}
+
+func (r *instrumentedRepo) Watch(ctx context.Context, filter domen.WatchFilter) (<-chan domen.TaskChange, error) {
+	return r.repo.Watch(ctx, filter)
+}
+
+type instrumentedBulkRepo struct {
+	*instrumentedRepo
+	bulk domen.BulkRepository
+}
+
+func (r *instrumentedBulkRepo) CreateMany(ctx context.Context, tasks []*domen.Task) (_ []error, err error) {
+	defer func(start time.Time) { r.m.observeRepo("create_many", start, err) }(time.Now())
+	return r.bulk.CreateMany(ctx, tasks)
+}
+
+func (r *instrumentedBulkRepo) UpdateMany(ctx context.Context, tasks []*domen.Task) (_ []error, err error) {
+	defer func(start time.Time) { r.m.observeRepo("update_many", start, err) }(time.Now())
+	return r.bulk.UpdateMany(ctx, tasks)
+}
+
+func (r *instrumentedBulkRepo) DeleteMany(ctx context.Context, ids []string) (_ []error, err error) {
+	defer func(start time.Time) { r.m.observeRepo("delete_many", start, err) }(time.Now())
+	return r.bulk.DeleteMany(ctx, ids)
+}
@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// stateQueryTimeout ограничивает запрос к репозиторию при опросе /metrics.
+const stateQueryTimeout = 5 * time.Second
+
+// Пауза перед переподпиской, когда репозиторий закрыл канал изменений:
+// растёт вдвое, пока изменения не пойдут снова.
+const (
+	resubscribeMinDelay = 100 * time.Millisecond
+	resubscribeMaxDelay = 5 * time.Second
+)
+
+// Run следит за изменениями задач и считает созданные задачи, переходы в
+// терминальные статусы, время ожидания и выполнения. Блокируется до
+// отмены ctx; переподписывается так же, как usecase.BatchUseCase.Run.
+//
+// Если ревизия вытеснена из журнала, Run подписывается заново с текущего
+// момента, а созданные и завершённые за пропуск задачи досчитывает по
+// снимку репозитория (см. resync).
+func (m *Metrics) Run(ctx context.Context, repo domen.TaskRepository) error {
+	log := logger.FromContext(ctx)
+	var rev int64
+	// seen — время последнего учтённого события; всё, что раньше, уже посчитано
+	seen := time.Now()
+	gap := false
+	delay := resubscribeMinDelay
+	for {
+		changes, err := repo.Watch(ctx, domen.WatchFilter{FromRevision: rev})
+		if errors.Is(err, domen.ErrRevisionCompacted) {
+			log.Warnw("metrics watcher fell behind the change log, resyncing from a snapshot", "revision", rev)
+			m.watchGaps.Inc()
+			rev = 0
+			gap = true
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		// Снимок берётся уже после подписки: события до until досчитывает
+		// resync, после — поток изменений
+		if gap {
+			until := time.Now()
+			if err := m.resync(ctx, repo, seen, until); err != nil {
+				log.Errorw("failed to resync task metrics after a watch gap", "error", err)
+			}
+			seen, gap = until, false
+		}
+		received := false
+		for c := range changes {
+			rev, received = c.Revision, true
+			m.observeChange(c)
+			if at := eventTime(c); at.After(seen) {
+				seen = at
+			}
+		}
+		if received {
+			delay = resubscribeMinDelay
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, resubscribeMaxDelay)
+	}
+}
+
+// resync досчитывает созданные и завершённые задачи, чьи события попали
+// в (from, until) и не дошли до Run через Watch. Гистограммы времени за
+// пропуск не восстанавливаются, удалённые за пропуск задачи теряются.
+func (m *Metrics) resync(ctx context.Context, repo domen.TaskRepository, from, until time.Time) error {
+	tasks, err := repo.Find(ctx, domen.TaskFilter{})
+	if err != nil {
+		return err
+	}
+	within := func(at time.Time) bool { return at.After(from) && at.Before(until) }
+	for _, t := range tasks {
+		typ, tenant := taskType(t), taskTenant(t)
+		if within(t.CreatedAt) {
+			m.tasksCreated.WithLabelValues(typ, tenant).Inc()
+		}
+		if t.Status.IsTerminal() && len(t.History) > 0 && within(t.History[len(t.History)-1].At) {
+			m.tasksFinished.WithLabelValues(typ, string(t.Status), tenant).Inc()
+		}
+	}
+	return nil
+}
+
+// eventTime — когда произошло изменение: создание задачи или её последний переход.
+func eventTime(c domen.TaskChange) time.Time {
+	if c.Type == domen.ChangeCreated || len(c.Task.History) == 0 {
+		return c.Task.CreatedAt
+	}
+	return c.Task.History[len(c.Task.History)-1].At
+}
+
+func (m *Metrics) observeChange(c domen.TaskChange) {
+	t := c.Task
+	typ, tenant := taskType(t), taskTenant(t)
+	switch c.Type {
+	case domen.ChangeCreated:
+		m.tasksCreated.WithLabelValues(typ, tenant).Inc()
+		return
+	case domen.ChangeDeleted:
+		return
+	}
+
+	// Каждое обновление задачи — ровно один переход статуса, и он
+	// последний в истории.
+	if len(t.History) == 0 {
+		return
+	}
+	last := t.History[len(t.History)-1]
+	if last.To != t.Status {
+		return
+	}
+	switch {
+	case t.Status == domen.StatusRunning:
+		m.taskWaitTime.WithLabelValues(typ).Observe(last.At.Sub(t.CreatedAt).Seconds())
+	case t.Status.IsTerminal():
+		status := string(t.Status)
+		m.tasksFinished.WithLabelValues(typ, status, tenant).Inc()
+		if !t.StartedAt.IsZero() {
+			m.taskRunTime.WithLabelValues(typ, status).Observe(last.At.Sub(t.StartedAt).Seconds())
+		}
+	}
+}
+
+// taskType возвращает тип задачи; задачи, созданные до появления типов, — default.
+func taskType(t *domen.Task) string {
+	if t.Type == "" {
+		return domen.DefaultTaskType
+	}
+	return t.Type
+}
+
+// taskTenant возвращает арендатора задачи; задачи, созданные до появления
+// арендаторов, принадлежат арендатору по умолчанию.
+func taskTenant(t *domen.Task) string {
+	if t.TenantID == "" {
+		return domen.DefaultTenant
+	}
+	return t.TenantID
+}
+
+// RegisterTaskStates добавляет метрики глубины очереди (задачи PENDING) и
+// числа выполняющихся задач по типам и арендаторам. Значения считаются запросом к repo
+// при каждом опросе /metrics, поэтому верны и после перезапуска.
+func (m *Metrics) RegisterTaskStates(repo domen.TaskRepository) {
+	m.reg.MustRegister(&stateCollector{
+		repo: repo,
+		queued: prometheus.NewDesc(prometheus.BuildFQName(namespace, "tasks", "queue_depth"),
+			"Tasks waiting to start (PENDING), by task type and tenant.", []string{"type", "tenant"}, nil),
+		running: prometheus.NewDesc(prometheus.BuildFQName(namespace, "tasks", "running"),
+			"Tasks currently RUNNING, by task type and tenant.", []string{"type", "tenant"}, nil),
+	})
+}
+
+type stateCollector struct {
+	repo    domen.TaskRepository
+	queued  *prometheus.Desc
+	running *prometheus.Desc
+}
+
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queued
+	ch <- c.running
+}
+
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), stateQueryTimeout)
+	defer cancel()
+	tasks, err := c.repo.Find(ctx, domen.TaskFilter{Statuses: []domen.Status{domen.StatusPending, domen.StatusRunning}})
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.queued, err)
+		ch <- prometheus.NewInvalidMetric(c.running, err)
+		return
+	}
+
+	// Ряд типа и арендатора по умолчанию отдаётся всегда, чтобы не пропадать при нуле
+	type series struct{ typ, tenant string }
+	type counts struct{ queued, running int }
+	bySeries := map[series]*counts{{domen.DefaultTaskType, domen.DefaultTenant}: {}}
+	for _, t := range tasks {
+		key := series{taskType(t), taskTenant(t)}
+		n := bySeries[key]
+		if n == nil {
+			n = &counts{}
+			bySeries[key] = n
+		}
+		if t.Status == domen.StatusPending {
+			n.queued++
+		} else {
+			n.running++
+		}
+	}
+	for key, n := range bySeries {
+		ch <- prometheus.MustNewConstMetric(c.queued, prometheus.GaugeValue, float64(n.queued), key.typ, key.tenant)
+		ch <- prometheus.MustNewConstMetric(c.running, prometheus.GaugeValue, float64(n.running), key.typ, key.tenant)
+	}
+}
@@ -0,0 +1,194 @@
+// Package policy реализует domen.Policy: ролевую модель доступа к задачам
+// с проверкой владельца задачи по CreatedBy.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// AnyAction в списке действий правила разрешает все действия.
+const AnyAction = "*"
+
+// File — содержимое файла политики:
+//
+//	{
+//	  "default_roles": ["user"],
+//	  "bindings": {"ci": ["operator"]},
+//	  "roles": {
+//	    "user": [
+//	      {"actions": ["task:read", "task:create"]},
+//	      {"actions": ["task:cancel", "task:delete", "task:rerun"], "own": true}
+//	    ],
+//	    "operator": [{"actions": ["*"]}]
+//	  }
+//	}
+//
+// Роли принципала — его собственные (из API-ключа или токена) и
+// назначенные в bindings по ID; если ролей нет, действуют default_roles.
+type File struct {
+	DefaultRoles []string            `json:"default_roles"`
+	Bindings     map[string][]string `json:"bindings"`
+	Roles        map[string][]Rule   `json:"roles"`
+}
+
+// Rule разрешает действия над задачами арендатора принципала.
+type Rule struct {
+	Actions []string `json:"actions"`
+	// Own ограничивает правило задачами, созданными самим принципалом.
+	Own bool `json:"own,omitempty"`
+}
+
+func (r Rule) allows(action string) bool {
+	return slices.Contains(r.Actions, action) || slices.Contains(r.Actions, AnyAction)
+}
+
+// Engine — политика доступа. Принципал с областью admin может всё;
+// задачи чужих арендаторов ему всё равно не видны.
+type Engine struct {
+	path string
+	file atomic.Pointer[File]
+}
+
+// New проверяет политику: у правил известные действия, роли из
+// default_roles и bindings описаны в roles.
+func New(f File) (*Engine, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	e := &Engine{}
+	e.file.Store(&f)
+	return e, nil
+}
+
+// Load читает политику из JSON-файла.
+func Load(path string) (*Engine, error) {
+	f, err := loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	e := &Engine{path: path}
+	e.file.Store(f)
+	return e, nil
+}
+
+// Reload перечитывает файл политики. При ошибке остаётся прежняя политика.
+func (e *Engine) Reload() error {
+	if e.path == "" {
+		return nil
+	}
+	f, err := loadFile(e.path)
+	if err != nil {
+		return err
+	}
+	e.file.Store(f)
+	return nil
+}
+
+func loadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, nil
+}
+
+func (f File) validate() error {
+	for name, rules := range f.Roles {
+		for _, r := range rules {
+			if len(r.Actions) == 0 {
+				return fmt.Errorf("role %s: rule without actions", name)
+			}
+			for _, a := range r.Actions {
+				if a != AnyAction && !slices.Contains(domen.KnownActions, a) {
+					return fmt.Errorf("role %s: unknown action %q", name, a)
+				}
+			}
+		}
+	}
+	for _, role := range f.DefaultRoles {
+		if _, ok := f.Roles[role]; !ok {
+			return fmt.Errorf("default_roles: unknown role %q", role)
+		}
+	}
+	for id, roles := range f.Bindings {
+		for _, role := range roles {
+			if _, ok := f.Roles[role]; !ok {
+				return fmt.Errorf("bindings %s: unknown role %q", id, role)
+			}
+		}
+	}
+	return nil
+}
+
+// Roles возвращает роли принципала по политике, упорядоченные по имени.
+func (e *Engine) Roles(p *domen.Principal) []string {
+	return e.file.Load().rolesOf(p)
+}
+
+func (f *File) rolesOf(p *domen.Principal) []string {
+	roles := slices.Concat(p.Roles, f.Bindings[p.ID])
+	if len(roles) == 0 {
+		roles = slices.Clone(f.DefaultRoles)
+	}
+	sort.Strings(roles)
+	return slices.Compact(roles)
+}
+
+// Authorize реализует domen.Policy. Создание (task == nil) ограничением
+// own не запрещается: новая задача и так принадлежит принципалу.
+func (e *Engine) Authorize(p *domen.Principal, action string, task *domen.Task) error {
+	if p.HasScope(domen.ScopeAdmin) {
+		return nil
+	}
+	f := e.file.Load()
+	roles := f.rolesOf(p)
+	ownOnly := false
+	for _, role := range roles {
+		for _, r := range f.Roles[role] {
+			if !r.allows(action) {
+				continue
+			}
+			if !r.Own || task == nil || task.CreatedBy == p.ID {
+				return nil
+			}
+			ownOnly = true
+		}
+	}
+
+	denial := domen.AccessDenial{Action: action, Roles: roles}
+	if roles == nil {
+		denial.Roles = []string{}
+	}
+	if task != nil {
+		denial.TaskID = task.ID
+	}
+	switch {
+	case len(roles) == 0:
+		denial.Reason = "principal has no roles"
+	case ownOnly:
+		owner := task.CreatedBy
+		if owner == "" {
+			owner = "an anonymous client"
+		}
+		denial.Reason = fmt.Sprintf("task was created by %s; roles [%s] may %s only own tasks",
+			owner, strings.Join(roles, " "), action)
+	default:
+		denial.Reason = fmt.Sprintf("roles [%s] do not allow %s", strings.Join(roles, " "), action)
+	}
+	return &domen.AccessDeniedError{AccessDenial: denial, Principal: p.ID}
+}
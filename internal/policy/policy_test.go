@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPolicy: пользователи отменяют, удаляют и повторяют только свои
+// задачи, операторы — любые задачи арендатора.
+const testPolicy = `{
+	"default_roles": ["user"],
+	"bindings": {"ops": ["operator"]},
+	"roles": {
+		"user": [
+			{"actions": ["task:read", "task:create"]},
+			{"actions": ["task:cancel", "task:delete", "task:rerun"], "own": true}
+		],
+		"operator": [{"actions": ["*"]}],
+		"viewer": [{"actions": ["task:read"]}]
+	}
+}`
+
+func writePolicy(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	return path
+}
+
+func TestAuthorize(t *testing.T) {
+	e, err := Load(writePolicy(t, testPolicy))
+	require.NoError(t, err)
+
+	alice := &domen.Principal{ID: "alice"}
+	ops := &domen.Principal{ID: "ops"}
+	viewer := &domen.Principal{ID: "bob", Roles: []string{"viewer"}}
+	admin := &domen.Principal{ID: "root", Scopes: []string{domen.ScopeAdmin}, Roles: []string{"viewer"}}
+	own := &domen.Task{ID: "t1", CreatedBy: "alice"}
+	foreign := &domen.Task{ID: "t2", CreatedBy: "carol"}
+
+	cases := []struct {
+		name    string
+		p       *domen.Principal
+		action  string
+		task    *domen.Task
+		allowed bool
+	}{
+		{"user creates", alice, domen.ActionTaskCreate, nil, true},
+		{"user reads foreign", alice, domen.ActionTaskRead, foreign, true},
+		{"user cancels own", alice, domen.ActionTaskCancel, own, true},
+		{"user cancels foreign", alice, domen.ActionTaskCancel, foreign, false},
+		{"user deletes foreign", alice, domen.ActionTaskDelete, foreign, false},
+		{"operator cancels foreign", ops, domen.ActionTaskCancel, foreign, true},
+		{"operator deletes foreign", ops, domen.ActionTaskDelete, foreign, true},
+		{"viewer creates", viewer, domen.ActionTaskCreate, nil, false},
+		{"viewer reads", viewer, domen.ActionTaskRead, own, true},
+		{"admin scope cancels foreign", admin, domen.ActionTaskCancel, foreign, true},
+	}
+	for _, c := range cases {
+		err := e.Authorize(c.p, c.action, c.task)
+		if c.allowed {
+			assert.NoError(t, err, c.name)
+			continue
+		}
+		assert.ErrorIs(t, err, domen.ErrForbidden, c.name)
+	}
+}
+
+func TestAuthorize_Denial(t *testing.T) {
+	e, err := Load(writePolicy(t, testPolicy))
+	require.NoError(t, err)
+
+	err = e.Authorize(&domen.Principal{ID: "alice"}, domen.ActionTaskCancel, &domen.Task{ID: "t2", CreatedBy: "carol"})
+	var denied *domen.AccessDeniedError
+	require.True(t, errors.As(err, &denied))
+	assert.Equal(t, "alice", denied.Principal)
+	assert.Equal(t, domen.ActionTaskCancel, denied.Action)
+	assert.Equal(t, "t2", denied.TaskID)
+	assert.Equal(t, []string{"user"}, denied.Roles)
+	assert.Equal(t, "task was created by carol; roles [user] may task:cancel only own tasks", denied.Reason)
+
+	err = e.Authorize(&domen.Principal{ID: "bob", Roles: []string{"viewer"}}, domen.ActionTaskCreate, nil)
+	require.True(t, errors.As(err, &denied))
+	assert.Equal(t, "roles [viewer] do not allow task:create", denied.Reason)
+
+	// Роли, которых нет в политике, ничего не разрешают
+	err = e.Authorize(&domen.Principal{ID: "svc", Roles: []string{"billing"}}, domen.ActionTaskRead, nil)
+	require.True(t, errors.As(err, &denied))
+	assert.Equal(t, []string{"billing"}, denied.Roles)
+}
+
+func TestRoles(t *testing.T) {
+	e, err := Load(writePolicy(t, testPolicy))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"user"}, e.Roles(&domen.Principal{ID: "alice"}))
+	assert.Equal(t, []string{"operator"}, e.Roles(&domen.Principal{ID: "ops"}))
+	assert.Equal(t, []string{"operator", "viewer"}, e.Roles(&domen.Principal{ID: "ops", Roles: []string{"viewer", "operator"}}))
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"not json":             `{`,
+		"unknown action":       `{"roles": {"user": [{"actions": ["task:launch"]}]}}`,
+		"rule without actions": `{"roles": {"user": [{"own": true}]}}`,
+		"unknown default role": `{"default_roles": ["user"], "roles": {}}`,
+		"unknown bound role":   `{"bindings": {"ci": ["operator"]}, "roles": {"user": []}}`,
+	}
+	for name, data := range cases {
+		_, err := Load(writePolicy(t, data))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := writePolicy(t, testPolicy)
+	e, err := Load(path)
+	require.NoError(t, err)
+	alice := &domen.Principal{ID: "alice"}
+	foreign := &domen.Task{ID: "t2", CreatedBy: "carol"}
+	require.Error(t, e.Authorize(alice, domen.ActionTaskCancel, foreign))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"default_roles": ["operator"], "roles": {"operator": [{"actions": ["*"]}]}}`), 0o600))
+	require.NoError(t, e.Reload())
+	assert.NoError(t, e.Authorize(alice, domen.ActionTaskCancel, foreign))
+
+	// Битый файл не сбрасывает действующую политику
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	assert.Error(t, e.Reload())
+	assert.NoError(t, e.Authorize(alice, domen.ActionTaskCancel, foreign))
+}
@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/gaz358/myprog/workmate/internal/tracing"
+
+// InstrumentRepository оборачивает репозиторий и открывает span на каждую
+// операцию. Watch не трассируется: это долгая подписка. Если repo
+// реализует domen.BulkRepository, обёртка тоже его реализует.
+func InstrumentRepository(repo domen.TaskRepository) domen.TaskRepository {
+	r := &tracedRepo{repo: repo, tracer: otel.Tracer(tracerName)}
+	if bulk, ok := repo.(domen.BulkRepository); ok {
+		return &tracedBulkRepo{tracedRepo: r, bulk: bulk}
+	}
+	return r
+}
+
+type tracedRepo struct {
+	repo   domen.TaskRepository
+	tracer trace.Tracer
+}
+
+func (r *tracedRepo) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, "repository."+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (r *tracedRepo) Create(ctx context.Context, t *domen.Task) (err error) {
+	ctx, span := r.start(ctx, "Create", attribute.String("task.id", t.ID))
+	defer func() { end(span, err) }()
+	return r.repo.Create(ctx, t)
+}
+
+func (r *tracedRepo) Update(ctx context.Context, t *domen.Task) (err error) {
+	ctx, span := r.start(ctx, "Update", attribute.String("task.id", t.ID))
+	defer func() { end(span, err) }()
+	return r.repo.Update(ctx, t)
+}
+
+func (r *tracedRepo) Delete(ctx context.Context, id string, version int64) (err error) {
+	ctx, span := r.start(ctx, "Delete", attribute.String("task.id", id))
+	defer func() { end(span, err) }()
+	return r.repo.Delete(ctx, id, version)
+}
+
+func (r *tracedRepo) Get(ctx context.Context, id string) (_ *domen.Task, err error) {
+	ctx, span := r.start(ctx, "Get", attribute.String("task.id", id))
+	defer func() { end(span, err) }()
+	return r.repo.Get(ctx, id)
+}
+
+func (r *tracedRepo) List(ctx context.Context) (_ []*domen.Task, err error) {
+	ctx, span := r.start(ctx, "List")
+	defer func() { end(span, err) }()
+	return r.repo.List(ctx)
+}
+
+func (r *tracedRepo) Find(ctx context.Context, filter domen.TaskFilter) (_ []*domen.Task, err error) {
+	ctx, span := r.start(ctx, "Find", attribute.Int("filter.limit", filter.Limit))
+	defer func() { end(span, err) }()
+	return r.repo.Find(ctx, filter)
+}
+
+func (r *tracedRepo) Watch(ctx context.Context, filter domen.WatchFilter) (<-chan domen.TaskChange, error) {
+	return r.repo.Watch(ctx, filter)
+}
+
+type tracedBulkRepo struct {
+	*tracedRepo
+	bulk domen.BulkRepository
+}
+
+func (r *tracedBulkRepo) CreateMany(ctx context.Context, tasks []*domen.Task) (_ []error, err error) {
+	ctx, span := r.start(ctx, "CreateMany", attribute.Int("tasks.count", len(tasks)))
+	defer func() { end(span, err) }()
+	return r.bulk.CreateMany(ctx, tasks)
+}
+
+func (r *tracedBulkRepo) UpdateMany(ctx context.Context, tasks []*domen.Task) (_ []error, err error) {
+	ctx, span := r.start(ctx, "UpdateMany", attribute.Int("tasks.count", len(tasks)))
+	defer func() { end(span, err) }()
+	return r.bulk.UpdateMany(ctx, tasks)
+}
+
+func (r *tracedBulkRepo) DeleteMany(ctx context.Context, ids []string) (_ []error, err error) {
+	ctx, span := r.start(ctx, "DeleteMany", attribute.Int("tasks.count", len(ids)))
+	defer func() { end(span, err) }()
+	return r.bulk.DeleteMany(ctx, ids)
+}
@@ -0,0 +1,31 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(context.Background(), ExporterNone, "")
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), "jaeger", "")
+	assert.Error(t, err)
+}
+
+func TestInstrumentRepository(t *testing.T) {
+	_, ok := InstrumentRepository(memory.NewInMemoryRepo()).(domen.BulkRepository)
+	assert.True(t, ok)
+
+	// Репозиторий без пакетных операций не притворяется BulkRepository
+	_, ok = InstrumentRepository(plainRepo{}).(domen.BulkRepository)
+	assert.False(t, ok)
+}
+
+type plainRepo struct{ domen.TaskRepository }
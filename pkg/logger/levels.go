@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Уровни именованных логгеров. Логгеры, созданные с глобальным уровнем,
+// сверяют каждую запись с уровнем своего имени, а если он не задан —
+// с уровнем ближайшего предка ("http" для "http.client") или глобальным.
+var named = struct {
+	sync.RWMutex
+	levels map[string]LogLevel
+}{levels: make(map[string]LogLevel)}
+
+// SetNamedLevel sets level for logger n and its descendants
+func SetNamedLevel(n string, l LogLevel) {
+	named.Lock()
+	defer named.Unlock()
+	named.levels[n] = l
+}
+
+// ResetNamedLevel makes logger n follow the global level again
+func ResetNamedLevel(n string) {
+	named.Lock()
+	defer named.Unlock()
+	delete(named.levels, n)
+}
+
+// NamedLevels returns levels set by SetNamedLevel
+func NamedLevels() map[string]LogLevel {
+	named.RLock()
+	defer named.RUnlock()
+	out := make(map[string]LogLevel, len(named.levels))
+	for n, l := range named.levels {
+		out[n] = l
+	}
+	return out
+}
+
+// LevelOf returns effective level of logger n
+func LevelOf(n string) LogLevel {
+	named.RLock()
+	defer named.RUnlock()
+	return levelOf(n)
+}
+
+func levelOf(n string) LogLevel {
+	for n != "" {
+		if l, ok := named.levels[n]; ok {
+			return l
+		}
+		i := strings.LastIndexByte(n, '.')
+		if i < 0 {
+			break
+		}
+		n = n[:i]
+	}
+	return defaultLevel.Level()
+}
+
+// minLevel — самый подробный из действующих уровней: ниже него записи
+// не нужны ни одному логгеру.
+func minLevel() LogLevel {
+	named.RLock()
+	defer named.RUnlock()
+	lvl := defaultLevel.Level()
+	for _, l := range named.levels {
+		if l < lvl {
+			lvl = l
+		}
+	}
+	return lvl
+}
+
+// KnownLevels lists level names accepted by ParseLevel
+func KnownLevels() []string {
+	return []string{DebugLevel.String(), InfoLevel.String(), WarnLevel.String(), ErrorLevel.String()}
+}
+
+// ParseLevel parses level name such as "debug" or "warn"
+func ParseLevel(s string) (LogLevel, error) {
+	return zapcore.ParseLevel(s)
+}
+
+func usesGlobalLevel(level LevelEnabler) bool {
+	switch l := level.(type) {
+	case zap.AtomicLevel:
+		return l == defaultLevel
+	case namedLevel:
+		return true
+	}
+	return false
+}
+
+// namedLevel — LevelEnabler логгера n с глобальным уровнем.
+type namedLevel string
+
+func (n namedLevel) Enabled(l LogLevel) bool {
+	return l >= LevelOf(string(n))
+}
+
+// namedLevelCore фильтрует записи по уровню имени логгера. Вложенное ядро
+// пропускает все уровни.
+type namedLevelCore struct {
+	zapcore.Core
+}
+
+func (c namedLevelCore) Enabled(l LogLevel) bool {
+	return l >= minLevel()
+}
+
+func (c namedLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return namedLevelCore{c.Core.With(fields)}
+}
+
+func (c namedLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < LevelOf(ent.LoggerName) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedLevels(t *testing.T) {
+	prev := Level()
+	SetLevel(InfoLevel)
+	t.Cleanup(func() {
+		SetLevel(prev)
+		ResetNamedLevel("http")
+	})
+
+	var buf bytes.Buffer
+	root := NewWithSink(nil, &buf)
+	httpLog, client, mainLog := root.Named("http"), root.Named("http").Named("client"), root.Named("main")
+
+	httpLog.Debug("hidden")
+	SetNamedLevel("http", DebugLevel)
+	httpLog.Debug("http debug")
+	client.Debug("client debug")
+	mainLog.Debug("main hidden")
+	mainLog.Info("main info")
+
+	out := buf.String()
+	assert.NotContains(t, out, "hidden")
+	assert.Contains(t, out, "http debug")
+	assert.Contains(t, out, "client debug", "уровень наследуется потомком http.client")
+	assert.Contains(t, out, "main info")
+	assert.True(t, httpLog.Enabled(DebugLevel))
+	assert.False(t, mainLog.Enabled(DebugLevel))
+	assert.Equal(t, map[string]LogLevel{"http": DebugLevel}, NamedLevels())
+
+	// Именованный уровень может быть и строже глобального
+	buf.Reset()
+	SetNamedLevel("http", ErrorLevel)
+	httpLog.Warn("http warn")
+	mainLog.Warn("main warn")
+	assert.False(t, strings.Contains(buf.String(), "http warn"))
+	assert.Contains(t, buf.String(), "main warn")
+
+	ResetNamedLevel("http")
+	assert.Equal(t, InfoLevel, LevelOf("http.client"))
+}
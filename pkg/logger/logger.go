@@ -51,28 +51,34 @@ func NewWithSink(level LevelEnabler, sink io.Writer, options ...zap.Option) Type
 	if level == nil {
 		level = defaultLevel
 	}
+	// С глобальным уровнем фильтрует namedLevelCore, с учётом имени логгера
+	coreLevel := level
+	if usesGlobalLevel(level) {
+		coreLevel = zap.LevelEnablerFunc(func(LogLevel) bool { return true })
+	}
+	core := zapcore.NewCore(
+		zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+			TimeKey:        "ts",
+			LevelKey:       "lvl",
+			NameKey:        "log-of",
+			CallerKey:      "at",
+			MessageKey:     "msg",
+			StacktraceKey:  "stack",
+			LineEnding:     zapcore.DefaultLineEnding,
+			EncodeLevel:    zapcore.LowercaseLevelEncoder,
+			EncodeTime:     zapcore.ISO8601TimeEncoder,
+			EncodeDuration: zapcore.SecondsDurationEncoder,
+			EncodeCaller:   zapcore.ShortCallerEncoder,
+		}),
+		zapcore.AddSync(sink),
+		coreLevel,
+	)
+	if usesGlobalLevel(level) {
+		core = namedLevelCore{core}
+	}
 	return TypeOfLogger{
-		LevelEnabler: level,
-		SugaredLogger: zap.New(
-			zapcore.NewCore(
-				zapcore.NewJSONEncoder(zapcore.EncoderConfig{
-					TimeKey:        "ts",
-					LevelKey:       "lvl",
-					NameKey:        "log-of",
-					CallerKey:      "at",
-					MessageKey:     "msg",
-					StacktraceKey:  "stack",
-					LineEnding:     zapcore.DefaultLineEnding,
-					EncodeLevel:    zapcore.LowercaseLevelEncoder,
-					EncodeTime:     zapcore.ISO8601TimeEncoder,
-					EncodeDuration: zapcore.SecondsDurationEncoder,
-					EncodeCaller:   zapcore.ShortCallerEncoder,
-				}),
-				zapcore.AddSync(sink),
-				level,
-			),
-			options...,
-		).Sugar(),
+		LevelEnabler:  level,
+		SugaredLogger: zap.New(core, options...).Sugar(),
 	}
 }
 
@@ -80,6 +86,9 @@ func NewWithSink(level LevelEnabler, sink io.Writer, options ...zap.Option) Type
 func (lg TypeOfLogger) Named(n string) TypeOfLogger {
 	ret := lg
 	ret.SugaredLogger = lg.SugaredLogger.Named(n)
+	if usesGlobalLevel(lg.LevelEnabler) {
+		ret.LevelEnabler = namedLevel(ret.Desugar().Name())
+	}
 	return ret
 }
 
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+type BatchRepo struct {
+	mu      sync.RWMutex
+	batches map[string]*domen.Batch
+}
+
+func NewBatchRepo() *BatchRepo {
+	return &BatchRepo{batches: make(map[string]*domen.Batch)}
+}
+
+func (r *BatchRepo) Create(ctx context.Context, b *domen.Batch) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.batches[b.ID]; ok {
+		return domen.ErrAlreadyExists
+	}
+	r.batches[b.ID] = b.Clone()
+	return nil
+}
+
+func (r *BatchRepo) Get(ctx context.Context, id string) (*domen.Batch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	b, ok := r.batches[id]
+	if !ok {
+		return nil, domen.ErrNotFound
+	}
+	return b.Clone(), nil
+}
+
+func (r *BatchRepo) Finish(ctx context.Context, id string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.batches[id]
+	if !ok {
+		return domen.ErrNotFound
+	}
+	if !b.FinishedAt.IsZero() {
+		return domen.ErrConflict
+	}
+	b.FinishedAt = at
+	return nil
+}
+
+func (r *BatchRepo) ListUnfinished(ctx context.Context) ([]*domen.Batch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*domen.Batch, 0)
+	for _, b := range r.batches {
+		if b.FinishedAt.IsZero() {
+			out = append(out, b.Clone())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
@@ -1,133 +1,86 @@
 package memory
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math/rand"
-	"sync"
+	"sync/atomic"
 	"testing"
-	"time"
 
 	"github.com/gaz358/myprog/workmate/domen"
 )
 
-func TestInMemoryRepo_Concurrency(t *testing.T) {
-	repo := NewInMemoryRepo()
-	const n = 100
-	var wg sync.WaitGroup
-
-	// Параллельное создание задач
-	for i := 0; i < n; i++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			tid := fmt.Sprintf("task-%d", id)
-			task := &domen.Task{
-				ID:     tid,
-				Status: domen.StatusPending,
-			}
-			if err := repo.Create(task); err != nil {
-				t.Errorf("create err: %v", err)
-			}
-		}(i)
-	}
-	wg.Wait()
-
-	// Проверяем, что все задачи создались
-	tasks, err := repo.List()
-	if err != nil {
-		t.Fatalf("List error: %v", err)
-	}
-	if len(tasks) != n {
-		t.Fatalf("want %d, got %d", n, len(tasks))
-	}
-
-	// Параллельное обновление задач на COMPLETED
-	for i := 0; i < n; i++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			tid := fmt.Sprintf("task-%d", id)
-			task := &domen.Task{
-				ID:     tid,
-				Status: domen.StatusCompleted,
-			}
-			if err := repo.Update(task); err != nil {
-				t.Errorf("update err: %v", err)
-			}
-		}(i)
-	}
-	wg.Wait()
-
-	for i := 0; i < n; i++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			tid := fmt.Sprintf("task-%d", id)
-			task := &domen.Task{
-				ID:     tid,
-				Status: domen.StatusFailed,
-			}
-			if err := repo.Update(task); err != nil {
-				t.Errorf("update err: %v", err)
-			}
-		}(i)
-	}
-	wg.Wait()
-
-	// Смешанный блок: параллельно Update и Get (50/50)
-	rand.Seed(time.Now().UnixNano())
-	for i := 0; i < n; i++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			tid := fmt.Sprintf("task-%d", id)
-			if rand.Intn(2) == 0 {
-				// Update
-				task := &domen.Task{
-					ID:     tid,
-					Status: domen.StatusCancelled,
-				}
-				if err := repo.Update(task); err != nil {
-					t.Errorf("update err: %v", err)
-				}
-			} else {
-				// Get
-				if _, err := repo.Get(tid); err != nil {
-					t.Errorf("get err: %v", err)
+// Сравнение InMemoryRepo и ShardedRepo под параллельной нагрузкой:
+// go test -run xxx -bench Parallel -cpu 1,4,16 ./repository/memory
+func BenchmarkRepo_ParallelCreateUpdate(b *testing.B) {
+	for _, bc := range benchRepos() {
+		b.Run(bc.name, func(b *testing.B) {
+			repo := bc.repo()
+			ctx := context.Background()
+			var seq atomic.Int64
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					task := &domen.Task{ID: fmt.Sprintf("task-%d", seq.Add(1)), Status: domen.StatusPending}
+					if err := repo.Create(ctx, task); err != nil {
+						b.Error(err)
+						return
+					}
+					task.Status = domen.StatusRunning
+					if err := repo.Update(ctx, task); err != nil {
+						b.Error(err)
+						return
+					}
 				}
-			}
-		}(i)
-	}
-	wg.Wait()
-
-	// Еще раз проверим, что задачи есть
-	tasks, err = repo.List()
-	if err != nil {
-		t.Fatalf("List error: %v", err)
-	}
-	if len(tasks) != n {
-		t.Fatalf("want %d, got %d", n, len(tasks))
+			})
+		})
 	}
+}
 
-	// Параллельное удаление задач
-	for i := 0; i < n; i++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			tid := fmt.Sprintf("task-%d", id)
-			if err := repo.Delete(tid); err != nil {
-				t.Errorf("delete err: %v", err)
+func BenchmarkRepo_ParallelMixed(b *testing.B) {
+	const preloaded = 10_000
+	for _, bc := range benchRepos() {
+		b.Run(bc.name, func(b *testing.B) {
+			repo := bc.repo()
+			ctx := context.Background()
+			for i := 0; i < preloaded; i++ {
+				if err := repo.Create(ctx, &domen.Task{ID: fmt.Sprintf("task-%d", i)}); err != nil {
+					b.Fatal(err)
+				}
 			}
-		}(i)
+			var seq atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := seq.Add(1)
+					id := fmt.Sprintf("task-%d", n%preloaded)
+					// 3 чтения на 1 запись
+					task, err := repo.Get(ctx, id)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+					if n%4 == 0 {
+						task.Result = "OK"
+						if err := repo.Update(ctx, task); err != nil && !errors.Is(err, domen.ErrConflict) {
+							b.Error(err)
+							return
+						}
+					}
+				}
+			})
+		})
 	}
-	wg.Wait()
+}
 
-	// После удаления ничего не должно остаться
-	tasks, err = repo.List()
-	if err != nil {
-		t.Fatalf("List error: %v", err)
-	}
-	if len(tasks) != 0 {
-		t.Fatalf("want 0, got %d", len(tasks))
+func benchRepos() []struct {
+	name string
+	repo func() domen.TaskRepository
+} {
+	return []struct {
+		name string
+		repo func() domen.TaskRepository
+	}{
+		{"InMemory", func() domen.TaskRepository { return NewInMemoryRepo() }},
+		{"Sharded", func() domen.TaskRepository { return NewShardedRepo(DefaultShards) }},
 	}
 }
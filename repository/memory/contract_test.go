@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/repository/repotest"
+)
+
+func TestInMemoryRepo_Contract(t *testing.T) {
+	repotest.RunContract(t, func(t *testing.T) domen.TaskRepository {
+		return NewInMemoryRepo()
+	})
+}
+
+func TestShardedRepo_Contract(t *testing.T) {
+	repotest.RunContract(t, func(t *testing.T) domen.TaskRepository {
+		return NewShardedRepo(4)
+	})
+}
+
+func TestBatchRepo_Contract(t *testing.T) {
+	repotest.RunBatchContract(t, func(t *testing.T) domen.BatchRepository {
+		return NewBatchRepo()
+	})
+}
@@ -0,0 +1,197 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/google/btree"
+)
+
+const indexDegree = 32
+
+// indexKey упорядочивает задачи по CreatedAt, а при равенстве — по ID.
+type indexKey struct {
+	createdAt time.Time
+	id        string
+}
+
+func keyOf(t *domen.Task) indexKey {
+	return indexKey{createdAt: t.CreatedAt, id: t.ID}
+}
+
+func lessKey(a, b indexKey) bool {
+	if !a.createdAt.Equal(b.createdAt) {
+		return a.createdAt.Before(b.createdAt)
+	}
+	return a.id < b.id
+}
+
+// taskIndex — вторичные индексы InMemoryRepo: все задачи по времени
+// создания, отдельно задачи каждого статуса и повторы каждой задачи
+// по времени создания. Изменяется только под r.mu.Lock вместе с картой задач.
+type taskIndex struct {
+	byCreated *btree.BTreeG[indexKey]
+	byStatus  map[domen.Status]*btree.BTreeG[indexKey]
+	byRerunOf map[string]*btree.BTreeG[indexKey]
+}
+
+func newTaskIndex() *taskIndex {
+	return &taskIndex{
+		byCreated: btree.NewG(indexDegree, lessKey),
+		byStatus:  make(map[domen.Status]*btree.BTreeG[indexKey]),
+		byRerunOf: make(map[string]*btree.BTreeG[indexKey]),
+	}
+}
+
+func (ix *taskIndex) add(t *domen.Task) {
+	k := keyOf(t)
+	ix.byCreated.ReplaceOrInsert(k)
+	tree, ok := ix.byStatus[t.Status]
+	if !ok {
+		tree = btree.NewG(indexDegree, lessKey)
+		ix.byStatus[t.Status] = tree
+	}
+	tree.ReplaceOrInsert(k)
+	if t.RerunOf != "" {
+		tree, ok := ix.byRerunOf[t.RerunOf]
+		if !ok {
+			tree = btree.NewG(indexDegree, lessKey)
+			ix.byRerunOf[t.RerunOf] = tree
+		}
+		tree.ReplaceOrInsert(k)
+	}
+}
+
+func (ix *taskIndex) remove(t *domen.Task) {
+	k := keyOf(t)
+	ix.byCreated.Delete(k)
+	if tree, ok := ix.byStatus[t.Status]; ok {
+		tree.Delete(k)
+	}
+	if tree, ok := ix.byRerunOf[t.RerunOf]; ok {
+		tree.Delete(k)
+		if tree.Len() == 0 {
+			delete(ix.byRerunOf, t.RerunOf)
+		}
+	}
+}
+
+// update переиндексирует задачу, только если изменились индексируемые поля.
+func (ix *taskIndex) update(old, cur *domen.Task) {
+	if old.Status == cur.Status && old.CreatedAt.Equal(cur.CreatedAt) && old.RerunOf == cur.RerunOf {
+		return
+	}
+	ix.remove(old)
+	ix.add(cur)
+}
+
+// all возвращает ID всех задач в порядке создания.
+func (ix *taskIndex) all() []string {
+	ids := make([]string, 0, ix.byCreated.Len())
+	ix.byCreated.Ascend(func(k indexKey) bool {
+		ids = append(ids, k.id)
+		return true
+	})
+	return ids
+}
+
+// find возвращает ID задач, подходящих под статусы и диапазон CreatedAt
+// фильтра, в запрошенном порядке и с учётом Limit. Если задан RerunOf,
+// статусы индекс не проверяет — это делает store.find.
+func (ix *taskIndex) find(f domen.TaskFilter) []string {
+	if f.RerunOf != "" {
+		tree, ok := ix.byRerunOf[f.RerunOf]
+		if !ok {
+			return nil
+		}
+		return keyIDs(walk(tree, f))
+	}
+	if len(f.Statuses) == 0 {
+		return keyIDs(walk(ix.byCreated, f))
+	}
+
+	statuses := uniqueStatuses(f.Statuses)
+	var keys []indexKey
+	for _, s := range statuses {
+		if tree, ok := ix.byStatus[s]; ok {
+			keys = append(keys, walk(tree, f)...)
+		}
+	}
+	if len(statuses) > 1 {
+		sort.Slice(keys, func(i, j int) bool {
+			if f.NewestFirst {
+				return lessKey(keys[j], keys[i])
+			}
+			return lessKey(keys[i], keys[j])
+		})
+		if f.Limit > 0 && len(keys) > f.Limit {
+			keys = keys[:f.Limit]
+		}
+	}
+	return keyIDs(keys)
+}
+
+// walk обходит дерево в границах [CreatedFrom, CreatedTo) фильтра.
+func walk(tree *btree.BTreeG[indexKey], f domen.TaskFilter) []indexKey {
+	from := indexKey{createdAt: f.CreatedFrom}
+	to := indexKey{createdAt: f.CreatedTo}
+	hasFrom, hasTo := !f.CreatedFrom.IsZero(), !f.CreatedTo.IsZero()
+
+	var keys []indexKey
+	collect := func(k indexKey) bool {
+		keys = append(keys, k)
+		return f.Limit == 0 || len(keys) < f.Limit
+	}
+
+	if f.NewestFirst {
+		iter := func(k indexKey) bool {
+			if hasTo && !lessKey(k, to) {
+				return true // DescendLessOrEqual включает саму границу
+			}
+			if hasFrom && lessKey(k, from) {
+				return false
+			}
+			return collect(k)
+		}
+		if hasTo {
+			tree.DescendLessOrEqual(to, iter)
+		} else {
+			tree.Descend(iter)
+		}
+		return keys
+	}
+
+	iter := func(k indexKey) bool {
+		if hasTo && !lessKey(k, to) {
+			return false
+		}
+		return collect(k)
+	}
+	if hasFrom {
+		tree.AscendGreaterOrEqual(from, iter)
+	} else {
+		tree.Ascend(iter)
+	}
+	return keys
+}
+
+func keyIDs(keys []indexKey) []string {
+	ids := make([]string, len(keys))
+	for i, k := range keys {
+		ids[i] = k.id
+	}
+	return ids
+}
+
+func uniqueStatuses(statuses []domen.Status) []domen.Status {
+	seen := make(map[domen.Status]bool, len(statuses))
+	out := statuses[:0:0]
+	for _, s := range statuses {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// benchTasks — размер репозитория в бенчмарках: go test -bench . -benchtime 10x ./repository/memory
+const benchTasks = 1_000_000
+
+var (
+	benchRepoOnce sync.Once
+	benchRepo     *InMemoryRepo
+)
+
+// loadedRepo заполняет репозиторий один раз на все бенчмарки:
+// 1% RUNNING, 9% PENDING, остальные COMPLETED.
+func loadedRepo(b *testing.B) *InMemoryRepo {
+	b.Helper()
+	benchRepoOnce.Do(func() {
+		benchRepo = NewInMemoryRepo()
+		ctx := context.Background()
+		base := time.Now().Add(-benchTasks * time.Millisecond)
+		for i := 0; i < benchTasks; i++ {
+			status := domen.StatusCompleted
+			switch {
+			case i%100 == 0:
+				status = domen.StatusRunning
+			case i%10 == 0:
+				status = domen.StatusPending
+			}
+			task := &domen.Task{
+				ID:        fmt.Sprintf("task-%07d", i),
+				CreatedAt: base.Add(time.Duration(i) * time.Millisecond),
+				Status:    status,
+			}
+			if err := benchRepo.Create(ctx, task); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	return benchRepo
+}
+
+func BenchmarkInMemoryRepo_List(b *testing.B) {
+	repo := loadedRepo(b)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.List(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkInMemoryRepo_ListAndFilterRunning — как выборку приходилось делать без индексов.
+func BenchmarkInMemoryRepo_ListAndFilterRunning(b *testing.B) {
+	repo := loadedRepo(b)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tasks, err := repo.List(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		running := make([]*domen.Task, 0)
+		for _, t := range tasks {
+			if t.Status == domen.StatusRunning {
+				running = append(running, t)
+			}
+		}
+		_ = running
+	}
+}
+
+func BenchmarkInMemoryRepo_FindRunning(b *testing.B) {
+	repo := loadedRepo(b)
+	ctx := context.Background()
+	filter := domen.TaskFilter{Statuses: []domen.Status{domen.StatusRunning}}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.Find(ctx, filter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInMemoryRepo_FindNewest50(b *testing.B) {
+	repo := loadedRepo(b)
+	ctx := context.Background()
+	filter := domen.TaskFilter{NewestFirst: true, Limit: 50}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.Find(ctx, filter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInMemoryRepo_FindNewest50Pending(b *testing.B) {
+	repo := loadedRepo(b)
+	ctx := context.Background()
+	filter := domen.TaskFilter{Statuses: []domen.Status{domen.StatusPending}, NewestFirst: true, Limit: 50}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.Find(ctx, filter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInMemoryRepo_UpdateStatus(b *testing.B) {
+	repo := loadedRepo(b)
+	ctx := context.Background()
+	statuses := []domen.Status{domen.StatusRunning, domen.StatusCompleted}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		task, err := repo.Get(ctx, fmt.Sprintf("task-%07d", i%benchTasks))
+		if err != nil {
+			b.Fatal(err)
+		}
+		task.Status = statuses[i%2]
+		if err := repo.Update(ctx, task); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// DefaultShards — число шардов ShardedRepo по умолчанию.
+const DefaultShards = 32
+
+// ShardedRepo — вариант InMemoryRepo, в котором задачи распределены по
+// шардам по хешу ID, и у каждого шарда свой лок. Запись в разные шарды
+// не блокирует друг друга; List и Find берут read-лок всех шардов, чтобы
+// вернуть согласованный снимок.
+type ShardedRepo struct {
+	shards []*shard
+	feed   *feed
+}
+
+type shard struct {
+	mu    sync.RWMutex
+	store *store
+}
+
+// NewShardedRepo создаёт репозиторий из n шардов (DefaultShards, если n <= 0).
+func NewShardedRepo(n int) *ShardedRepo {
+	if n <= 0 {
+		n = DefaultShards
+	}
+	shards := make([]*shard, n)
+	for i := range shards {
+		shards[i] = &shard{store: newStore()}
+	}
+	return &ShardedRepo{shards: shards, feed: newFeed()}
+}
+
+// shardFor выбирает шард по FNV-1a хешу ID.
+func (r *ShardedRepo) shardFor(id string) *shard {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	for i := 0; i < len(id); i++ {
+		h ^= uint32(id[i])
+		h *= prime32
+	}
+	return r.shards[h%uint32(len(r.shards))]
+}
+
+func (r *ShardedRepo) Create(ctx context.Context, t *domen.Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s := r.shardFor(t.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, err := s.store.create(t)
+	if err != nil {
+		return err
+	}
+	r.feed.publish(domen.ChangeCreated, stored)
+	return nil
+}
+
+func (r *ShardedRepo) Update(ctx context.Context, t *domen.Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s := r.shardFor(t.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, err := s.store.update(t)
+	if err != nil {
+		return err
+	}
+	r.feed.publish(domen.ChangeUpdated, stored)
+	return nil
+}
+
+func (r *ShardedRepo) Delete(ctx context.Context, id string, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s := r.shardFor(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.store.delete(id, version)
+	if err != nil {
+		return err
+	}
+	r.feed.publish(domen.ChangeDeleted, t)
+	return nil
+}
+
+func (r *ShardedRepo) Get(ctx context.Context, id string) (*domen.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s := r.shardFor(id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.store.get(id)
+}
+
+func (r *ShardedRepo) List(ctx context.Context) ([]*domen.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlockAll()
+	defer r.runlockAll()
+
+	var tasks []*domen.Task
+	for _, s := range r.shards {
+		tasks = append(tasks, s.store.all()...)
+	}
+	sortTasks(tasks, false)
+	return tasks, nil
+}
+
+func (r *ShardedRepo) Find(ctx context.Context, filter domen.TaskFilter) ([]*domen.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlockAll()
+	defer r.runlockAll()
+
+	// Каждый шард отдаёт не больше Limit задач, итог сливаем и обрезаем.
+	var tasks []*domen.Task
+	for _, s := range r.shards {
+		tasks = append(tasks, s.store.find(filter)...)
+	}
+	sortTasks(tasks, filter.NewestFirst)
+	if filter.Limit > 0 && len(tasks) > filter.Limit {
+		tasks = tasks[:filter.Limit]
+	}
+	return tasks, nil
+}
+
+func (r *ShardedRepo) Watch(ctx context.Context, filter domen.WatchFilter) (<-chan domen.TaskChange, error) {
+	return r.feed.watch(ctx, filter)
+}
+
+// CreateMany группирует задачи по шардам и берёт лок каждого шарда один раз.
+func (r *ShardedRepo) CreateMany(ctx context.Context, tasks []*domen.Task) ([]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(tasks))
+	for i, t := range tasks {
+		keys[i] = t.ID
+	}
+	errs := make([]error, len(tasks))
+	r.eachShard(keys, func(s *store, i int) {
+		stored, err := s.create(tasks[i])
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		r.feed.publish(domen.ChangeCreated, stored)
+	})
+	return errs, nil
+}
+
+func (r *ShardedRepo) UpdateMany(ctx context.Context, tasks []*domen.Task) ([]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(tasks))
+	for i, t := range tasks {
+		keys[i] = t.ID
+	}
+	errs := make([]error, len(tasks))
+	r.eachShard(keys, func(s *store, i int) {
+		stored, err := s.update(tasks[i])
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		r.feed.publish(domen.ChangeUpdated, stored)
+	})
+	return errs, nil
+}
+
+func (r *ShardedRepo) DeleteMany(ctx context.Context, ids []string) ([]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	errs := make([]error, len(ids))
+	r.eachShard(ids, func(s *store, i int) {
+		t, err := s.delete(ids[i], 0)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		r.feed.publish(domen.ChangeDeleted, t)
+	})
+	return errs, nil
+}
+
+// eachShard вызывает fn для индексов keys, сгруппированных по шардам, под
+// write-локом соответствующего шарда. Внутри шарда порядок входа сохраняется.
+func (r *ShardedRepo) eachShard(keys []string, fn func(s *store, i int)) {
+	groups := make(map[*shard][]int)
+	for i, k := range keys {
+		s := r.shardFor(k)
+		groups[s] = append(groups[s], i)
+	}
+	for _, s := range r.shards {
+		idx, ok := groups[s]
+		if !ok {
+			continue
+		}
+		s.mu.Lock()
+		for _, i := range idx {
+			fn(s.store, i)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// rlockAll берёт read-лок всех шардов всегда в одном порядке.
+func (r *ShardedRepo) rlockAll() {
+	for _, s := range r.shards {
+		s.mu.RLock()
+	}
+}
+
+func (r *ShardedRepo) runlockAll() {
+	for _, s := range r.shards {
+		s.mu.RUnlock()
+	}
+}
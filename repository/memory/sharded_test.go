@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ids(tasks []*domen.Task) []string {
+	out := make([]string, len(tasks))
+	for i, t := range tasks {
+		out[i] = t.ID
+	}
+	return out
+}
+
+func TestShardedRepo_ListAndFindAcrossShards(t *testing.T) {
+	repo := NewShardedRepo(4)
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	const n = 40
+	want := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("task-%02d", i)
+		status := domen.StatusPending
+		if i%2 == 0 {
+			status = domen.StatusRunning
+		}
+		require.NoError(t, repo.Create(ctx, &domen.Task{ID: id, CreatedAt: base.Add(time.Duration(i) * time.Second), Status: status}))
+		want = append(want, id)
+	}
+
+	// Задачи из разных шардов сливаются в общий порядок создания
+	all, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, want, ids(all))
+
+	got, err := repo.Find(ctx, domen.TaskFilter{
+		Statuses:    []domen.Status{domen.StatusRunning},
+		NewestFirst: true,
+		Limit:       3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"task-38", "task-36", "task-34"}, ids(got))
+}
+
+func TestShardedRepo_WatchOrdered(t *testing.T) {
+	repo := NewShardedRepo(4)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := repo.Watch(ctx, domen.WatchFilter{})
+	require.NoError(t, err)
+
+	const n = 20
+	for i := 0; i < n; i++ {
+		require.NoError(t, repo.Create(ctx, &domen.Task{ID: fmt.Sprintf("task-%d", i)}))
+	}
+	for i := 1; i <= n; i++ {
+		c := recv(t, ch)
+		assert.Equal(t, int64(i), c.Revision)
+		assert.Equal(t, fmt.Sprintf("task-%d", i-1), c.Task.ID)
+	}
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// store — карта задач вместе с индексами. Собственной синхронизации нет:
+// InMemoryRepo и каждый шард ShardedRepo защищают store своим мьютексом.
+// store владеет своими данными: на запись кладётся глубокая копия задачи,
+// на чтение отдаётся глубокая копия, так что вызывающий код не может
+// изменить сохранённое состояние в обход лока.
+type store struct {
+	tasks map[string]*domen.Task
+	index *taskIndex
+}
+
+func newStore() *store {
+	return &store{
+		tasks: make(map[string]*domen.Task),
+		index: newTaskIndex(),
+	}
+}
+
+// create сохраняет копию t и возвращает сохранённую задачу.
+func (s *store) create(t *domen.Task) (*domen.Task, error) {
+	if _, ok := s.tasks[t.ID]; ok {
+		return nil, domen.ErrAlreadyExists
+	}
+	t.Version = 1
+	stored := t.Clone()
+	s.tasks[t.ID] = stored
+	s.index.add(stored)
+	return stored, nil
+}
+
+// update выполняет compare-and-swap по Version и возвращает сохранённую задачу.
+func (s *store) update(t *domen.Task) (*domen.Task, error) {
+	cur, ok := s.tasks[t.ID]
+	if !ok {
+		return nil, domen.ErrNotFound
+	}
+	if cur.Version != t.Version {
+		return nil, domen.ErrConflict
+	}
+	t.Version++
+	stored := t.Clone()
+	s.tasks[t.ID] = stored
+	s.index.update(cur, stored)
+	return stored, nil
+}
+
+// delete удаляет задачу и возвращает её последнее состояние. Если
+// version != 0, версии должны совпасть.
+func (s *store) delete(id string, version int64) (*domen.Task, error) {
+	t, ok := s.tasks[id]
+	if !ok {
+		return nil, domen.ErrNotFound
+	}
+	if version != 0 && t.Version != version {
+		return nil, domen.ErrConflict
+	}
+	delete(s.tasks, id)
+	s.index.remove(t)
+	return t, nil
+}
+
+func (s *store) get(id string) (*domen.Task, error) {
+	t, ok := s.tasks[id]
+	if !ok {
+		return nil, domen.ErrNotFound
+	}
+	return t.Clone(), nil
+}
+
+func (s *store) all() []*domen.Task {
+	return s.collect(s.index.all())
+}
+
+func (s *store) find(filter domen.TaskFilter) []*domen.Task {
+	if filter.TenantID == "" && len(filter.Types) == 0 && (filter.RerunOf == "" || len(filter.Statuses) == 0) {
+		return s.collect(s.index.find(filter))
+	}
+	// Повторов одной задачи немного, статусы проверяем по самим задачам.
+	// Арендатора и тип индекс не знает, их тоже проверяем по задачам.
+	all := filter
+	all.Limit = 0
+	tasks := make([]*domen.Task, 0)
+	for _, id := range s.index.find(all) {
+		if filter.Limit > 0 && len(tasks) == filter.Limit {
+			break
+		}
+		if t := s.tasks[id]; filter.Match(t) {
+			tasks = append(tasks, t.Clone())
+		}
+	}
+	return tasks
+}
+
+func (s *store) collect(ids []string) []*domen.Task {
+	tasks := make([]*domen.Task, 0, len(ids))
+	for _, id := range ids {
+		tasks = append(tasks, s.tasks[id].Clone())
+	}
+	return tasks
+}
+
+// sortTasks упорядочивает задачи так же, как индекс: по CreatedAt, затем по ID.
+func sortTasks(tasks []*domen.Task, newestFirst bool) {
+	sort.Slice(tasks, func(i, j int) bool {
+		if newestFirst {
+			return lessKey(keyOf(tasks[j]), keyOf(tasks[i]))
+		}
+		return lessKey(keyOf(tasks[i]), keyOf(tasks[j]))
+	})
+}
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"context"
 	"sync"
 
 	"github.com/gaz358/myprog/workmate/domen"
@@ -8,59 +9,142 @@ import (
 
 type InMemoryRepo struct {
 	mu    sync.RWMutex
-	tasks map[string]*domen.Task
+	store *store
+	feed  *feed
 }
 
 func NewInMemoryRepo() *InMemoryRepo {
-	return &InMemoryRepo{tasks: make(map[string]*domen.Task)}
+	return &InMemoryRepo{
+		store: newStore(),
+		feed:  newFeed(),
+	}
 }
 
-func (r *InMemoryRepo) Create(t *domen.Task) error {
+func (r *InMemoryRepo) Create(ctx context.Context, t *domen.Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.tasks[t.ID] = t
+	stored, err := r.store.create(t)
+	if err != nil {
+		return err
+	}
+	r.feed.publish(domen.ChangeCreated, stored)
 	return nil
 }
 
-func (r *InMemoryRepo) Update(t *domen.Task) error {
+func (r *InMemoryRepo) Update(ctx context.Context, t *domen.Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, ok := r.tasks[t.ID]; !ok {
-		return domen.ErrNotFound
+	stored, err := r.store.update(t)
+	if err != nil {
+		return err
 	}
-	r.tasks[t.ID] = t
+	r.feed.publish(domen.ChangeUpdated, stored)
 	return nil
 }
 
-func (r *InMemoryRepo) Delete(id string) error {
+func (r *InMemoryRepo) Delete(ctx context.Context, id string, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, ok := r.tasks[id]; !ok {
-		return domen.ErrNotFound
+	t, err := r.store.delete(id, version)
+	if err != nil {
+		return err
 	}
-	delete(r.tasks, id)
+	r.feed.publish(domen.ChangeDeleted, t)
 	return nil
 }
 
-func (r *InMemoryRepo) Get(id string) (*domen.Task, error) {
+func (r *InMemoryRepo) Get(ctx context.Context, id string) (*domen.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	t, ok := r.tasks[id]
-	if !ok {
-		return nil, domen.ErrNotFound
+	return r.store.get(id)
+}
+
+func (r *InMemoryRepo) List(ctx context.Context) ([]*domen.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	tCopy := *t // поверхностная копия!
-	return &tCopy, nil
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.store.all(), nil
 }
 
-func (r *InMemoryRepo) List() ([]*domen.Task, error) {
+// Find выбирает задачи по индексам статуса и времени создания без полного обхода.
+func (r *InMemoryRepo) Find(ctx context.Context, filter domen.TaskFilter) ([]*domen.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	return r.store.find(filter), nil
+}
 
-	tasks := make([]*domen.Task, 0, len(r.tasks))
-	for _, t := range r.tasks {
-		tCopy := *t // поверхностная копия!
-		tasks = append(tasks, &tCopy)
+func (r *InMemoryRepo) Watch(ctx context.Context, filter domen.WatchFilter) (<-chan domen.TaskChange, error) {
+	return r.feed.watch(ctx, filter)
+}
+
+// CreateMany создаёт задачи под одним захватом лока.
+func (r *InMemoryRepo) CreateMany(ctx context.Context, tasks []*domen.Task) ([]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	errs := make([]error, len(tasks))
+	for i, t := range tasks {
+		stored, err := r.store.create(t)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		r.feed.publish(domen.ChangeCreated, stored)
+	}
+	return errs, nil
+}
+
+func (r *InMemoryRepo) UpdateMany(ctx context.Context, tasks []*domen.Task) ([]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	errs := make([]error, len(tasks))
+	for i, t := range tasks {
+		stored, err := r.store.update(t)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		r.feed.publish(domen.ChangeUpdated, stored)
+	}
+	return errs, nil
+}
+
+func (r *InMemoryRepo) DeleteMany(ctx context.Context, ids []string) ([]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	errs := make([]error, len(ids))
+	for i, id := range ids {
+		t, err := r.store.delete(id, 0)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		r.feed.publish(domen.ChangeDeleted, t)
 	}
-	return tasks, nil
+	return errs, nil
 }
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -10,10 +11,11 @@ import (
 
 func TestCreateTask_UniqueIDs(t *testing.T) {
 	repo := NewInMemoryRepo()
+	ctx := context.Background()
 	uc := usecase.NewTaskUseCase(repo, 1*time.Second)
 
-	task1, err1 := uc.CreateTask()
-	task2, err2 := uc.CreateTask()
+	task1, err1 := uc.CreateTask(ctx, "", nil)
+	task2, err2 := uc.CreateTask(ctx, "", nil)
 
 	assert.NoError(t, err1)
 	assert.NoError(t, err2)
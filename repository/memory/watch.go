@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+const (
+	// changeLogSize — сколько последних изменений как минимум хранится для возобновления Watch.
+	changeLogSize = 1024
+	// watchBufferSize — запас буфера подписчика сверх догоняющих изменений.
+	// Подписчик, переполнивший буфер, отключается.
+	watchBufferSize = 64
+)
+
+// feed — журнал изменений и подписчики Watch. Ревизии выдаются в порядке
+// вызовов publish, поэтому publish должен вызываться под тем же локом,
+// что и сама запись.
+type feed struct {
+	mu       sync.Mutex
+	rev      int64
+	changes  []domen.TaskChange
+	watchers map[*watcher]struct{}
+}
+
+type watcher struct {
+	filter domen.WatchFilter
+	ch     chan domen.TaskChange
+	// done закрывается вместе с ch и отпускает горутину, ждущую отмены ctx.
+	done chan struct{}
+}
+
+func newFeed() *feed {
+	return &feed{watchers: make(map[*watcher]struct{})}
+}
+
+func (f *feed) watch(ctx context.Context, filter domen.WatchFilter) (<-chan domen.TaskChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if filter.FromRevision > f.rev {
+		return nil, fmt.Errorf("%w: revision %d is ahead of the current revision %d",
+			domen.ErrInvalidArgument, filter.FromRevision, f.rev)
+	}
+	var backlog []domen.TaskChange
+	if filter.FromRevision > 0 && filter.FromRevision < f.rev {
+		if len(f.changes) == 0 || filter.FromRevision < f.changes[0].Revision-1 {
+			return nil, domen.ErrRevisionCompacted
+		}
+		for _, c := range f.changes {
+			if c.Revision > filter.FromRevision && filter.Match(c) {
+				backlog = append(backlog, c)
+			}
+		}
+	}
+
+	w := &watcher{
+		filter: filter,
+		ch:     make(chan domen.TaskChange, len(backlog)+watchBufferSize),
+		done:   make(chan struct{}),
+	}
+	for _, c := range backlog {
+		w.ch <- cloneChange(c)
+	}
+	f.watchers[w] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-w.done:
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.removeWatcher(w)
+	}()
+
+	return w.ch, nil
+}
+
+// publish записывает изменение в журнал и рассылает подписчикам.
+func (f *feed) publish(typ domen.ChangeType, t *domen.Task) {
+	c := domen.TaskChange{Type: typ, Task: t.Clone()}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.rev++
+	c.Revision = f.rev
+
+	f.changes = append(f.changes, c)
+	if len(f.changes) > 2*changeLogSize {
+		// Сдвигаем окно пачкой, чтобы копирование амортизировалось.
+		f.changes = append(f.changes[:0:0], f.changes[len(f.changes)-changeLogSize:]...)
+	}
+
+	for w := range f.watchers {
+		if !w.filter.Match(c) {
+			continue
+		}
+		select {
+		case w.ch <- cloneChange(c):
+		default:
+			// Медленный подписчик: закрываем канал, он переподпишется с последней ревизии.
+			f.removeWatcher(w)
+		}
+	}
+}
+
+// removeWatcher вызывается под f.mu.
+func (f *feed) removeWatcher(w *watcher) {
+	if _, ok := f.watchers[w]; !ok {
+		return
+	}
+	delete(f.watchers, w)
+	close(w.ch)
+	close(w.done)
+}
+
+// cloneChange даёт каждому подписчику собственную копию задачи.
+func cloneChange(c domen.TaskChange) domen.TaskChange {
+	c.Task = c.Task.Clone()
+	return c
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recv(t *testing.T, ch <-chan domen.TaskChange) domen.TaskChange {
+	t.Helper()
+	select {
+	case c, ok := <-ch:
+		require.True(t, ok, "канал закрыт раньше времени")
+		return c
+	case <-time.After(time.Second):
+		t.Fatal("изменение не пришло")
+		return domen.TaskChange{}
+	}
+}
+
+func TestInMemoryRepo_WatchResume(t *testing.T) {
+	repo := NewInMemoryRepo()
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, repo.Create(ctx, &domen.Task{ID: fmt.Sprintf("task-%d", i)}))
+	}
+
+	// Продолжаем с ревизии 3: догоняем 4 и 5, затем получаем новые
+	ch, err := repo.Watch(ctx, domen.WatchFilter{FromRevision: 3})
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "task-5"}))
+
+	for _, want := range []int64{4, 5, 6} {
+		assert.Equal(t, want, recv(t, ch).Revision)
+	}
+
+	// Вытесненная ревизия
+	for i := 0; i < changeLogSize; i++ {
+		require.NoError(t, repo.Delete(ctx, "task-0", 0))
+		require.NoError(t, repo.Create(ctx, &domen.Task{ID: "task-0"}))
+	}
+	_, err = repo.Watch(ctx, domen.WatchFilter{FromRevision: 3})
+	assert.ErrorIs(t, err, domen.ErrRevisionCompacted)
+}
+
+func TestInMemoryRepo_WatchSlowConsumer(t *testing.T) {
+	repo := NewInMemoryRepo()
+	ctx := context.Background()
+
+	goroutines := runtime.NumGoroutine()
+	slow, err := repo.Watch(ctx, domen.WatchFilter{})
+	require.NoError(t, err)
+
+	// Переполняем буфер, не читая из канала
+	for i := 0; i <= watchBufferSize; i++ {
+		require.NoError(t, repo.Create(ctx, &domen.Task{ID: fmt.Sprintf("task-%d", i)}))
+	}
+
+	var last int64
+	for c := range slow {
+		assert.Equal(t, last+1, c.Revision)
+		last = c.Revision
+	}
+	assert.Equal(t, int64(watchBufferSize), last)
+	// ctx не отменяется никогда, но отключённый подписчик не держит горутину.
+	// assert.Eventually не подходит: он сам проверяет условие в горутине
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+
+	// Переподписка с последней полученной ревизии ничего не теряет
+	resumed, err := repo.Watch(ctx, domen.WatchFilter{FromRevision: last})
+	require.NoError(t, err)
+	assert.Equal(t, last+1, recv(t, resumed).Revision)
+}
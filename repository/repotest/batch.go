@@ -0,0 +1,105 @@
+package repotest
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// BatchFactory создаёт пустой репозиторий пакетов для одного подтеста.
+type BatchFactory func(t *testing.T) domen.BatchRepository
+
+// RunBatchContract прогоняет проверки контракта domen.BatchRepository.
+func RunBatchContract(t *testing.T, newRepo BatchFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo domen.BatchRepository)
+	}{
+		{"CreateGet", testBatchCreateGet},
+		{"FinishOnce", testBatchFinishOnce},
+		{"ListUnfinished", testBatchListUnfinished},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) { tc.fn(t, newRepo(t)) })
+	}
+}
+
+func testBatchCreateGet(t *testing.T, repo domen.BatchRepository) {
+	ctx := context.Background()
+	batch := &domen.Batch{ID: "batch-1", CreatedAt: time.Now(), TaskIDs: []string{"a", "b"}, TenantID: "team-a"}
+	require.NoError(t, repo.Create(ctx, batch))
+	assert.ErrorIs(t, repo.Create(ctx, batch), domen.ErrAlreadyExists)
+
+	// Изменение исходного пакета не влияет на сохранённый
+	batch.TaskIDs[0] = "changed"
+
+	got, err := repo.Get(ctx, "batch-1")
+	require.NoError(t, err)
+	assert.Equal(t, "batch-1", got.ID)
+	assert.True(t, batch.CreatedAt.Equal(got.CreatedAt))
+	assert.Equal(t, []string{"a", "b"}, got.TaskIDs)
+	assert.True(t, got.FinishedAt.IsZero())
+	assert.Equal(t, "team-a", got.TenantID)
+
+	_, err = repo.Get(ctx, "missing")
+	assert.ErrorIs(t, err, domen.ErrNotFound)
+}
+
+func testBatchFinishOnce(t *testing.T, repo domen.BatchRepository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, &domen.Batch{ID: "batch-1", CreatedAt: time.Now(), TaskIDs: []string{"a"}}))
+	assert.ErrorIs(t, repo.Finish(ctx, "missing", time.Now()), domen.ErrNotFound)
+
+	// Из нескольких параллельных Finish успешен ровно один
+	at := time.Now()
+	var (
+		wg       sync.WaitGroup
+		finished atomic.Int32
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.Finish(ctx, "batch-1", at)
+			if err == nil {
+				finished.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, domen.ErrConflict)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), finished.Load())
+
+	got, err := repo.Get(ctx, "batch-1")
+	require.NoError(t, err)
+	assert.True(t, at.Equal(got.FinishedAt))
+}
+
+func testBatchListUnfinished(t *testing.T, repo domen.BatchRepository) {
+	ctx := context.Background()
+	batches, err := repo.ListUnfinished(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, batches)
+
+	base := time.Now()
+	for i, id := range []string{"batch-3", "batch-1", "batch-2"} {
+		require.NoError(t, repo.Create(ctx, &domen.Batch{
+			ID: id, CreatedAt: base.Add(time.Duration(3-i) * time.Second), TaskIDs: []string{"t-" + id},
+		}))
+	}
+	require.NoError(t, repo.Finish(ctx, "batch-2", time.Now()))
+
+	batches, err = repo.ListUnfinished(ctx)
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+	assert.Equal(t, "batch-1", batches[0].ID)
+	assert.Equal(t, "batch-3", batches[1].ID)
+	assert.Equal(t, []string{"t-batch-1"}, batches[0].TaskIDs)
+}
@@ -0,0 +1,73 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBulk(t *testing.T, repo domen.TaskRepository) {
+	bulk, ok := repo.(domen.BulkRepository)
+	if !ok {
+		t.Skip("repository does not implement domen.BulkRepository")
+	}
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "b", CreatedAt: base}))
+
+	tasks := []*domen.Task{
+		{ID: "a", CreatedAt: base, Status: domen.StatusPending},
+		{ID: "b", CreatedAt: base, Status: domen.StatusPending},
+		{ID: "c", CreatedAt: base.Add(time.Second), Status: domen.StatusPending},
+	}
+	errs, err := bulk.CreateMany(ctx, tasks)
+	require.NoError(t, err)
+	require.Len(t, errs, 3)
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], domen.ErrAlreadyExists)
+	assert.NoError(t, errs[2])
+	assert.Equal(t, int64(1), tasks[0].Version)
+
+	all, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, ids(all))
+
+	// Одна задача с устаревшей версией, одна несуществующая
+	a, err := repo.Get(ctx, "a")
+	require.NoError(t, err)
+	a.Status = domen.StatusRunning
+	stale := &domen.Task{ID: "c", CreatedAt: base.Add(time.Second), Version: 7}
+	missing := &domen.Task{ID: "missing", Version: 1}
+	errs, err = bulk.UpdateMany(ctx, []*domen.Task{a, stale, missing})
+	require.NoError(t, err)
+	require.Len(t, errs, 3)
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], domen.ErrConflict)
+	assert.ErrorIs(t, errs[2], domen.ErrNotFound)
+	assert.Equal(t, int64(2), a.Version)
+
+	got, err := repo.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, domen.StatusRunning, got.Status)
+
+	errs, err = bulk.DeleteMany(ctx, []string{"a", "missing", "c"})
+	require.NoError(t, err)
+	require.Len(t, errs, 3)
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], domen.ErrNotFound)
+	assert.NoError(t, errs[2])
+
+	all, err = repo.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, ids(all))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = bulk.DeleteMany(canceled, []string{"b"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
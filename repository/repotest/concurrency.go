@@ -0,0 +1,138 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+func testConcurrency(t *testing.T, repo domen.TaskRepository) {
+	ctx := context.Background()
+	const n = 100
+	var wg sync.WaitGroup
+
+	// Параллельное создание задач
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			tid := fmt.Sprintf("task-%d", id)
+			task := &domen.Task{
+				ID:     tid,
+				Status: domen.StatusPending,
+			}
+			if err := repo.Create(ctx, task); err != nil {
+				t.Errorf("create err: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Проверяем, что все задачи создались
+	tasks, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(tasks) != n {
+		t.Fatalf("want %d, got %d", n, len(tasks))
+	}
+
+	// Параллельное обновление задач на COMPLETED
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			tid := fmt.Sprintf("task-%d", id)
+			task, err := repo.Get(ctx, tid)
+			if err != nil {
+				t.Errorf("get err: %v", err)
+				return
+			}
+			task.Status = domen.StatusCompleted
+			if err := repo.Update(ctx, task); err != nil {
+				t.Errorf("update err: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			tid := fmt.Sprintf("task-%d", id)
+			task, err := repo.Get(ctx, tid)
+			if err != nil {
+				t.Errorf("get err: %v", err)
+				return
+			}
+			task.Status = domen.StatusFailed
+			if err := repo.Update(ctx, task); err != nil {
+				t.Errorf("update err: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Смешанный блок: параллельно Update и Get (50/50)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			tid := fmt.Sprintf("task-%d", id)
+			if rand.Intn(2) == 0 {
+				// Update
+				task, err := repo.Get(ctx, tid)
+				if err != nil {
+					t.Errorf("get err: %v", err)
+					return
+				}
+				task.Status = domen.StatusCancelled
+				if err := repo.Update(ctx, task); err != nil {
+					t.Errorf("update err: %v", err)
+				}
+			} else {
+				// Get
+				if _, err := repo.Get(ctx, tid); err != nil {
+					t.Errorf("get err: %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Еще раз проверим, что задачи есть
+	tasks, err = repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(tasks) != n {
+		t.Fatalf("want %d, got %d", n, len(tasks))
+	}
+
+	// Параллельное удаление задач
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			tid := fmt.Sprintf("task-%d", id)
+			if err := repo.Delete(ctx, tid, 0); err != nil {
+				t.Errorf("delete err: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// После удаления ничего не должно остаться
+	tasks, err = repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(tasks) != 0 {
+		t.Fatalf("want 0, got %d", len(tasks))
+	}
+}
@@ -0,0 +1,274 @@
+// Package repotest содержит общий набор проверок для реализаций
+// domen.TaskRepository. Каждая реализация вызывает RunContract из своих тестов.
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory создаёт пустой репозиторий для одного подтеста.
+type Factory func(t *testing.T) domen.TaskRepository
+
+// RunContract прогоняет все проверки контракта domen.TaskRepository.
+// Каждый подтест получает новый репозиторий из newRepo.
+func RunContract(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo domen.TaskRepository)
+	}{
+		{"CreateGet", testCreateGet},
+		{"CreateDuplicate", testCreateDuplicate},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"DeleteVersion", testDeleteVersion},
+		{"CanceledContext", testCanceledContext},
+		{"ListOrder", testListOrder},
+		{"Find", testFind},
+		{"Pagination", testPagination},
+		{"Isolation", testIsolation},
+		{"IsolationConcurrent", testIsolationConcurrent},
+		{"Concurrency", testConcurrency},
+		{"Watch", testWatch},
+		{"WatchResume", testWatchResume},
+		{"WatchSlowConsumer", testWatchSlowConsumer},
+		{"Bulk", testBulk},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) { tc.fn(t, newRepo(t)) })
+	}
+}
+
+// sameTask сравнивает задачи с точностью до представления времени.
+func sameTask(t *testing.T, want, got *domen.Task) {
+	t.Helper()
+	assert.Equal(t, want.ID, got.ID)
+	assert.True(t, want.CreatedAt.Equal(got.CreatedAt), "CreatedAt: want %v, got %v", want.CreatedAt, got.CreatedAt)
+	assert.True(t, want.StartedAt.Equal(got.StartedAt), "StartedAt: want %v, got %v", want.StartedAt, got.StartedAt)
+	assert.True(t, want.EndedAt.Equal(got.EndedAt), "EndedAt: want %v, got %v", want.EndedAt, got.EndedAt)
+	assert.Equal(t, want.Duration, got.Duration)
+	assert.Equal(t, want.Status, got.Status)
+	assert.Equal(t, want.Result, got.Result)
+	assert.Equal(t, want.Version, got.Version)
+	assert.Equal(t, want.BatchID, got.BatchID)
+	assert.Equal(t, want.RerunOf, got.RerunOf)
+	assert.Equal(t, want.Type, got.Type)
+	assert.Equal(t, want.TraceContext, got.TraceContext)
+	assert.Equal(t, want.CreatedBy, got.CreatedBy)
+	assert.Equal(t, want.TenantID, got.TenantID)
+	if want.Payload == nil {
+		assert.Nil(t, got.Payload)
+	} else {
+		assert.JSONEq(t, string(want.Payload), string(got.Payload))
+	}
+	require.Len(t, got.History, len(want.History))
+	for i := range want.History {
+		assert.Equal(t, want.History[i].From, got.History[i].From)
+		assert.Equal(t, want.History[i].To, got.History[i].To)
+		assert.Equal(t, want.History[i].Reason, got.History[i].Reason)
+		assert.True(t, want.History[i].At.Equal(got.History[i].At))
+	}
+}
+
+func ids(tasks []*domen.Task) []string {
+	out := make([]string, len(tasks))
+	for i, t := range tasks {
+		out[i] = t.ID
+	}
+	return out
+}
+
+func testCreateGet(t *testing.T, repo domen.TaskRepository) {
+	ctx := context.Background()
+	now := time.Now()
+	task := &domen.Task{
+		ID:           "task-abc123",
+		CreatedAt:    now,
+		StartedAt:    now.Add(time.Second),
+		EndedAt:      now.Add(5 * time.Second),
+		Duration:     "4s",
+		Status:       domen.StatusCompleted,
+		Result:       "OK",
+		BatchID:      "batch-1",
+		RerunOf:      "task-parent",
+		Type:         "report",
+		Payload:      json.RawMessage(`{"format":"pdf","pages":[1,2]}`),
+		TraceContext: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		CreatedBy:    "ci-pipeline",
+		TenantID:     "team-a",
+		History: []domen.Transition{
+			{To: domen.StatusPending, At: now, Reason: "created"},
+			{From: domen.StatusPending, To: domen.StatusRunning, At: now.Add(time.Second), Reason: "started"},
+		},
+	}
+	require.NoError(t, repo.Create(ctx, task))
+	assert.Equal(t, int64(1), task.Version)
+
+	got, err := repo.Get(ctx, task.ID)
+	require.NoError(t, err)
+	sameTask(t, task, got)
+
+	got, err = repo.Get(ctx, "task-nonexistent")
+	assert.Nil(t, got)
+	assert.ErrorIs(t, err, domen.ErrNotFound)
+}
+
+func testCreateDuplicate(t *testing.T, repo domen.TaskRepository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "task-1", Status: domen.StatusPending}))
+	err := repo.Create(ctx, &domen.Task{ID: "task-1", Status: domen.StatusFailed})
+	assert.ErrorIs(t, err, domen.ErrAlreadyExists)
+
+	got, err := repo.Get(ctx, "task-1")
+	require.NoError(t, err)
+	assert.Equal(t, domen.StatusPending, got.Status)
+
+	// Из одновременных созданий одного ID успешно ровно одно
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.Create(ctx, &domen.Task{ID: "task-2", Status: domen.StatusPending})
+			if err != nil {
+				assert.ErrorIs(t, err, domen.ErrAlreadyExists)
+				return
+			}
+			mu.Lock()
+			created++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, created)
+}
+
+func testUpdate(t *testing.T, repo domen.TaskRepository) {
+	ctx := context.Background()
+	task := &domen.Task{ID: "task-1", CreatedAt: time.Now(), Status: domen.StatusPending}
+	require.NoError(t, repo.Create(ctx, task))
+
+	first, err := repo.Get(ctx, task.ID)
+	require.NoError(t, err)
+	second, err := repo.Get(ctx, task.ID)
+	require.NoError(t, err)
+
+	first.Status = domen.StatusCompleted
+	first.Result = "done"
+	require.NoError(t, repo.Update(ctx, first))
+	assert.Equal(t, int64(2), first.Version)
+
+	// Устаревшая версия отклоняется
+	second.Status = domen.StatusCancelled
+	assert.ErrorIs(t, repo.Update(ctx, second), domen.ErrConflict)
+	assert.Equal(t, int64(1), second.Version)
+
+	got, err := repo.Get(ctx, task.ID)
+	require.NoError(t, err)
+	sameTask(t, first, got)
+
+	assert.ErrorIs(t, repo.Update(ctx, &domen.Task{ID: "nonexistent", Version: 1}), domen.ErrNotFound)
+}
+
+func testDelete(t *testing.T, repo domen.TaskRepository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "task-1", CreatedAt: time.Now()}))
+
+	require.NoError(t, repo.Delete(ctx, "task-1", 0))
+	_, err := repo.Get(ctx, "task-1")
+	assert.ErrorIs(t, err, domen.ErrNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, "task-1", 0), domen.ErrNotFound)
+
+	// ID снова свободен
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "task-1"}))
+}
+
+func testDeleteVersion(t *testing.T, repo domen.TaskRepository) {
+	ctx := context.Background()
+	task := &domen.Task{ID: "task-1", CreatedAt: time.Now(), Status: domen.StatusPending}
+	require.NoError(t, repo.Create(ctx, task))
+	stale := task.Version
+	task.Status = domen.StatusRunning
+	require.NoError(t, repo.Update(ctx, task))
+
+	// Устаревшая версия не удаляет задачу
+	assert.ErrorIs(t, repo.Delete(ctx, task.ID, stale), domen.ErrConflict)
+	got, err := repo.Get(ctx, task.ID)
+	require.NoError(t, err)
+	sameTask(t, task, got)
+
+	require.NoError(t, repo.Delete(ctx, task.ID, task.Version))
+	assert.ErrorIs(t, repo.Delete(ctx, task.ID, task.Version), domen.ErrNotFound)
+
+	// Из одновременных изменения и удаления одной версии проходит только одно
+	task = &domen.Task{ID: "task-2", CreatedAt: time.Now(), Status: domen.StatusPending}
+	require.NoError(t, repo.Create(ctx, task))
+	version := task.Version
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		errs[0] = repo.Delete(ctx, task.ID, version)
+	}()
+	go func() {
+		defer wg.Done()
+		update := task.Clone()
+		update.Status = domen.StatusRunning
+		errs[1] = repo.Update(ctx, update)
+	}()
+	wg.Wait()
+	if errs[0] == nil {
+		assert.ErrorIs(t, errs[1], domen.ErrNotFound)
+	} else {
+		assert.ErrorIs(t, errs[0], domen.ErrConflict)
+		assert.NoError(t, errs[1])
+	}
+}
+
+func testCanceledContext(t *testing.T, repo domen.TaskRepository) {
+	task := &domen.Task{ID: "task-1", CreatedAt: time.Now(), Status: domen.StatusPending}
+	require.NoError(t, repo.Create(context.Background(), task))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Ни одна операция не выполняется с отменённым контекстом
+	assert.ErrorIs(t, repo.Create(ctx, &domen.Task{ID: "task-2"}), context.Canceled)
+	assert.ErrorIs(t, repo.Update(ctx, task), context.Canceled)
+	assert.ErrorIs(t, repo.Delete(ctx, task.ID, 0), context.Canceled)
+
+	got, err := repo.Get(ctx, task.ID)
+	assert.Nil(t, got)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	tasks, err := repo.List(ctx)
+	assert.Nil(t, tasks)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	tasks, err = repo.Find(ctx, domen.TaskFilter{})
+	assert.Nil(t, tasks)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	ch, err := repo.Watch(ctx, domen.WatchFilter{})
+	assert.Nil(t, ch)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Состояние не изменилось
+	stored, err := repo.Get(context.Background(), task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stored.Version)
+	_, err = repo.Get(context.Background(), "task-2")
+	assert.ErrorIs(t, err, domen.ErrNotFound)
+}
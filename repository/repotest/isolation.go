@@ -0,0 +1,134 @@
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Сценарии изоляции рассчитаны на go test -race: любое разделение памяти
+// между вызывающим кодом и репозиторием даёт гонку или меняет сохранённую задачу.
+
+func testIsolation(t *testing.T, repo domen.TaskRepository) {
+	ctx := context.Background()
+	now := time.Now()
+
+	task := &domen.Task{
+		ID:      "task-1",
+		Status:  domen.StatusPending,
+		Payload: json.RawMessage(`{"n":1}`),
+		History: []domen.Transition{{To: domen.StatusPending, At: now, Reason: "created"}},
+	}
+	require.NoError(t, repo.Create(ctx, task))
+
+	// Изменения исходного объекта после Create не попадают в репозиторий
+	task.Status = domen.StatusFailed
+	task.Payload[5] = '2'
+	task.History[0].Reason = "mutated"
+	task.History = append(task.History, domen.Transition{To: domen.StatusFailed})
+
+	got, err := repo.Get(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domen.StatusPending, got.Status)
+	require.Len(t, got.History, 1)
+	assert.Equal(t, "created", got.History[0].Reason)
+	assert.True(t, now.Equal(got.History[0].At))
+	assert.JSONEq(t, `{"n":1}`, string(got.Payload))
+
+	// Изменения результата Get не попадают в репозиторий
+	got.Status = domen.StatusCompleted
+	got.Payload[5] = '3'
+	got.History[0].Reason = "mutated"
+
+	// ... как и изменения результата List и Find
+	listed, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	listed[0].History[0].Reason = "mutated"
+	found, err := repo.Find(ctx, domen.TaskFilter{})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	found[0].History[0].Reason = "mutated"
+
+	again, err := repo.Get(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domen.StatusPending, again.Status)
+	assert.Equal(t, "created", again.History[0].Reason)
+	assert.JSONEq(t, `{"n":1}`, string(again.Payload))
+
+	// Изменения объекта после Update не попадают в репозиторий
+	require.NoError(t, again.Transition(domen.StatusRunning, "started", now))
+	require.NoError(t, repo.Update(ctx, again))
+	again.History[1].Reason = "mutated"
+
+	stored, err := repo.Get(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domen.StatusRunning, stored.Status)
+	assert.Equal(t, "started", stored.History[1].Reason)
+}
+
+// testIsolationConcurrent: читатели свободно меняют полученные копии,
+// пока писатели обновляют те же задачи через CAS.
+func testIsolationConcurrent(t *testing.T, repo domen.TaskRepository) {
+	ctx := context.Background()
+	const n = 10
+	for i := 0; i < n; i++ {
+		task := &domen.Task{ID: fmt.Sprintf("task-%d", i), Status: domen.StatusPending}
+		require.NoError(t, repo.Create(ctx, task))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("task-%d", i)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				task, err := repo.Get(ctx, id)
+				if err != nil {
+					t.Errorf("get err: %v", err)
+					return
+				}
+				task.History = append(task.History, domen.Transition{Reason: fmt.Sprint(j)})
+				if err := repo.Update(ctx, task); err != nil {
+					t.Errorf("update err: %v", err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				tasks, err := repo.List(ctx)
+				if err != nil {
+					t.Errorf("list err: %v", err)
+					return
+				}
+				for _, task := range tasks {
+					task.Status = domen.StatusFailed
+					for k := range task.History {
+						task.History[k].Reason = "reader"
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	tasks, err := repo.List(ctx)
+	require.NoError(t, err)
+	for _, task := range tasks {
+		assert.Equal(t, domen.StatusPending, task.Status)
+		require.Len(t, task.History, 50)
+		for k, tr := range task.History {
+			assert.Equal(t, fmt.Sprint(k), tr.Reason)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testListOrder(t *testing.T, repo domen.TaskRepository) {
+	ctx := context.Background()
+
+	tasks, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Создаём не по порядку; одинаковое время упорядочивается по ID
+	for _, c := range []struct {
+		id  string
+		min int
+	}{{"c", 2}, {"a", 0}, {"b2", 1}, {"b1", 1}} {
+		require.NoError(t, repo.Create(ctx, &domen.Task{ID: c.id, CreatedAt: base.Add(time.Duration(c.min) * time.Minute)}))
+	}
+
+	tasks, err = repo.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b1", "b2", "c"}, ids(tasks))
+}
+
+func testFind(t *testing.T, repo domen.TaskRepository) {
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	statuses := []domen.Status{domen.StatusPending, domen.StatusRunning, domen.StatusCompleted}
+	// task-3, task-6 и task-8 — повторы task-0; чётные задачи у team-a, нечётные у team-b;
+	// task-0, task-4 и task-8 — типа report, остальные — default
+	reruns := map[int]string{3: "task-0", 6: "task-0", 8: "task-0"}
+	tenants := []string{"team-a", "team-b"}
+	types := []string{"report", "default", "default", "default"}
+	for i := 0; i < 9; i++ {
+		require.NoError(t, repo.Create(ctx, &domen.Task{
+			ID:        fmt.Sprintf("task-%d", i),
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+			Status:    statuses[i%3],
+			RerunOf:   reruns[i],
+			TenantID:  tenants[i%2],
+			Type:      types[i%4],
+		}))
+	}
+
+	cases := []struct {
+		name   string
+		filter domen.TaskFilter
+		want   []string
+	}{
+		{"all", domen.TaskFilter{}, []string{"task-0", "task-1", "task-2", "task-3", "task-4", "task-5", "task-6", "task-7", "task-8"}},
+		{"status", domen.TaskFilter{Statuses: []domen.Status{domen.StatusRunning}}, []string{"task-1", "task-4", "task-7"}},
+		{"newest", domen.TaskFilter{NewestFirst: true, Limit: 2}, []string{"task-8", "task-7"}},
+		{"range", domen.TaskFilter{
+			CreatedFrom: base.Add(2 * time.Minute),
+			CreatedTo:   base.Add(6 * time.Minute),
+			NewestFirst: true,
+		}, []string{"task-5", "task-4", "task-3", "task-2"}},
+		{"statuses limit", domen.TaskFilter{
+			Statuses: []domen.Status{domen.StatusPending, domen.StatusCompleted},
+			Limit:    3,
+		}, []string{"task-0", "task-2", "task-3"}},
+		{"no match", domen.TaskFilter{Statuses: []domen.Status{domen.StatusFailed}}, []string{}},
+		{"rerun of", domen.TaskFilter{RerunOf: "task-0", NewestFirst: true}, []string{"task-8", "task-6", "task-3"}},
+		{"rerun of status", domen.TaskFilter{
+			RerunOf:  "task-0",
+			Statuses: []domen.Status{domen.StatusPending},
+			Limit:    1,
+		}, []string{"task-3"}},
+		{"rerun of range", domen.TaskFilter{RerunOf: "task-0", CreatedFrom: base.Add(4 * time.Minute)}, []string{"task-6", "task-8"}},
+		{"no reruns", domen.TaskFilter{RerunOf: "task-1"}, []string{}},
+		{"tenant", domen.TaskFilter{TenantID: "team-b"}, []string{"task-1", "task-3", "task-5", "task-7"}},
+		{"tenant status limit", domen.TaskFilter{
+			TenantID: "team-a",
+			Statuses: []domen.Status{domen.StatusPending},
+			Limit:    1,
+		}, []string{"task-0"}},
+		{"tenant newest limit", domen.TaskFilter{TenantID: "team-a", NewestFirst: true, Limit: 2}, []string{"task-8", "task-6"}},
+		{"tenant rerun of", domen.TaskFilter{TenantID: "team-a", RerunOf: "task-0"}, []string{"task-6", "task-8"}},
+		{"unknown tenant", domen.TaskFilter{TenantID: "team-c"}, []string{}},
+		{"type", domen.TaskFilter{Types: []string{"report"}}, []string{"task-0", "task-4", "task-8"}},
+		{"type status", domen.TaskFilter{Types: []string{"report"}, Statuses: []domen.Status{domen.StatusRunning}}, []string{"task-4"}},
+		{"tenant type", domen.TaskFilter{TenantID: "team-a", Types: []string{"default"}, Limit: 1}, []string{"task-2"}},
+		{"types", domen.TaskFilter{Types: []string{"report", "default"}, Limit: 2}, []string{"task-0", "task-1"}},
+		{"unknown type", domen.TaskFilter{Types: []string{"nope"}}, []string{}},
+	}
+	for _, c := range cases {
+		got, err := repo.Find(ctx, c.filter)
+		require.NoError(t, err, c.name)
+		assert.Equal(t, c.want, ids(got), c.name)
+	}
+
+	// Смена статуса отражается в выборке
+	task, err := repo.Get(ctx, "task-1")
+	require.NoError(t, err)
+	task.Status = domen.StatusCompleted
+	require.NoError(t, repo.Update(ctx, task))
+	require.NoError(t, repo.Delete(ctx, "task-4", 0))
+
+	got, err := repo.Find(ctx, domen.TaskFilter{Statuses: []domen.Status{domen.StatusRunning}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"task-7"}, ids(got))
+}
+
+// testPagination листает задачи страницами по Limit, сдвигая границу
+// CreatedFrom/CreatedTo за последнюю полученную задачу.
+func testPagination(t *testing.T, repo domen.TaskRepository) {
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	const n = 25
+	for i := 0; i < n; i++ {
+		require.NoError(t, repo.Create(ctx, &domen.Task{
+			ID:        fmt.Sprintf("task-%02d", i),
+			CreatedAt: base.Add(time.Duration(i) * time.Second),
+		}))
+	}
+
+	// Вперёд: от старых к новым
+	var forward []string
+	filter := domen.TaskFilter{Limit: 10}
+	for {
+		page, err := repo.Find(ctx, filter)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(page), filter.Limit)
+		forward = append(forward, ids(page)...)
+		if len(page) < filter.Limit {
+			break
+		}
+		filter.CreatedFrom = page[len(page)-1].CreatedAt.Add(time.Nanosecond)
+	}
+
+	// Назад: от новых к старым
+	var backward []string
+	filter = domen.TaskFilter{Limit: 10, NewestFirst: true}
+	for {
+		page, err := repo.Find(ctx, filter)
+		require.NoError(t, err)
+		backward = append(backward, ids(page)...)
+		if len(page) < filter.Limit {
+			break
+		}
+		filter.CreatedTo = page[len(page)-1].CreatedAt
+	}
+
+	require.Len(t, forward, n)
+	require.Len(t, backward, n)
+	for i := 0; i < n; i++ {
+		assert.Equal(t, fmt.Sprintf("task-%02d", i), forward[i])
+		assert.Equal(t, fmt.Sprintf("task-%02d", n-1-i), backward[i])
+	}
+}
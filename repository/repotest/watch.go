@@ -0,0 +1,166 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recv(t *testing.T, ch <-chan domen.TaskChange) domen.TaskChange {
+	t.Helper()
+	select {
+	case c, ok := <-ch:
+		require.True(t, ok, "канал закрыт раньше времени")
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("изменение не пришло")
+		return domen.TaskChange{}
+	}
+}
+
+func testWatch(t *testing.T, repo domen.TaskRepository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	all, err := repo.Watch(ctx, domen.WatchFilter{})
+	require.NoError(t, err)
+	only, err := repo.Watch(ctx, domen.WatchFilter{TaskID: "task-2", Statuses: []domen.Status{domen.StatusRunning}})
+	require.NoError(t, err)
+	tenant, err := repo.Watch(ctx, domen.WatchFilter{TenantID: "team-b"})
+	require.NoError(t, err)
+
+	for i, id := range []string{"task-1", "task-2"} {
+		task := &domen.Task{
+			ID:           id,
+			Status:       domen.StatusPending,
+			TraceContext: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			TenantID:     []string{"team-a", "team-b"}[i],
+		}
+		require.NoError(t, repo.Create(ctx, task))
+		task.Status = domen.StatusRunning
+		require.NoError(t, repo.Update(ctx, task))
+	}
+	require.NoError(t, repo.Delete(ctx, "task-1", 0))
+
+	var revs []int64
+	var types []domen.ChangeType
+	for i := 0; i < 5; i++ {
+		c := recv(t, all)
+		revs = append(revs, c.Revision)
+		types = append(types, c.Type)
+	}
+	for i := 1; i < len(revs); i++ {
+		assert.Greater(t, revs[i], revs[i-1], "ревизии должны возрастать")
+	}
+	assert.Equal(t, []domen.ChangeType{
+		domen.ChangeCreated, domen.ChangeUpdated, domen.ChangeCreated, domen.ChangeUpdated, domen.ChangeDeleted,
+	}, types)
+
+	c := recv(t, only)
+	assert.Equal(t, "task-2", c.Task.ID)
+	assert.Equal(t, domen.StatusRunning, c.Task.Status)
+	assert.Equal(t, revs[3], c.Revision)
+	// Изменение несёт и поля, которых нет в JSON задачи
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", c.Task.TraceContext)
+	assert.Equal(t, "team-b", c.Task.TenantID)
+
+	c = recv(t, tenant)
+	assert.Equal(t, "task-2", c.Task.ID)
+	assert.Equal(t, domen.ChangeCreated, c.Type)
+
+	// Полученное изменение — копия, а не сохранённая задача
+	c.Task.Status = domen.StatusFailed
+	stored, err := repo.Get(ctx, "task-2")
+	require.NoError(t, err)
+	assert.Equal(t, domen.StatusRunning, stored.Status)
+
+	// Отмена контекста закрывает канал
+	cancel()
+	assert.Eventually(t, func() bool {
+		select {
+		case _, ok := <-all:
+			return !ok
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func testWatchResume(t *testing.T, repo domen.TaskRepository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first, err := repo.Watch(ctx, domen.WatchFilter{})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, repo.Create(ctx, &domen.Task{ID: fmt.Sprintf("task-%d", i)}))
+	}
+	var revs []int64
+	for i := 0; i < 5; i++ {
+		revs = append(revs, recv(t, first).Revision)
+	}
+
+	// Продолжаем с третьего изменения: догоняем пропущенные, затем новые
+	resumed, err := repo.Watch(ctx, domen.WatchFilter{FromRevision: revs[2]})
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "task-5"}))
+
+	assert.Equal(t, revs[3], recv(t, resumed).Revision)
+	assert.Equal(t, revs[4], recv(t, resumed).Revision)
+	last := recv(t, resumed)
+	assert.Equal(t, "task-5", last.Task.ID)
+	assert.Greater(t, last.Revision, revs[4])
+
+	// Ревизии из будущего репозиторий не выдавал
+	_, err = repo.Watch(ctx, domen.WatchFilter{FromRevision: last.Revision + 1})
+	assert.ErrorIs(t, err, domen.ErrInvalidArgument)
+}
+
+func testWatchSlowConsumer(t *testing.T, repo domen.TaskRepository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	slow, err := repo.Watch(ctx, domen.WatchFilter{})
+	require.NoError(t, err)
+
+	// Не читаем канал, пока изменений не станет больше любого разумного буфера
+	const n = 300
+	for i := 0; i < n; i++ {
+		require.NoError(t, repo.Create(ctx, &domen.Task{ID: fmt.Sprintf("task-%d", i)}))
+	}
+
+	// Канал закрывается, а дошедшие изменения идут подряд
+	var first, last int64
+	received := 0
+	timeout := time.After(5 * time.Second)
+	for open := true; open; {
+		select {
+		case c, ok := <-slow:
+			if !ok {
+				open = false
+				break
+			}
+			if first == 0 {
+				first = c.Revision
+			} else {
+				assert.Equal(t, last+1, c.Revision)
+			}
+			last = c.Revision
+			received++
+		case <-timeout:
+			t.Fatal("канал медленного подписчика не закрыт")
+		}
+	}
+	require.NotZero(t, received)
+	assert.Less(t, received, n)
+
+	// Переподписка с последней полученной ревизии ничего не теряет
+	resumed, err := repo.Watch(ctx, domen.WatchFilter{FromRevision: last})
+	require.NoError(t, err)
+	assert.Equal(t, last+1, recv(t, resumed).Revision)
+}
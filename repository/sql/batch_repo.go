@@ -0,0 +1,103 @@
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// BatchRepo — реализация domen.BatchRepository в той же базе, что и задачи.
+// Таблицу создаёт SQLRepo.Migrate.
+type BatchRepo struct {
+	repo *SQLRepo
+}
+
+// Batches возвращает репозиторий пакетов, работающий через то же подключение.
+func (r *SQLRepo) Batches() *BatchRepo {
+	return &BatchRepo{repo: r}
+}
+
+func (b *BatchRepo) Create(ctx context.Context, batch *domen.Batch) error {
+	taskIDs, err := json.Marshal(batch.TaskIDs)
+	if err != nil {
+		return err
+	}
+	res, err := b.repo.db.ExecContext(ctx, b.repo.rebind(`INSERT INTO batches (`+batchColumns+`) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (id) DO NOTHING`),
+		batch.ID, encodeTime(batch.CreatedAt), string(taskIDs), encodeTime(batch.FinishedAt), batch.TenantID)
+	if err != nil {
+		return err
+	}
+	return insertedOne(res)
+}
+
+const batchColumns = `id, created_at, task_ids, finished_at, tenant_id`
+
+func (b *BatchRepo) Get(ctx context.Context, id string) (*domen.Batch, error) {
+	return scanBatch(b.repo.db.QueryRowContext(ctx,
+		b.repo.rebind(`SELECT `+batchColumns+` FROM batches WHERE id = ?`), id))
+}
+
+func (b *BatchRepo) ListUnfinished(ctx context.Context) ([]*domen.Batch, error) {
+	rows, err := b.repo.db.QueryContext(ctx,
+		`SELECT `+batchColumns+` FROM batches WHERE finished_at = 0 ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	batches := make([]*domen.Batch, 0)
+	for rows.Next() {
+		batch, err := scanBatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+	return batches, rows.Err()
+}
+
+func scanBatch(row scanner) (*domen.Batch, error) {
+	var (
+		batch                 domen.Batch
+		taskIDs               string
+		createdAt, finishedAt int64
+	)
+	err := row.Scan(&batch.ID, &createdAt, &taskIDs, &finishedAt, &batch.TenantID)
+	if errors.Is(err, stdsql.ErrNoRows) {
+		return nil, domen.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	batch.CreatedAt = decodeTime(createdAt)
+	batch.FinishedAt = decodeTime(finishedAt)
+	if err := json.Unmarshal([]byte(taskIDs), &batch.TaskIDs); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// Finish выставляет finished_at одним условным UPDATE, поэтому завершить
+// пакет может только один экземпляр сервиса.
+func (b *BatchRepo) Finish(ctx context.Context, id string, at time.Time) error {
+	res, err := b.repo.db.ExecContext(ctx,
+		b.repo.rebind(`UPDATE batches SET finished_at = ? WHERE id = ? AND finished_at = 0`), encodeTime(at), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	if _, err := b.Get(ctx, id); err != nil {
+		return err
+	}
+	return domen.ErrConflict
+}
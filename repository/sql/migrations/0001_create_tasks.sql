@@ -0,0 +1,14 @@
+CREATE TABLE tasks (
+    id         TEXT PRIMARY KEY,
+    created_at BIGINT NOT NULL,
+    started_at BIGINT,
+    ended_at   BIGINT,
+    duration   TEXT NOT NULL DEFAULT '',
+    status     TEXT NOT NULL,
+    result     TEXT NOT NULL DEFAULT '',
+    version    BIGINT NOT NULL,
+    history    TEXT NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX idx_tasks_created_at ON tasks (created_at, id);
+CREATE INDEX idx_tasks_status_created_at ON tasks (status, created_at, id);
@@ -0,0 +1,14 @@
+-- Журнал изменений для Watch. Ревизия выдаётся из счётчика task_revision
+-- в той же транзакции, что и запись, поэтому ревизии фиксируются по порядку.
+CREATE TABLE task_revision (
+    id       INTEGER PRIMARY KEY,
+    revision BIGINT NOT NULL
+);
+
+INSERT INTO task_revision (id, revision) VALUES (1, 0);
+
+CREATE TABLE task_changes (
+    revision BIGINT PRIMARY KEY,
+    type     TEXT NOT NULL,
+    task     TEXT NOT NULL
+);
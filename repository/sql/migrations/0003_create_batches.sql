@@ -0,0 +1,9 @@
+ALTER TABLE tasks ADD COLUMN batch_id TEXT NOT NULL DEFAULT '';
+
+-- Состав пакета хранится JSON-массивом: он не меняется после создания.
+CREATE TABLE batches (
+    id          TEXT PRIMARY KEY,
+    created_at  BIGINT NOT NULL,
+    task_ids    TEXT NOT NULL,
+    finished_at BIGINT NOT NULL DEFAULT 0
+);
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN rerun_of TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_tasks_rerun_of_created_at ON tasks (rerun_of, created_at, id);
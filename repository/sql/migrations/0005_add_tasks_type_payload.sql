@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN task_type TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE tasks ADD COLUMN payload TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN trace_context TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE tasks ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE batches ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX idx_tasks_tenant_status_created_at ON tasks (tenant_id, status, created_at, id);
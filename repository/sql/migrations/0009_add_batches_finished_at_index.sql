@@ -0,0 +1 @@
+CREATE INDEX idx_batches_finished_at ON batches (finished_at, created_at);
@@ -0,0 +1,416 @@
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// Dialect определяет синтаксис плейсхолдеров. Поддерживаются только SQLite
+// и PostgreSQL: запросы опираются на ON CONFLICT и DELETE ... RETURNING,
+// а миграции выполняются одним вызовом из нескольких операторов.
+type Dialect int
+
+const (
+	// SQLite использует плейсхолдеры "?".
+	SQLite Dialect = iota
+	// Postgres использует плейсхолдеры "$1", "$2", ...
+	Postgres
+)
+
+const taskColumns = "id, created_at, started_at, ended_at, duration, status, result, version, history, batch_id, rerun_of, task_type, payload, trace_context, created_by, tenant_id"
+
+// SQLRepo — реализация domen.TaskRepository поверх database/sql.
+// Драйвер регистрирует вызывающий код; перед работой нужно вызвать Migrate.
+type SQLRepo struct {
+	db      *stdsql.DB
+	dialect Dialect
+	// pollInterval — как часто подписчики Watch проверяют журнал на изменения,
+	// сделанные другими экземплярами сервиса. Локальные записи будят их сразу.
+	pollInterval time.Duration
+	notify       *notifier
+}
+
+func NewSQLRepo(db *stdsql.DB, dialect Dialect) *SQLRepo {
+	return &SQLRepo{
+		db:           db,
+		dialect:      dialect,
+		pollInterval: defaultPollInterval,
+		notify:       newNotifier(),
+	}
+}
+
+func (r *SQLRepo) Create(ctx context.Context, t *domen.Task) error {
+	var stored *domen.Task
+	err := r.inTx(ctx, func(tx *txn) (err error) {
+		stored, err = tx.create(ctx, t)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	t.Version = stored.Version
+	r.notify.broadcast()
+	return nil
+}
+
+// Update выполняет compare-and-swap по version одним UPDATE в транзакции
+// вместе с записью в журнал изменений.
+func (r *SQLRepo) Update(ctx context.Context, t *domen.Task) error {
+	var stored *domen.Task
+	err := r.inTx(ctx, func(tx *txn) (err error) {
+		stored, err = tx.update(ctx, t)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	t.Version = stored.Version
+	r.notify.broadcast()
+	return nil
+}
+
+func (r *SQLRepo) Delete(ctx context.Context, id string, version int64) error {
+	err := r.inTx(ctx, func(tx *txn) error {
+		return tx.delete(ctx, id, version)
+	})
+	if err != nil {
+		return err
+	}
+	r.notify.broadcast()
+	return nil
+}
+
+// CreateMany создаёт задачи в одной транзакции. Ошибки отдельных задач
+// (ErrAlreadyExists) не откатывают остальные; ошибка базы откатывает всё.
+func (r *SQLRepo) CreateMany(ctx context.Context, tasks []*domen.Task) ([]error, error) {
+	stored := make([]*domen.Task, len(tasks))
+	errs, err := r.bulk(ctx, len(tasks), func(tx *txn, i int) (err error) {
+		stored[i], err = tx.create(ctx, tasks[i])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, t := range tasks {
+		if errs[i] == nil {
+			t.Version = stored[i].Version
+		}
+	}
+	return errs, nil
+}
+
+func (r *SQLRepo) UpdateMany(ctx context.Context, tasks []*domen.Task) ([]error, error) {
+	stored := make([]*domen.Task, len(tasks))
+	errs, err := r.bulk(ctx, len(tasks), func(tx *txn, i int) (err error) {
+		stored[i], err = tx.update(ctx, tasks[i])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, t := range tasks {
+		if errs[i] == nil {
+			t.Version = stored[i].Version
+		}
+	}
+	return errs, nil
+}
+
+func (r *SQLRepo) DeleteMany(ctx context.Context, ids []string) ([]error, error) {
+	return r.bulk(ctx, len(ids), func(tx *txn, i int) error {
+		return tx.delete(ctx, ids[i], 0)
+	})
+}
+
+// bulk выполняет fn для n элементов в одной транзакции. Доменные ошибки
+// записываются в результат элемента, любая другая прерывает пакет.
+func (r *SQLRepo) bulk(ctx context.Context, n int, fn func(tx *txn, i int) error) ([]error, error) {
+	errs := make([]error, n)
+	err := r.inTx(ctx, func(tx *txn) error {
+		for i := 0; i < n; i++ {
+			err := fn(tx, i)
+			if errors.Is(err, domen.ErrNotFound) || errors.Is(err, domen.ErrConflict) || errors.Is(err, domen.ErrAlreadyExists) {
+				errs[i] = err
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.notify.broadcast()
+	return errs, nil
+}
+
+func (r *SQLRepo) Get(ctx context.Context, id string) (*domen.Task, error) {
+	return scanTask(r.db.QueryRowContext(ctx, r.rebind(`SELECT `+taskColumns+` FROM tasks WHERE id = ?`), id))
+}
+
+func (r *SQLRepo) List(ctx context.Context) ([]*domen.Task, error) {
+	return r.Find(ctx, domen.TaskFilter{})
+}
+
+// Find строит запрос по индексам (status, created_at, id), (rerun_of, created_at, id),
+// (tenant_id, status, created_at, id) и (created_at, id).
+func (r *SQLRepo) Find(ctx context.Context, filter domen.TaskFilter) ([]*domen.Task, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if filter.TenantID != "" {
+		where = append(where, "tenant_id = ?")
+		args = append(args, filter.TenantID)
+	}
+	if filter.RerunOf != "" {
+		where = append(where, "rerun_of = ?")
+		args = append(args, filter.RerunOf)
+	}
+	if len(filter.Statuses) > 0 {
+		marks := make([]string, len(filter.Statuses))
+		for i, s := range filter.Statuses {
+			marks[i] = "?"
+			args = append(args, string(s))
+		}
+		where = append(where, "status IN ("+strings.Join(marks, ", ")+")")
+	}
+	if len(filter.Types) > 0 {
+		marks := make([]string, len(filter.Types))
+		for i, typ := range filter.Types {
+			marks[i] = "?"
+			args = append(args, typ)
+		}
+		where = append(where, "task_type IN ("+strings.Join(marks, ", ")+")")
+	}
+	if !filter.CreatedFrom.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, encodeTime(filter.CreatedFrom))
+	}
+	if !filter.CreatedTo.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, encodeTime(filter.CreatedTo))
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM tasks`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	if filter.NewestFirst {
+		query += " ORDER BY created_at DESC, id DESC"
+	} else {
+		query += " ORDER BY created_at, id"
+	}
+	if filter.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(filter.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, r.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]*domen.Task, 0)
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(row scanner) (*domen.Task, error) {
+	var (
+		t                           domen.Task
+		status, history, payload    string
+		createdAt, startedAt, ended int64
+	)
+	err := row.Scan(&t.ID, &createdAt, &startedAt, &ended, &t.Duration, &status, &t.Result, &t.Version, &history,
+		&t.BatchID, &t.RerunOf, &t.Type, &payload, &t.TraceContext, &t.CreatedBy, &t.TenantID)
+	if errors.Is(err, stdsql.ErrNoRows) {
+		return nil, domen.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.CreatedAt = decodeTime(createdAt)
+	t.StartedAt = decodeTime(startedAt)
+	t.EndedAt = decodeTime(ended)
+	t.Status = domen.Status(status)
+	if payload != "" {
+		t.Payload = json.RawMessage(payload)
+	}
+	if err := json.Unmarshal([]byte(history), &t.History); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// encodeTime хранит время как Unix-наносекунды, нулевое время — как 0.
+func encodeTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func decodeTime(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// txn — транзакция с переписыванием плейсхолдеров под диалект.
+type txn struct {
+	*stdsql.Tx
+	repo *SQLRepo
+}
+
+func (tx *txn) exec(ctx context.Context, query string, args ...any) (stdsql.Result, error) {
+	return tx.ExecContext(ctx, tx.repo.rebind(query), args...)
+}
+
+func (tx *txn) queryRow(ctx context.Context, query string, args ...any) *stdsql.Row {
+	return tx.QueryRowContext(ctx, tx.repo.rebind(query), args...)
+}
+
+func (tx *txn) create(ctx context.Context, t *domen.Task) (*domen.Task, error) {
+	stored := t.Clone()
+	stored.Version = 1
+	history, err := json.Marshal(stored.History)
+	if err != nil {
+		return nil, err
+	}
+	// Занятость ID проверяет сама вставка: между отдельными SELECT и INSERT
+	// задачу с тем же ID успел бы вставить другой экземпляр сервиса
+	res, err := tx.exec(ctx, `INSERT INTO tasks (`+taskColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) DO NOTHING`,
+		stored.ID, encodeTime(stored.CreatedAt), encodeTime(stored.StartedAt), encodeTime(stored.EndedAt),
+		stored.Duration, string(stored.Status), stored.Result, stored.Version, string(history), stored.BatchID, stored.RerunOf,
+		stored.Type, string(stored.Payload), stored.TraceContext, stored.CreatedBy, stored.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertedOne(res); err != nil {
+		return nil, err
+	}
+	return stored, tx.appendChange(ctx, domen.ChangeCreated, stored)
+}
+
+// insertedOne проверяет, что INSERT ... ON CONFLICT DO NOTHING вставил
+// строку; иначе ключ занят.
+func insertedOne(res stdsql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domen.ErrAlreadyExists
+	}
+	return nil
+}
+
+func (tx *txn) update(ctx context.Context, t *domen.Task) (*domen.Task, error) {
+	stored := t.Clone()
+	stored.Version = t.Version + 1
+	history, err := json.Marshal(stored.History)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.exec(ctx, `UPDATE tasks
+SET created_at = ?, started_at = ?, ended_at = ?, duration = ?, status = ?, result = ?, version = ?, history = ?, batch_id = ?, rerun_of = ?, task_type = ?, payload = ?, trace_context = ?, created_by = ?, tenant_id = ?
+WHERE id = ? AND version = ?`,
+		encodeTime(stored.CreatedAt), encodeTime(stored.StartedAt), encodeTime(stored.EndedAt),
+		stored.Duration, string(stored.Status), stored.Result, stored.Version, string(history), stored.BatchID, stored.RerunOf,
+		stored.Type, string(stored.Payload), stored.TraceContext, stored.CreatedBy, stored.TenantID, t.ID, t.Version)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		var version int64
+		err := tx.queryRow(ctx, `SELECT version FROM tasks WHERE id = ?`, t.ID).Scan(&version)
+		if errors.Is(err, stdsql.ErrNoRows) {
+			return nil, domen.ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, domen.ErrConflict
+	}
+	return stored, tx.appendChange(ctx, domen.ChangeUpdated, stored)
+}
+
+// delete удаляет задачу одним запросом, так что проверка версии и
+// удаление не разделены чужими изменениями.
+func (tx *txn) delete(ctx context.Context, id string, version int64) error {
+	t, err := scanTask(tx.queryRow(ctx, `DELETE FROM tasks WHERE id = ? AND (? = 0 OR version = ?) RETURNING `+taskColumns,
+		id, version, version))
+	if errors.Is(err, domen.ErrNotFound) && version != 0 {
+		var exists int
+		err = tx.queryRow(ctx, `SELECT 1 FROM tasks WHERE id = ?`, id).Scan(&exists)
+		if errors.Is(err, stdsql.ErrNoRows) {
+			return domen.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return domen.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	return tx.appendChange(ctx, domen.ChangeDeleted, t)
+}
+
+func (r *SQLRepo) inTx(ctx context.Context, fn func(tx *txn) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(&txn{Tx: tx, repo: r}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// rebind заменяет "?" на "$n" для Postgres. Запросы репозитория не содержат
+// "?" внутри строковых литералов.
+func (r *SQLRepo) rebind(query string) string {
+	if r.dialect != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
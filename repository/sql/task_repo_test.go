@@ -0,0 +1,117 @@
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "modernc.org/sqlite"
+)
+
+func openSQLite(t *testing.T) *stdsql.DB {
+	t.Helper()
+	dsn := "file:" + filepath.Join(t.TempDir(), "tasks.db") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := stdsql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	// SQLite допускает одного писателя; одно соединение исключает SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func newTestRepo(t *testing.T) *SQLRepo {
+	t.Helper()
+	repo := NewSQLRepo(openSQLite(t), SQLite)
+	repo.pollInterval = 20 * time.Millisecond
+	require.NoError(t, repo.Migrate(context.Background()))
+	return repo
+}
+
+func TestSQLRepo_Contract(t *testing.T) {
+	repotest.RunContract(t, func(t *testing.T) domen.TaskRepository {
+		return newTestRepo(t)
+	})
+}
+
+func TestBatchRepo_Contract(t *testing.T) {
+	repotest.RunBatchContract(t, func(t *testing.T) domen.BatchRepository {
+		return newTestRepo(t).Batches()
+	})
+}
+
+func TestSQLRepo_MigrateIdempotent(t *testing.T) {
+	db := openSQLite(t)
+	repo := NewSQLRepo(db, SQLite)
+	ctx := context.Background()
+
+	require.NoError(t, repo.Migrate(ctx))
+	require.NoError(t, repo.Migrate(ctx))
+
+	migrations, err := loadMigrations()
+	require.NoError(t, err)
+	var applied int
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+	assert.Equal(t, len(migrations), applied)
+}
+
+func TestSQLRepo_Durable(t *testing.T) {
+	// Данные и ревизии переживают пересоздание репозитория поверх той же БД
+	db := openSQLite(t)
+	ctx := context.Background()
+
+	repo := NewSQLRepo(db, SQLite)
+	require.NoError(t, repo.Migrate(ctx))
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "task-1", Status: domen.StatusPending}))
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "task-2", Status: domen.StatusPending}))
+
+	reopened := NewSQLRepo(db, SQLite)
+	require.NoError(t, reopened.Migrate(ctx))
+	got, err := reopened.Get(ctx, "task-1")
+	require.NoError(t, err)
+	assert.Equal(t, domen.StatusPending, got.Status)
+
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch, err := reopened.Watch(wctx, domen.WatchFilter{FromRevision: 1})
+	require.NoError(t, err)
+	select {
+	case c := <-ch:
+		assert.Equal(t, int64(2), c.Revision)
+		assert.Equal(t, "task-2", c.Task.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("изменение не пришло")
+	}
+}
+
+func TestSQLRepo_WatchCompacted(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "task-1"}))
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "task-2"}))
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM task_changes WHERE revision <= 1`)
+	require.NoError(t, err)
+
+	// Ревизия 1 ещё позволяет продолжить (следующая, 2, в журнале есть), 0 — нет
+	_, err = repo.Watch(ctx, domen.WatchFilter{FromRevision: 1})
+	assert.NoError(t, err)
+	_, err = repo.db.ExecContext(ctx, `DELETE FROM task_changes WHERE revision <= 2`)
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(ctx, &domen.Task{ID: "task-3"}))
+	_, err = repo.Watch(ctx, domen.WatchFilter{FromRevision: 1})
+	assert.ErrorIs(t, err, domen.ErrRevisionCompacted)
+}
+
+func TestSQLRepo_Rebind(t *testing.T) {
+	pg := &SQLRepo{dialect: Postgres}
+	assert.Equal(t, "SELECT a FROM t WHERE id = $1 AND v = $2", pg.rebind("SELECT a FROM t WHERE id = ? AND v = ?"))
+	lite := &SQLRepo{dialect: SQLite}
+	assert.Equal(t, "SELECT a FROM t WHERE id = ?", lite.rebind("SELECT a FROM t WHERE id = ?"))
+}
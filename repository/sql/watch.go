@@ -0,0 +1,218 @@
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+)
+
+const (
+	// changeRetention — сколько последних изменений как минимум хранится в task_changes.
+	changeRetention = 10000
+	// watchBufferSize — буфер канала подписчика. Подписчик, переполнивший
+	// буфер, отключается.
+	watchBufferSize = 64
+	// watchBatchSize — сколько изменений подписчик читает за один запрос.
+	watchBatchSize = 256
+
+	defaultPollInterval = 500 * time.Millisecond
+)
+
+// appendChange выдаёт следующую ревизию и пишет изменение в журнал в той же
+// транзакции. Счётчик в task_revision блокирует строку до коммита, поэтому
+// ревизии становятся видны строго по порядку.
+func (tx *txn) appendChange(ctx context.Context, typ domen.ChangeType, t *domen.Task) error {
+	if _, err := tx.exec(ctx, `UPDATE task_revision SET revision = revision + 1 WHERE id = 1`); err != nil {
+		return err
+	}
+	var rev int64
+	if err := tx.queryRow(ctx, `SELECT revision FROM task_revision WHERE id = 1`).Scan(&rev); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(changeTask{Task: t, History: t.History, TraceContext: t.TraceContext, TenantID: t.TenantID})
+	if err != nil {
+		return err
+	}
+	if _, err := tx.exec(ctx, `INSERT INTO task_changes (revision, type, task) VALUES (?, ?, ?)`,
+		rev, string(typ), string(payload)); err != nil {
+		return err
+	}
+	if rev%changeRetention == 0 {
+		_, err = tx.exec(ctx, `DELETE FROM task_changes WHERE revision <= ?`, rev-changeRetention)
+	}
+	return err
+}
+
+// changeTask сериализует задачу в журнал вместе с History, TraceContext
+// и TenantID, которые не попадают в JSON задачи.
+type changeTask struct {
+	*domen.Task
+	History      []domen.Transition `json:"history"`
+	TraceContext string             `json:"trace_context,omitempty"`
+	TenantID     string             `json:"tenant_id,omitempty"`
+}
+
+// Watch реализует domen.TaskRepository. Изменения читаются из task_changes,
+// поэтому подписка переживает рестарт: достаточно передать FromRevision.
+// Подписчик, отставший дальше, чем хранится журнал, или не успевающий читать
+// канал, получает закрытый канал; если его ревизия уже вытеснена, повторный
+// Watch с ней вернёт domen.ErrRevisionCompacted.
+func (r *SQLRepo) Watch(ctx context.Context, filter domen.WatchFilter) (<-chan domen.TaskChange, error) {
+	var cur int64
+	if err := r.db.QueryRowContext(ctx, `SELECT revision FROM task_revision WHERE id = 1`).Scan(&cur); err != nil {
+		return nil, err
+	}
+	from := filter.FromRevision
+	switch {
+	case from > cur:
+		return nil, fmt.Errorf("%w: revision %d is ahead of the current revision %d",
+			domen.ErrInvalidArgument, from, cur)
+	case from == 0:
+		from = cur
+	case from < cur:
+		compacted, err := r.compacted(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+		if compacted {
+			return nil, domen.ErrRevisionCompacted
+		}
+	}
+
+	ch := make(chan domen.TaskChange, watchBufferSize)
+	wake := r.notify.subscribe()
+	go func() {
+		defer close(ch)
+		defer r.notify.unsubscribe(wake)
+		r.pollChanges(ctx, filter, from, ch, wake)
+	}()
+	return ch, nil
+}
+
+func (r *SQLRepo) pollChanges(ctx context.Context, filter domen.WatchFilter, last int64, ch chan<- domen.TaskChange, wake <-chan struct{}) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	log := logger.FromContext(ctx)
+	for {
+		changes, err := r.changesAfter(ctx, last)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warnw("failed to read task changes, closing watch", "revision", last, "error", err)
+			}
+			return
+		}
+		if len(changes) > 0 && changes[0].Revision != last+1 {
+			// Журнал успели подрезать: подписчик отстал безвозвратно.
+			log.Warnw("watcher fell behind the change log, closing watch", "revision", last)
+			return
+		}
+		for _, c := range changes {
+			last = c.Revision
+			if !filter.Match(c) {
+				continue
+			}
+			select {
+			case ch <- c:
+			default:
+				// Медленный подписчик: закрываем канал, он переподпишется с последней ревизии.
+				return
+			}
+		}
+		if len(changes) == watchBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *SQLRepo) changesAfter(ctx context.Context, rev int64) ([]domen.TaskChange, error) {
+	rows, err := r.db.QueryContext(ctx,
+		r.rebind(`SELECT revision, type, task FROM task_changes WHERE revision > ? ORDER BY revision LIMIT ?`),
+		rev, watchBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []domen.TaskChange
+	for rows.Next() {
+		var (
+			c       domen.TaskChange
+			typ     string
+			payload string
+		)
+		if err := rows.Scan(&c.Revision, &typ, &payload); err != nil {
+			return nil, err
+		}
+		var ct changeTask
+		if err := json.Unmarshal([]byte(payload), &ct); err != nil {
+			return nil, err
+		}
+		c.Type = domen.ChangeType(typ)
+		c.Task = ct.Task
+		c.Task.History = ct.History
+		c.Task.TraceContext = ct.TraceContext
+		c.Task.TenantID = ct.TenantID
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// compacted сообщает, что изменения после rev уже удалены из журнала.
+func (r *SQLRepo) compacted(ctx context.Context, rev int64) (bool, error) {
+	var oldest stdsql.NullInt64
+	err := r.db.QueryRowContext(ctx, `SELECT MIN(revision) FROM task_changes`).Scan(&oldest)
+	if err != nil && !errors.Is(err, stdsql.ErrNoRows) {
+		return false, err
+	}
+	return !oldest.Valid || rev < oldest.Int64-1, nil
+}
+
+// notifier будит подписчиков Watch после локальных записей.
+type notifier struct {
+	mu   sync.Mutex
+	subs map[chan struct{}]struct{}
+}
+
+func newNotifier() *notifier {
+	return &notifier{subs: make(map[chan struct{}]struct{})}
+}
+
+func (n *notifier) subscribe() chan struct{} {
+	ch := make(chan struct{}, 1)
+	n.mu.Lock()
+	n.subs[ch] = struct{}{}
+	n.mu.Unlock()
+	return ch
+}
+
+func (n *notifier) unsubscribe(ch chan struct{}) {
+	n.mu.Lock()
+	delete(n.subs, ch)
+	n.mu.Unlock()
+}
+
+func (n *notifier) broadcast() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for ch := range n.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+)
+
+// SetAuditLog задаёт журнал, куда записываются создание, отмена, удаление
+// и повтор задач. Вызывать до начала работы.
+func (uc *TaskUseCase) SetAuditLog(l domen.AuditLog) {
+	uc.auditLog = l
+}
+
+// audit записывает в журнал действие принципала из ctx над задачей target
+// и его исход по err. Ошибку журнала действие не отменяет: оно уже
+// выполнено, поэтому она только логируется.
+func (uc *TaskUseCase) audit(ctx context.Context, action, target string, err error) {
+	uc.auditCreated(ctx, action, target, "", err)
+}
+
+// auditCreated — audit для действия, создавшего из target задачу created.
+func (uc *TaskUseCase) auditCreated(ctx context.Context, action, target, created string, err error) {
+	if uc.auditLog == nil {
+		return
+	}
+	origin := domen.OriginFromContext(ctx)
+	r := &domen.AuditRecord{
+		Action:    action,
+		Target:    target,
+		Created:   created,
+		Outcome:   domen.OutcomeSuccess,
+		RequestID: origin.RequestID,
+		ClientIP:  origin.ClientIP,
+	}
+	if p, ok := domen.PrincipalFromContext(ctx); ok {
+		r.Principal, r.Tenant = p.ID, p.TenantID()
+	}
+	switch {
+	case errors.Is(err, domen.ErrForbidden):
+		r.Outcome, r.Error = domen.OutcomeDenied, err.Error()
+	case err != nil:
+		r.Outcome, r.Error = domen.OutcomeFailure, err.Error()
+	}
+	if err := uc.auditLog.Append(ctx, r); err != nil {
+		logger.FromContext(ctx).Errorw("failed to write audit record", "action", action, "target", target, "error", err)
+	}
+}
+
+// auditBulk записывает по записи на каждую задачу пакетной операции или
+// одну запись без задачи, если до задач дело не дошло.
+func (uc *TaskUseCase) auditBulk(ctx context.Context, action string, results []BulkResult, err error) {
+	if results == nil {
+		uc.audit(ctx, action, "", err)
+		return
+	}
+	for _, res := range results {
+		uc.audit(ctx, action, res.ID, res.Err)
+	}
+}
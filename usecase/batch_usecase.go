@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Пауза перед переподпиской, когда репозиторий закрыл канал изменений:
+// растёт вдвое, пока изменения не пойдут снова.
+const (
+	resubscribeMinDelay = 100 * time.Millisecond
+	resubscribeMaxDelay = 5 * time.Second
+)
+
+// BatchFinishedFunc вызывается один раз, когда все задачи пакета
+// в терминальном состоянии или удалены.
+type BatchFinishedFunc func(ctx context.Context, p *domen.BatchProgress)
+
+type BatchUseCase struct {
+	tasks    *TaskUseCase
+	batches  domen.BatchRepository
+	finished []BatchFinishedFunc
+
+	watching  chan struct{}
+	watchOnce sync.Once
+}
+
+func NewBatchUseCase(tasks *TaskUseCase, batches domen.BatchRepository) *BatchUseCase {
+	return &BatchUseCase{
+		tasks:    tasks,
+		batches:  isolateBatches(batches),
+		watching: make(chan struct{}),
+	}
+}
+
+// Watching закрывается, когда Run подписался на изменения задач: с этого
+// момента завершение пакетов не теряется.
+func (uc *BatchUseCase) Watching() <-chan struct{} {
+	return uc.watching
+}
+
+// OnFinished регистрирует обработчик завершения пакета. Вызывать до Run.
+func (uc *BatchUseCase) OnFinished(fn BatchFinishedFunc) {
+	uc.finished = append(uc.finished, fn)
+}
+
+// CreateBatch создаёт пакет из n задач по spec и запускает их выполнение.
+func (uc *BatchUseCase) CreateBatch(ctx context.Context, n int, spec TaskSpec) (_ *domen.Batch, err error) {
+	ctx, span := startSpan(ctx, "BatchUseCase.CreateBatch", attribute.Int("tasks.count", n))
+	defer func() { endSpan(span, err) }()
+	var results []BulkResult
+	defer func() { uc.tasks.auditBulk(ctx, domen.ActionTaskCreate, results, err) }()
+
+	if err := uc.tasks.checkCreate(ctx, n, &spec); err != nil {
+		return nil, err
+	}
+	done, err := uc.tasks.admit(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { done(countCreated(results)) }()
+	tasks := uc.tasks.newTasks(ctx, n, spec)
+	batch := &domen.Batch{
+		ID:        uuid.NewString(),
+		CreatedAt: tasks[0].CreatedAt,
+		TaskIDs:   make([]string, n),
+		TenantID:  tenantOf(ctx),
+	}
+	for i, t := range tasks {
+		t.BatchID = batch.ID
+		batch.TaskIDs[i] = t.ID
+	}
+
+	// Пакет создаётся целиком или не создаётся вовсе: задачи сохраняются
+	// без запуска, затем пакет, и только потом задачи запускаются. Пока
+	// задачи не запущены, они не завершатся, и Run не пропустит пакет.
+	saved, err := uc.tasks.saveTasks(ctx, tasks)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range saved {
+		if res.Err != nil {
+			uc.tasks.discardSaved(ctx, saved)
+			return nil, fmt.Errorf("task %s: %w", res.ID, res.Err)
+		}
+	}
+	if err := uc.batches.Create(ctx, batch); err != nil {
+		uc.tasks.discardSaved(ctx, saved)
+		return nil, err
+	}
+	uc.tasks.startSaved(ctx, saved)
+	results = saved
+	return batch, nil
+}
+
+// GetBatch возвращает пакет со сводкой по статусам его задач. Если
+// принципалу из ctx нельзя читать хотя бы одну задачу пакета, весь запрос
+// отклоняется с domen.ErrForbidden.
+func (uc *BatchUseCase) GetBatch(ctx context.Context, id string) (_ *domen.BatchProgress, err error) {
+	ctx, span := startSpan(ctx, "BatchUseCase.GetBatch", attribute.String("batch.id", id))
+	defer func() { endSpan(span, err) }()
+
+	batch, err := uc.batches.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]*domen.Task, 0, len(batch.TaskIDs))
+	for _, taskID := range batch.TaskIDs {
+		t, err := uc.tasks.get(ctx, domen.ActionTaskRead, taskID)
+		if errors.Is(err, domen.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return domen.NewBatchProgress(batch, tasks), nil
+}
+
+// CancelBatch отменяет все задачи пакета. Для уже завершённых задач
+// результат содержит *domen.TransitionError, как и в CancelTasks.
+func (uc *BatchUseCase) CancelBatch(ctx context.Context, id string) (_ []BulkResult, err error) {
+	ctx, span := startSpan(ctx, "BatchUseCase.CancelBatch", attribute.String("batch.id", id))
+	defer func() { endSpan(span, err) }()
+
+	batch, err := uc.batches.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return uc.tasks.CancelTasks(ctx, BulkSelector{IDs: batch.TaskIDs})
+}
+
+// Run следит за изменениями задач и завершает пакеты, все задачи которых
+// дошли до терминального состояния. Блокируется до отмены ctx.
+func (uc *BatchUseCase) Run(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+	var rev int64
+	// Подписка с текущего момента пропускает изменения до неё: при запуске
+	// и после вытеснения ревизии пакеты сверяются с состоянием задач
+	reconcile := true
+	delay := resubscribeMinDelay
+	for {
+		changes, err := uc.tasks.repo.Watch(ctx, domen.WatchFilter{FromRevision: rev})
+		if errors.Is(err, domen.ErrRevisionCompacted) {
+			log.Warnw("batch watcher fell behind the change log, reconciling unfinished batches", "revision", rev)
+			rev = 0
+			reconcile = true
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		uc.watchOnce.Do(func() { close(uc.watching) })
+		// Сверка идёт уже после подписки, чтобы не потерять изменения,
+		// случившиеся во время неё; повторная проверка пакета безвредна
+		if reconcile {
+			uc.reconcile(ctx)
+			reconcile = false
+		}
+		received := false
+		for c := range changes {
+			rev, received = c.Revision, true
+			if c.Task.BatchID == "" {
+				continue
+			}
+			if c.Type == domen.ChangeDeleted || c.Task.Status.IsTerminal() {
+				uc.check(ctx, c.Task.BatchID)
+			}
+		}
+		if received {
+			delay = resubscribeMinDelay
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, resubscribeMaxDelay)
+	}
+}
+
+// reconcile проверяет все незавершённые пакеты.
+func (uc *BatchUseCase) reconcile(ctx context.Context) {
+	batches, err := uc.batches.ListUnfinished(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Errorw("failed to list unfinished batches", "error", err)
+		return
+	}
+	for _, b := range batches {
+		uc.check(ctx, b.ID)
+	}
+}
+
+// check завершает пакет, если все его задачи закончены.
+func (uc *BatchUseCase) check(ctx context.Context, id string) {
+	log := logger.FromContext(ctx).WithField("batch_id", id)
+
+	p, err := uc.GetBatch(ctx, id)
+	if err != nil {
+		if !errors.Is(err, domen.ErrNotFound) {
+			log.Errorw("failed to check batch", "error", err)
+		}
+		return
+	}
+	if !p.FinishedAt.IsZero() || !p.Finished() {
+		return
+	}
+	p.FinishedAt = time.Now()
+	err = uc.batches.Finish(ctx, id, p.FinishedAt)
+	if errors.Is(err, domen.ErrConflict) {
+		return
+	}
+	if err != nil {
+		log.Errorw("failed to finish batch", "error", err)
+		return
+	}
+	for _, fn := range uc.finished {
+		fn(ctx, p)
+	}
+}
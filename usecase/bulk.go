@@ -0,0 +1,369 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxBulkItems ограничивает число задач в одной пакетной операции.
+const maxBulkItems = 1000
+
+// BulkSelector задаёт задачи пакетной операции: либо список ID, либо фильтр.
+type BulkSelector struct {
+	IDs    []string
+	Filter *domen.TaskFilter
+}
+
+// BulkResult — результат пакетной операции для одной задачи. Task равен nil,
+// если операция над задачей не удалась или задача удалена.
+type BulkResult struct {
+	ID   string
+	Task *domen.Task
+	Err  error
+}
+
+// TaskSpec — тип и параметры задач, создаваемых пачкой. Пустой Type —
+// domen.DefaultTaskType.
+type TaskSpec struct {
+	Type    string
+	Payload json.RawMessage
+}
+
+// CreateTasks создаёт n задач по spec и запускает их выполнение. Payload,
+// не прошедший схему типа, даёт *domen.ValidationError.
+func (uc *TaskUseCase) CreateTasks(ctx context.Context, n int, spec TaskSpec) (results []BulkResult, err error) {
+	ctx, span := startSpan(ctx, "TaskUseCase.CreateTasks", attribute.Int("tasks.count", n))
+	defer func() { endSpan(span, err) }()
+	defer func() { uc.auditBulk(ctx, domen.ActionTaskCreate, results, err) }()
+
+	if err := uc.checkCreate(ctx, n, &spec); err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("task.type", spec.Type))
+	done, err := uc.admit(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { done(countCreated(results)) }()
+	return uc.createTasks(ctx, uc.newTasks(ctx, n, spec))
+}
+
+// countCreated возвращает число успешно созданных задач.
+func countCreated(results []BulkResult) int {
+	n := 0
+	for _, res := range results {
+		if res.Err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// checkCreate проверяет число задач, право на создание и payload по схеме
+// типа. Пустой spec.Type заменяется типом по умолчанию.
+func (uc *TaskUseCase) checkCreate(ctx context.Context, n int, spec *TaskSpec) error {
+	if n <= 0 || n > maxBulkItems {
+		return fmt.Errorf("%w: count must be between 1 and %d", domen.ErrInvalidArgument, maxBulkItems)
+	}
+	if spec.Type == "" {
+		spec.Type = domen.DefaultTaskType
+	}
+	if err := uc.authorize(ctx, domen.ActionTaskCreate, nil); err != nil {
+		return err
+	}
+	return uc.types.validate(spec.Type, spec.Payload)
+}
+
+// newTasks создаёт n новых задач по spec.
+func (uc *TaskUseCase) newTasks(ctx context.Context, n int, spec TaskSpec) []*domen.Task {
+	now := time.Now()
+	tasks := make([]*domen.Task, n)
+	for i := range tasks {
+		tasks[i] = newTask(ctx, now)
+		tasks[i].Type = spec.Type
+		tasks[i].Payload = spec.Payload
+	}
+	return tasks
+}
+
+// createTasks сохраняет задачи и запускает выполнение сохранённых. Квоты
+// проверяет вызывающий через admit.
+func (uc *TaskUseCase) createTasks(ctx context.Context, tasks []*domen.Task) ([]BulkResult, error) {
+	results, err := uc.saveTasks(ctx, tasks)
+	if err != nil {
+		return nil, err
+	}
+	uc.startSaved(ctx, results)
+	return results, nil
+}
+
+// saveTasks сохраняет задачи за один проход, если репозиторий это
+// поддерживает, но не запускает их.
+func (uc *TaskUseCase) saveTasks(ctx context.Context, tasks []*domen.Task) ([]BulkResult, error) {
+	tc := traceParent(ctx)
+	for _, t := range tasks {
+		t.TraceContext = tc
+	}
+
+	var errs []error
+	if bulk, ok := uc.repo.(domen.BulkRepository); ok {
+		var err error
+		if errs, err = bulk.CreateMany(ctx, tasks); err != nil {
+			return nil, err
+		}
+	} else {
+		errs = make([]error, len(tasks))
+		for i, t := range tasks {
+			errs[i] = uc.repo.Create(ctx, t)
+		}
+	}
+
+	results := make([]BulkResult, len(tasks))
+	for i, t := range tasks {
+		results[i] = BulkResult{ID: t.ID, Err: errs[i]}
+		if errs[i] == nil {
+			results[i].Task = t
+		}
+	}
+	return results, nil
+}
+
+// startSaved запускает задачи, сохранённые saveTasks.
+func (uc *TaskUseCase) startSaved(ctx context.Context, results []BulkResult) {
+	for _, res := range results {
+		if res.Err == nil {
+			uc.start(ctx, res.Task)
+		}
+	}
+}
+
+// discardSaved удаляет задачи, сохранённые saveTasks, но так и не
+// запущенные. Удаление не зависит от отмены запроса: иначе задачи
+// останутся в хранилище навсегда в PENDING.
+func (uc *TaskUseCase) discardSaved(ctx context.Context, results []BulkResult) {
+	ctx = context.WithoutCancel(ctx)
+	for _, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		if err := uc.repo.Delete(ctx, res.ID, res.Task.Version); err != nil {
+			logger.FromContext(ctx).Errorw("failed to discard task", "task_id", res.ID, "error", err)
+		}
+	}
+}
+
+// CancelTasks отменяет выбранные задачи. Если репозиторий поддерживает
+// пакетные операции, все изменения сохраняются за один проход; задачи,
+// изменённые параллельно, отменяются повторно по одной.
+func (uc *TaskUseCase) CancelTasks(ctx context.Context, sel BulkSelector) (results []BulkResult, err error) {
+	ctx, span := startSpan(ctx, "TaskUseCase.CancelTasks", attribute.Int("tasks.ids", len(sel.IDs)))
+	defer func() { endSpan(span, err) }()
+	defer func() { uc.auditBulk(ctx, domen.ActionTaskCancel, results, err) }()
+
+	results, err = uc.load(ctx, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	bulk, ok := uc.repo.(domen.BulkRepository)
+	if !ok {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Task, results[i].Err = uc.cancelTask(ctx, results[i].ID, 0)
+			}
+		}
+		return results, nil
+	}
+
+	var (
+		pending []*domen.Task
+		pos     []int
+	)
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		err := uc.authorize(ctx, domen.ActionTaskCancel, results[i].Task)
+		if err == nil {
+			err = cancel(results[i].Task)
+		}
+		if errors.Is(err, errSkipUpdate) {
+			continue
+		}
+		if err != nil {
+			results[i].Task, results[i].Err = nil, err
+			continue
+		}
+		pending = append(pending, results[i].Task)
+		pos = append(pos, i)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	errs, err := bulk.UpdateMany(ctx, pending)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range pos {
+		switch {
+		case errors.Is(errs[j], domen.ErrConflict):
+			results[i].Task, results[i].Err = uc.cancelTask(ctx, results[i].ID, 0)
+		case errs[j] != nil:
+			results[i].Task, results[i].Err = nil, errs[j]
+		}
+	}
+	return results, nil
+}
+
+// DeleteTasks удаляет выбранные задачи. Задачи, удалять которые
+// политика не разрешает, остаются, а в результате для них — отказ.
+func (uc *TaskUseCase) DeleteTasks(ctx context.Context, sel BulkSelector) (results []BulkResult, err error) {
+	ctx, span := startSpan(ctx, "TaskUseCase.DeleteTasks", attribute.Int("tasks.ids", len(sel.IDs)))
+	defer func() { endSpan(span, err) }()
+	defer func() { uc.auditBulk(ctx, domen.ActionTaskDelete, results, err) }()
+
+	if uc.policy != nil {
+		return uc.deleteAuthorized(ctx, sel)
+	}
+	ids, err := uc.resolve(ctx, sel)
+	if err != nil {
+		return nil, err
+	}
+	return uc.deleteTasks(ctx, ids)
+}
+
+// deleteAuthorized читает выбранные задачи, чтобы проверить политику,
+// и удаляет разрешённые.
+func (uc *TaskUseCase) deleteAuthorized(ctx context.Context, sel BulkSelector) ([]BulkResult, error) {
+	results, err := uc.load(ctx, sel)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		ids []string
+		pos []int
+	)
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Err = uc.authorize(ctx, domen.ActionTaskDelete, results[i].Task)
+		}
+		results[i].Task = nil
+		if results[i].Err == nil {
+			ids = append(ids, results[i].ID)
+			pos = append(pos, i)
+		}
+	}
+	if len(ids) == 0 {
+		return results, nil
+	}
+	deleted, err := uc.deleteTasks(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range pos {
+		results[i] = deleted[j]
+	}
+	return results, nil
+}
+
+func (uc *TaskUseCase) deleteTasks(ctx context.Context, ids []string) ([]BulkResult, error) {
+	var errs []error
+	if bulk, ok := uc.repo.(domen.BulkRepository); ok {
+		var err error
+		if errs, err = bulk.DeleteMany(ctx, ids); err != nil {
+			return nil, err
+		}
+	} else {
+		errs = make([]error, len(ids))
+		for i, id := range ids {
+			errs[i] = uc.repo.Delete(ctx, id, 0)
+		}
+	}
+
+	results := make([]BulkResult, len(ids))
+	for i, id := range ids {
+		results[i] = BulkResult{ID: id, Err: errs[i]}
+	}
+	return results, nil
+}
+
+// load читает выбранные задачи. Ненайденные ID попадают в результат с ErrNotFound.
+func (uc *TaskUseCase) load(ctx context.Context, sel BulkSelector) ([]BulkResult, error) {
+	if err := sel.validate(); err != nil {
+		return nil, err
+	}
+	if sel.Filter != nil {
+		tasks, err := uc.find(ctx, *sel.Filter)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]BulkResult, len(tasks))
+		for i, t := range tasks {
+			results[i] = BulkResult{ID: t.ID, Task: t}
+		}
+		return results, nil
+	}
+
+	results := make([]BulkResult, len(sel.IDs))
+	for i, id := range sel.IDs {
+		t, err := uc.repo.Get(ctx, id)
+		if err != nil && !errors.Is(err, domen.ErrNotFound) {
+			return nil, err
+		}
+		results[i] = BulkResult{ID: id, Task: t, Err: err}
+	}
+	return results, nil
+}
+
+// resolve возвращает ID выбранных задач, не читая их, если ID заданы явно.
+func (uc *TaskUseCase) resolve(ctx context.Context, sel BulkSelector) ([]string, error) {
+	if err := sel.validate(); err != nil {
+		return nil, err
+	}
+	if sel.Filter == nil {
+		return sel.IDs, nil
+	}
+	tasks, err := uc.find(ctx, *sel.Filter)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	return ids, nil
+}
+
+// find выбирает задачи по фильтру и отказывает, если их больше maxBulkItems.
+func (uc *TaskUseCase) find(ctx context.Context, filter domen.TaskFilter) ([]*domen.Task, error) {
+	filter.NewestFirst = false
+	filter.Limit = maxBulkItems + 1
+	tasks, err := uc.repo.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) > maxBulkItems {
+		return nil, fmt.Errorf("%w: filter matches more than %d tasks", domen.ErrInvalidArgument, maxBulkItems)
+	}
+	return tasks, nil
+}
+
+func (s BulkSelector) validate() error {
+	switch {
+	case s.Filter != nil && len(s.IDs) > 0:
+		return fmt.Errorf("%w: ids and filter are mutually exclusive", domen.ErrInvalidArgument)
+	case s.Filter == nil && len(s.IDs) == 0:
+		return fmt.Errorf("%w: ids or filter is required", domen.ErrInvalidArgument)
+	case len(s.IDs) > maxBulkItems:
+		return fmt.Errorf("%w: at most %d ids allowed", domen.ErrInvalidArgument, maxBulkItems)
+	}
+	return nil
+}
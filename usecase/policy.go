@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// SetPolicy задаёт политику доступа к задачам. Без политики принципалу
+// разрешено всё, что позволяют его области доступа. Вызывать до начала работы.
+func (uc *TaskUseCase) SetPolicy(p domen.Policy) {
+	uc.policy = p
+}
+
+// authorize проверяет действие принципала из ctx над задачей по политике.
+// Без принципала (фоновые задачи сервиса, выключенная аутентификация)
+// проверять нечего.
+func (uc *TaskUseCase) authorize(ctx context.Context, action string, task *domen.Task) error {
+	if uc.policy == nil {
+		return nil
+	}
+	p, ok := domen.PrincipalFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return uc.policy.Authorize(p, action, task)
+}
+
+// readable оставляет задачи, которые принципалу из ctx разрешено читать.
+func (uc *TaskUseCase) readable(ctx context.Context, tasks []*domen.Task) ([]*domen.Task, error) {
+	if uc.policy == nil {
+		return tasks, nil
+	}
+	out := tasks[:0]
+	for _, t := range tasks {
+		err := uc.authorize(ctx, domen.ActionTaskRead, t)
+		if errors.Is(err, domen.ErrForbidden) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
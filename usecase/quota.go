@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// Quota ограничивает нагрузку одного арендатора. Нулевое поле — без ограничения.
+type Quota struct {
+	// MaxPending — сколько задач арендатора может ждать запуска (PENDING).
+	MaxPending int
+	// MaxRunning — сколько задач арендатора выполняется одновременно,
+	// остальные ждут своей очереди в PENDING. Считается в пределах
+	// одного экземпляра сервиса.
+	MaxRunning int
+	// PerMinute — сколько задач арендатор может создать за минуту.
+	// Неизрасходованная квота копится, но не больше чем на минуту вперёд.
+	PerMinute int
+}
+
+// Quotas — квота по умолчанию и квоты отдельных арендаторов.
+type Quotas struct {
+	Default Quota
+	Tenants map[string]Quota
+}
+
+// For возвращает квоту арендатора.
+func (q Quotas) For(tenant string) Quota {
+	if t, ok := q.Tenants[tenant]; ok {
+		return t
+	}
+	return q.Default
+}
+
+// ParseQuotas разбирает квоты арендаторов из строки вида
+// "tenant:pending,running,per_minute;tenant2:0,5,0".
+func ParseQuotas(spec string) (map[string]Quota, error) {
+	quotas := make(map[string]Quota)
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		tenant, limits, ok := strings.Cut(item, ":")
+		parts := strings.Split(limits, ",")
+		if !ok || tenant == "" || len(parts) != 3 {
+			return nil, fmt.Errorf("tenant quota %q: expected tenant:pending,running,per_minute", item)
+		}
+		var n [3]int
+		for i, p := range parts {
+			v, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("tenant quota %q: limits must be non-negative integers", item)
+			}
+			n[i] = v
+		}
+		if _, ok := quotas[tenant]; ok {
+			return nil, fmt.Errorf("tenant quota %q: duplicate tenant", item)
+		}
+		quotas[tenant] = Quota{MaxPending: n[0], MaxRunning: n[1], PerMinute: n[2]}
+	}
+	return quotas, nil
+}
+
+// SetQuotas задаёт квоты арендаторов. Вызывать до начала работы.
+func (uc *TaskUseCase) SetQuotas(q Quotas) {
+	uc.quotas = q
+}
+
+// tenantOf возвращает арендатора, от имени которого создаются задачи.
+func tenantOf(ctx context.Context) string {
+	if tenant, ok := domen.TenantFromContext(ctx); ok {
+		return tenant
+	}
+	return domen.DefaultTenant
+}
+
+// admit проверяет, что арендатор из ctx может создать ещё n задач, и
+// засчитывает их в поминутный лимит. До вызова done другие создания
+// задач того же арендатора ждут, чтобы параллельные запросы не превысили
+// MaxPending вместе. Превышение квоты — domen.ErrRateLimited.
+//
+// done получает число действительно сохранённых задач: квота за
+// несохранённые возвращается арендатору.
+func (uc *TaskUseCase) admit(ctx context.Context, n int) (done func(created int), err error) {
+	tenant := tenantOf(ctx)
+	q := uc.quotas.For(tenant)
+	unlock := uc.limits.lock(tenant)
+	if err := uc.checkQuota(ctx, tenant, q, n); err != nil {
+		unlock()
+		return nil, err
+	}
+	return func(created int) {
+		if q.PerMinute > 0 && created < n {
+			uc.limits.refund(tenant, n-created, q.PerMinute)
+		}
+		unlock()
+	}, nil
+}
+
+func (uc *TaskUseCase) checkQuota(ctx context.Context, tenant string, q Quota, n int) error {
+	if q.MaxPending > 0 {
+		pending, err := uc.repo.Find(ctx, domen.TaskFilter{
+			Statuses: []domen.Status{domen.StatusPending},
+			TenantID: tenant,
+			Limit:    q.MaxPending,
+		})
+		if err != nil {
+			return err
+		}
+		if len(pending)+n > q.MaxPending {
+			return fmt.Errorf("%w: tenant %s: quota of %d pending tasks exceeded", domen.ErrRateLimited, tenant, q.MaxPending)
+		}
+	}
+	if q.PerMinute > 0 && !uc.limits.take(tenant, n, q.PerMinute, time.Now()) {
+		return fmt.Errorf("%w: tenant %s: quota of %d tasks per minute exceeded", domen.ErrRateLimited, tenant, q.PerMinute)
+	}
+	return nil
+}
+
+// tenantLimits хранит состояние квот арендаторов в памяти процесса.
+type tenantLimits struct {
+	mu      sync.Mutex
+	locks   map[string]*sync.Mutex
+	buckets map[string]*tokenBucket
+	slots   map[string]chan struct{}
+}
+
+// tokenBucket — сколько задач арендатор может создать прямо сейчас.
+// Запас пополняется равномерно, PerMinute задач в минуту.
+type tokenBucket struct {
+	tokens float64
+	at     time.Time
+}
+
+func newTenantLimits() *tenantLimits {
+	return &tenantLimits{
+		locks:   make(map[string]*sync.Mutex),
+		buckets: make(map[string]*tokenBucket),
+		slots:   make(map[string]chan struct{}),
+	}
+}
+
+func (l *tenantLimits) lock(tenant string) func() {
+	l.mu.Lock()
+	m, ok := l.locks[tenant]
+	if !ok {
+		m = &sync.Mutex{}
+		l.locks[tenant] = m
+	}
+	l.mu.Unlock()
+	m.Lock()
+	return m.Unlock
+}
+
+// take забирает n задач из запаса арендатора, если их там хватает.
+func (l *tenantLimits) take(tenant string, n, perMinute int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[tenant]
+	if !ok {
+		b = &tokenBucket{tokens: float64(perMinute), at: now}
+		l.buckets[tenant] = b
+	}
+	b.tokens = min(float64(perMinute), b.tokens+now.Sub(b.at).Minutes()*float64(perMinute))
+	b.at = now
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
+
+// refund возвращает в запас арендатора n задач, взятых take, но не созданных.
+func (l *tenantLimits) refund(tenant string, n, perMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[tenant]; ok {
+		b.tokens = min(float64(perMinute), b.tokens+float64(n))
+	}
+}
+
+// acquire ждёт свободного места среди выполняющихся задач арендатора.
+// При limit == 0 места не ограничены.
+func (l *tenantLimits) acquire(tenant string, limit int) (release func()) {
+	if limit <= 0 {
+		return func() {}
+	}
+	l.mu.Lock()
+	slots, ok := l.slots[tenant]
+	if !ok {
+		slots = make(chan struct{}, limit)
+		l.slots[tenant] = slots
+	}
+	l.mu.Unlock()
+	slots <- struct{}{}
+	return func() { <-slots }
+}
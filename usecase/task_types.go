@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// defaultTaskSchema допускает любой объект: у задач по умолчанию нет параметров.
+const defaultTaskSchema = `{"type": "object"}`
+
+// taskTypes — реестр типов задач со скомпилированными схемами payload.
+type taskTypes struct {
+	mu    sync.RWMutex
+	types map[string]*taskType
+}
+
+type taskType struct {
+	info   domen.TaskType
+	schema *jsonschema.Schema
+}
+
+func newTaskTypes() *taskTypes {
+	r := &taskTypes{types: make(map[string]*taskType)}
+	if err := r.register(domen.TaskType{
+		Name:        domen.DefaultTaskType,
+		Description: "Task without parameters",
+		Schema:      json.RawMessage(defaultTaskSchema),
+	}); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func (r *taskTypes) register(t domen.TaskType) error {
+	if t.Name == "" {
+		return fmt.Errorf("%w: task type name is required", domen.ErrInvalidArgument)
+	}
+	c := jsonschema.NewCompiler()
+	url := t.Name + ".json"
+	if err := c.AddResource(url, bytes.NewReader(t.Schema)); err != nil {
+		return fmt.Errorf("%w: task type %s: %v", domen.ErrInvalidArgument, t.Name, err)
+	}
+	schema, err := c.Compile(url)
+	if err != nil {
+		return fmt.Errorf("%w: task type %s: %v", domen.ErrInvalidArgument, t.Name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.types[t.Name]; ok {
+		return fmt.Errorf("task type %s: %w", t.Name, domen.ErrAlreadyExists)
+	}
+	t.Schema = append(json.RawMessage(nil), t.Schema...)
+	r.types[t.Name] = &taskType{info: t, schema: schema}
+	return nil
+}
+
+func (r *taskTypes) list() []domen.TaskType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]domen.TaskType, 0, len(r.types))
+	for _, t := range r.types {
+		info := t.info
+		info.Schema = append(json.RawMessage(nil), t.info.Schema...)
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// validate проверяет payload по схеме типа. Отсутствующий payload
+// проверяется как пустой объект. Ошибки схемы возвращаются как
+// *domen.ValidationError с путями от корня тела запроса.
+func (r *taskTypes) validate(name string, payload json.RawMessage) error {
+	r.mu.RLock()
+	t, ok := r.types[name]
+	r.mu.RUnlock()
+	if !ok {
+		return &domen.ValidationError{Fields: []domen.FieldError{
+			{Field: "/type", Message: fmt.Sprintf("unknown task type %q", name)},
+		}}
+	}
+
+	if len(payload) == 0 {
+		payload = json.RawMessage(`{}`)
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return &domen.ValidationError{Fields: []domen.FieldError{
+			{Field: "/payload", Message: "invalid JSON: " + err.Error()},
+		}}
+	}
+
+	err := t.schema.Validate(v)
+	var ve *jsonschema.ValidationError
+	if errors.As(err, &ve) {
+		verr := &domen.ValidationError{}
+		collectFieldErrors(ve, verr)
+		return verr
+	}
+	return err
+}
+
+// collectFieldErrors собирает листовые ошибки: промежуточные узлы дерева
+// только сообщают, что не прошла вложенная схема.
+func collectFieldErrors(ve *jsonschema.ValidationError, out *domen.ValidationError) {
+	if len(ve.Causes) == 0 {
+		out.Fields = append(out.Fields, domen.FieldError{
+			Field:   "/payload" + ve.InstanceLocation,
+			Message: ve.Message,
+		})
+		return
+	}
+	for _, c := range ve.Causes {
+		collectFieldErrors(c, out)
+	}
+}
@@ -1,72 +1,359 @@
 package usecase
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
 	"time"
 
 	"github.com/gaz358/myprog/workmate/domen"
+	"github.com/gaz358/myprog/workmate/pkg/logger"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// maxUpdateRetries ограничивает число повторов read-modify-write при ErrConflict.
+const maxUpdateRetries = 5
+
+// errSkipUpdate возвращается из mutate, когда изменять задачу не нужно.
+var errSkipUpdate = errors.New("skip update")
+
 type TaskUseCase struct {
 	repo     domen.TaskRepository
 	duration time.Duration
+	types    *taskTypes
+	quotas   Quotas
+	limits   *tenantLimits
+	policy   domen.Policy
+	auditLog domen.AuditLog
 }
 
+// NewTaskUseCase создаёт use case задач. Все запросы к repo ограничены
+// арендатором принципала из контекста.
 func NewTaskUseCase(repo domen.TaskRepository, duration time.Duration) *TaskUseCase {
 	return &TaskUseCase{
-		repo:     repo,
+		repo:     isolate(repo),
 		duration: duration,
+		types:    newTaskTypes(),
+		limits:   newTenantLimits(),
 	}
 }
 
-func (uc *TaskUseCase) CreateTask() (*domen.Task, error) {
-	task := &domen.Task{
-		ID:        uuid.NewString(),
-		CreatedAt: time.Now(),
-		Status:    domen.StatusPending,
+// RegisterTaskType добавляет тип задач со схемой payload. Некорректная
+// схема даёт domen.ErrInvalidArgument, занятое имя — domen.ErrAlreadyExists.
+func (uc *TaskUseCase) RegisterTaskType(t domen.TaskType) error {
+	return uc.types.register(t)
+}
+
+// TaskTypes возвращает зарегистрированные типы задач, упорядоченные по имени.
+func (uc *TaskUseCase) TaskTypes() []domen.TaskType {
+	return uc.types.list()
+}
+
+// CreateTask создаёт задачу типа typ (domen.DefaultTaskType, если пусто)
+// и запускает её. Payload, не прошедший схему типа, даёт *domen.ValidationError.
+func (uc *TaskUseCase) CreateTask(ctx context.Context, typ string, payload json.RawMessage) (task *domen.Task, err error) {
+	if typ == "" {
+		typ = domen.DefaultTaskType
+	}
+	ctx, span := startSpan(ctx, "TaskUseCase.CreateTask", attribute.String("task.type", typ))
+	defer func() { endSpan(span, err) }()
+	defer func() { uc.audit(ctx, domen.ActionTaskCreate, taskID(task), err) }()
+
+	if err := uc.authorize(ctx, domen.ActionTaskCreate, nil); err != nil {
+		return nil, err
 	}
-	if err := uc.repo.Create(task); err != nil {
+	if err := uc.types.validate(typ, payload); err != nil {
 		return nil, err
 	}
-	go uc.run(task)
+	done, err := uc.admit(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	created := 0
+	defer func() { done(created) }()
+	task = newTask(ctx, time.Now())
+	task.Type = typ
+	task.Payload = payload
+	task.TraceContext = traceParent(ctx)
+	if err := uc.repo.Create(ctx, task); err != nil {
+		return nil, err
+	}
+	created = 1
+	span.SetAttributes(attribute.String("task.id", task.ID))
+	uc.start(ctx, task)
 	return task, nil
 }
 
-func (uc *TaskUseCase) run(task *domen.Task) {
-	task.Status = domen.StatusRunning
-	task.StartedAt = time.Now()
+// newTask создаёт задачу в статусе PENDING от имени принципала из ctx.
+func newTask(ctx context.Context, now time.Time) *domen.Task {
+	var createdBy string
+	if p, ok := domen.PrincipalFromContext(ctx); ok {
+		createdBy = p.ID
+	}
+	return &domen.Task{
+		ID:        uuid.NewString(),
+		CreatedAt: now,
+		Status:    domen.StatusPending,
+		Type:      domen.DefaultTaskType,
+		CreatedBy: createdBy,
+		TenantID:  tenantOf(ctx),
+		History:   []domen.Transition{{To: domen.StatusPending, At: now, Reason: "created"}},
+	}
+}
+
+func taskID(t *domen.Task) string {
+	if t == nil {
+		return ""
+	}
+	return t.ID
+}
+
+// start запускает выполнение задачи в фоне. Задача живёт дольше запроса:
+// отмену не наследуем, логгер и принципала сохраняем, а span выполнения
+// связываем с запросом через task.TraceContext.
+func (uc *TaskUseCase) start(ctx context.Context, task *domen.Task) {
+	go uc.run(context.WithoutCancel(ctx), task.ID, task.TenantID, task.TraceContext)
+}
+
+// run выполняет задачу в собственном корневом span: запрос, создавший
+// задачу, к этому времени может давно завершиться, поэтому с ним span
+// связан ссылкой, а не вложенностью. Пока у арендатора заняты все места
+// MaxRunning, задача ждёт в PENDING.
+func (uc *TaskUseCase) run(ctx context.Context, id, tenant, traceContext string) {
+	ctx, span := tracer.Start(ctx, "task.run",
+		trace.WithNewRoot(),
+		trace.WithLinks(linkTo(traceContext)...),
+		trace.WithAttributes(attribute.String("task.id", id)))
+	var err error
+	defer func() { endSpan(span, err) }()
+	log := logger.FromContext(ctx).WithField("task_id", id)
+
+	release := uc.limits.acquire(tenant, uc.quotas.For(tenant).MaxRunning)
+	defer release()
+
+	task, err := uc.updateTask(ctx, id, func(t *domen.Task) error {
+		if t.Status != domen.StatusPending {
+			return errSkipUpdate
+		}
+		t.StartedAt = time.Now()
+		return t.Transition(domen.StatusRunning, "started", t.StartedAt)
+	})
+	if err != nil {
+		log.Errorw("failed to start task", "error", err)
+		return
+	}
+	if task.Status != domen.StatusRunning {
+		return
+	}
 
 	time.Sleep(uc.duration)
 
-	task.Status = domen.StatusCompleted
-	task.EndedAt = time.Now()
-	task.Duration = task.EndedAt.Sub(task.StartedAt).String()
-	task.Result = "OK"
+	_, err = uc.updateTask(ctx, id, func(t *domen.Task) error {
+		if t.Status != domen.StatusRunning {
+			return errSkipUpdate
+		}
+		t.EndedAt = time.Now()
+		t.Duration = t.EndedAt.Sub(t.StartedAt).String()
+		t.Result = "OK"
+		return t.Transition(domen.StatusCompleted, "finished", t.EndedAt)
+	})
+	if err != nil {
+		log.Errorw("failed to complete task", "error", err)
+	}
+}
 
-	_ = uc.repo.Update(task)
+func (uc *TaskUseCase) GetTask(ctx context.Context, id string) (_ *domen.Task, err error) {
+	ctx, span := startSpan(ctx, "TaskUseCase.GetTask", attribute.String("task.id", id))
+	defer func() { endSpan(span, err) }()
+	return uc.get(ctx, domen.ActionTaskRead, id)
 }
 
-func (uc *TaskUseCase) GetTask(id string) (*domen.Task, error) {
-	return uc.repo.Get(id)
+// get читает задачу и проверяет, что принципалу из ctx разрешено action над ней.
+func (uc *TaskUseCase) get(ctx context.Context, action, id string) (*domen.Task, error) {
+	task, err := uc.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.authorize(ctx, action, task); err != nil {
+		return nil, err
+	}
+	return task, nil
 }
 
-func (uc *TaskUseCase) DeleteTask(id string) error {
-	return uc.repo.Delete(id)
+// DeleteTask удаляет задачу. Если version != 0, задача удаляется только
+// при совпадении версии, иначе возвращается domen.ErrPreconditionFailed.
+func (uc *TaskUseCase) DeleteTask(ctx context.Context, id string, version int64) (err error) {
+	ctx, span := startSpan(ctx, "TaskUseCase.DeleteTask", attribute.String("task.id", id))
+	defer func() { endSpan(span, err) }()
+	defer func() { uc.audit(ctx, domen.ActionTaskDelete, id, err) }()
+
+	if uc.policy != nil {
+		if _, err := uc.get(ctx, domen.ActionTaskDelete, id); err != nil {
+			return err
+		}
+	}
+	err = uc.repo.Delete(ctx, id, version)
+	if version != 0 && errors.Is(err, domen.ErrConflict) {
+		return domen.ErrPreconditionFailed
+	}
+	return err
 }
 
-func (uc *TaskUseCase) ListTasks() ([]*domen.Task, error) {
-	return uc.repo.List()
+func (uc *TaskUseCase) ListTasks(ctx context.Context) (_ []*domen.Task, err error) {
+	ctx, span := startSpan(ctx, "TaskUseCase.ListTasks")
+	defer func() { endSpan(span, err) }()
+
+	tasks, err := uc.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return uc.readable(ctx, tasks)
 }
 
-func (uc *TaskUseCase) CancelTask(id string) error {
-	task, err := uc.repo.Get(id)
+// GetTaskHistory возвращает историю переходов статуса задачи.
+func (uc *TaskUseCase) GetTaskHistory(ctx context.Context, id string) (_ []domen.Transition, err error) {
+	ctx, span := startSpan(ctx, "TaskUseCase.GetTaskHistory", attribute.String("task.id", id))
+	defer func() { endSpan(span, err) }()
+
+	task, err := uc.get(ctx, domen.ActionTaskRead, id)
 	if err != nil {
+		return nil, err
+	}
+	return task.History, nil
+}
+
+// CancelTask отменяет незавершённую задачу. Если version != 0, отмена
+// выполняется только при совпадении версии, иначе возвращается
+// domen.ErrPreconditionFailed.
+// Повторная отмена ничего не меняет, отмена завершённой задачи возвращает
+// *domen.TransitionError.
+func (uc *TaskUseCase) CancelTask(ctx context.Context, id string, version int64) (_ *domen.Task, err error) {
+	ctx, span := startSpan(ctx, "TaskUseCase.CancelTask", attribute.String("task.id", id))
+	defer func() { endSpan(span, err) }()
+	defer func() { uc.audit(ctx, domen.ActionTaskCancel, id, err) }()
+
+	return uc.cancelTask(ctx, id, version)
+}
+
+// cancelTask — CancelTask без записи в журнал аудита: пакетная отмена
+// записывает исход каждой задачи сама.
+func (uc *TaskUseCase) cancelTask(ctx context.Context, id string, version int64) (*domen.Task, error) {
+	return uc.updateTask(ctx, id, func(t *domen.Task) error {
+		if err := uc.authorize(ctx, domen.ActionTaskCancel, t); err != nil {
+			return err
+		}
+		if version != 0 && t.Version != version {
+			return domen.ErrPreconditionFailed
+		}
+		return cancel(t)
+	})
+}
+
+// cancel переводит задачу в CANCELED. Для уже отменённой задачи возвращает
+// errSkipUpdate.
+func cancel(t *domen.Task) error {
+	if t.Status == domen.StatusCancelled {
+		return errSkipUpdate
+	}
+	if err := t.Transition(domen.StatusCancelled, "canceled by request", time.Now()); err != nil {
 		return err
 	}
-	if task.Status == domen.StatusCompleted || task.Status == domen.StatusFailed || task.Status == domen.StatusCancelled {
-		return nil
+	t.Result = "Canceled"
+	return nil
+}
+
+// RerunTask создаёт и запускает новую задачу — повтор завершённой задачи id
+// с тем же типом. Непустой payload заменяет payload исходной задачи и
+// проверяется по схеме типа; nil оставляет прежний. Исходная задача не
+// меняется. Для незавершённой задачи возвращается domen.ErrNotFinished.
+func (uc *TaskUseCase) RerunTask(ctx context.Context, id string, payload json.RawMessage) (_ *domen.Task, err error) {
+	ctx, span := startSpan(ctx, "TaskUseCase.RerunTask", attribute.String("task.id", id))
+	defer func() { endSpan(span, err) }()
+	var rerunID string
+	defer func() { uc.auditCreated(ctx, domen.ActionTaskRerun, id, rerunID, err) }()
+
+	orig, err := uc.get(ctx, domen.ActionTaskRerun, id)
+	if err != nil {
+		return nil, err
+	}
+	if !orig.Status.IsTerminal() {
+		return nil, domen.ErrNotFinished
+	}
+	if payload == nil {
+		payload = orig.Payload
+	} else if err := uc.types.validate(orig.Type, payload); err != nil {
+		return nil, err
+	}
+	done, err := uc.admit(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	created := 0
+	defer func() { done(created) }()
+	task := newTask(ctx, time.Now())
+	task.RerunOf = orig.ID
+	task.Type = orig.Type
+	task.Payload = payload
+	task.History[0].Reason = "rerun of " + orig.ID
+	task.TraceContext = traceParent(ctx)
+	if err := uc.repo.Create(ctx, task); err != nil {
+		return nil, err
+	}
+	created, rerunID = 1, task.ID
+	uc.start(ctx, task)
+	return task, nil
+}
+
+// ListReruns возвращает все повторы задачи id, включая повторы повторов,
+// в порядке создания.
+func (uc *TaskUseCase) ListReruns(ctx context.Context, id string) (_ []*domen.Task, err error) {
+	ctx, span := startSpan(ctx, "TaskUseCase.ListReruns", attribute.String("task.id", id))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := uc.get(ctx, domen.ActionTaskRead, id); err != nil {
+		return nil, err
+	}
+	reruns := make([]*domen.Task, 0)
+	for queue := []string{id}; len(queue) > 0 && len(reruns) < maxBulkItems; queue = queue[1:] {
+		tasks, err := uc.repo.Find(ctx, domen.TaskFilter{RerunOf: queue[0], Limit: maxBulkItems - len(reruns)})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tasks {
+			reruns = append(reruns, t)
+			queue = append(queue, t.ID)
+		}
+	}
+	sort.SliceStable(reruns, func(i, j int) bool { return reruns[i].CreatedAt.Before(reruns[j].CreatedAt) })
+	return uc.readable(ctx, reruns)
+}
+
+// updateTask перечитывает задачу, применяет к ней mutate и сохраняет,
+// повторяя попытку, если задачу успели изменить параллельно.
+func (uc *TaskUseCase) updateTask(ctx context.Context, id string, mutate func(*domen.Task) error) (*domen.Task, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		task, err := uc.repo.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := mutate(task); err != nil {
+			if errors.Is(err, errSkipUpdate) {
+				return task, nil
+			}
+			return nil, err
+		}
+		err = uc.repo.Update(ctx, task)
+		if errors.Is(err, domen.ErrConflict) {
+			logger.FromContext(ctx).Debugw("task update conflict, retrying", "task_id", id, "attempt", attempt+1)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return task, nil
 	}
-	task.Status = domen.StatusCancelled
-	task.Result = "Canceled"
-	return uc.repo.Update(task)
+	return nil, domen.ErrConflict
 }
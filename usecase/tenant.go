@@ -0,0 +1,221 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gaz358/myprog/workmate/domen"
+)
+
+// isolate оборачивает репозиторий так, что каждый запрос видит только
+// задачи арендатора принципала из ctx. Задачи чужого арендатора
+// неотличимы от несуществующих: ErrNotFound. Без принципала (фоновые
+// задачи сервиса, выключенная аутентификация) запросы не ограничиваются.
+// Если repo реализует domen.BulkRepository, обёртка тоже его реализует.
+func isolate(repo domen.TaskRepository) domen.TaskRepository {
+	r := &tenantRepo{repo: repo}
+	if bulk, ok := repo.(domen.BulkRepository); ok {
+		return &tenantBulkRepo{tenantRepo: r, bulk: bulk}
+	}
+	return r
+}
+
+type tenantRepo struct {
+	repo domen.TaskRepository
+}
+
+// owns проверяет, что сохранённая задача id принадлежит арендатору.
+// Арендатора берём из хранилища, а не из переданной задачи: так чужую
+// задачу нельзя перезаписать, подставив в неё свой TenantID.
+func (r *tenantRepo) owns(ctx context.Context, tenant, id string) error {
+	t, err := r.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if t.TenantID != tenant {
+		return domen.ErrNotFound
+	}
+	return nil
+}
+
+func (r *tenantRepo) Create(ctx context.Context, t *domen.Task) error {
+	if tenant, ok := domen.TenantFromContext(ctx); ok {
+		t.TenantID = tenant
+	}
+	return r.repo.Create(ctx, t)
+}
+
+func (r *tenantRepo) Update(ctx context.Context, t *domen.Task) error {
+	if tenant, ok := domen.TenantFromContext(ctx); ok {
+		if err := r.owns(ctx, tenant, t.ID); err != nil {
+			return err
+		}
+	}
+	return r.repo.Update(ctx, t)
+}
+
+func (r *tenantRepo) Delete(ctx context.Context, id string, version int64) error {
+	if tenant, ok := domen.TenantFromContext(ctx); ok {
+		if err := r.owns(ctx, tenant, id); err != nil {
+			return err
+		}
+	}
+	return r.repo.Delete(ctx, id, version)
+}
+
+func (r *tenantRepo) Get(ctx context.Context, id string) (*domen.Task, error) {
+	t, err := r.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tenant, ok := domen.TenantFromContext(ctx); ok && t.TenantID != tenant {
+		return nil, domen.ErrNotFound
+	}
+	return t, nil
+}
+
+func (r *tenantRepo) List(ctx context.Context) ([]*domen.Task, error) {
+	if tenant, ok := domen.TenantFromContext(ctx); ok {
+		return r.repo.Find(ctx, domen.TaskFilter{TenantID: tenant})
+	}
+	return r.repo.List(ctx)
+}
+
+func (r *tenantRepo) Find(ctx context.Context, filter domen.TaskFilter) ([]*domen.Task, error) {
+	if tenant, ok := domen.TenantFromContext(ctx); ok {
+		filter.TenantID = tenant
+	}
+	return r.repo.Find(ctx, filter)
+}
+
+func (r *tenantRepo) Watch(ctx context.Context, filter domen.WatchFilter) (<-chan domen.TaskChange, error) {
+	if tenant, ok := domen.TenantFromContext(ctx); ok {
+		filter.TenantID = tenant
+	}
+	return r.repo.Watch(ctx, filter)
+}
+
+type tenantBulkRepo struct {
+	*tenantRepo
+	bulk domen.BulkRepository
+}
+
+func (r *tenantBulkRepo) CreateMany(ctx context.Context, tasks []*domen.Task) ([]error, error) {
+	if tenant, ok := domen.TenantFromContext(ctx); ok {
+		for _, t := range tasks {
+			t.TenantID = tenant
+		}
+	}
+	return r.bulk.CreateMany(ctx, tasks)
+}
+
+func (r *tenantBulkRepo) UpdateMany(ctx context.Context, tasks []*domen.Task) ([]error, error) {
+	ids := make([]string, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	return r.owned(ctx, ids, func(pos []int) ([]error, error) {
+		owned := make([]*domen.Task, len(pos))
+		for i, p := range pos {
+			owned[i] = tasks[p]
+		}
+		return r.bulk.UpdateMany(ctx, owned)
+	})
+}
+
+func (r *tenantBulkRepo) DeleteMany(ctx context.Context, ids []string) ([]error, error) {
+	return r.owned(ctx, ids, func(pos []int) ([]error, error) {
+		owned := make([]string, len(pos))
+		for i, p := range pos {
+			owned[i] = ids[p]
+		}
+		return r.bulk.DeleteMany(ctx, owned)
+	})
+}
+
+// owned передаёт в fn позиции задач арендатора и раскладывает ошибки
+// fn обратно по входу; чужим и несуществующим задачам достаётся ErrNotFound.
+func (r *tenantBulkRepo) owned(ctx context.Context, ids []string, fn func(pos []int) ([]error, error)) ([]error, error) {
+	errs := make([]error, len(ids))
+	pos := make([]int, 0, len(ids))
+	tenant, scoped := domen.TenantFromContext(ctx)
+	for i, id := range ids {
+		if scoped {
+			err := r.owns(ctx, tenant, id)
+			if errors.Is(err, domen.ErrNotFound) {
+				errs[i] = err
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		pos = append(pos, i)
+	}
+	if len(pos) == 0 {
+		return errs, ctx.Err()
+	}
+	res, err := fn(pos)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range pos {
+		errs[p] = res[i]
+	}
+	return errs, nil
+}
+
+// isolateBatches ограничивает пакеты арендатором так же, как isolate — задачи.
+func isolateBatches(repo domen.BatchRepository) domen.BatchRepository {
+	return &tenantBatchRepo{repo: repo}
+}
+
+type tenantBatchRepo struct {
+	repo domen.BatchRepository
+}
+
+func (r *tenantBatchRepo) Create(ctx context.Context, b *domen.Batch) error {
+	if tenant, ok := domen.TenantFromContext(ctx); ok {
+		b.TenantID = tenant
+	}
+	return r.repo.Create(ctx, b)
+}
+
+func (r *tenantBatchRepo) Get(ctx context.Context, id string) (*domen.Batch, error) {
+	b, err := r.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tenant, ok := domen.TenantFromContext(ctx); ok && b.TenantID != tenant {
+		return nil, domen.ErrNotFound
+	}
+	return b, nil
+}
+
+func (r *tenantBatchRepo) Finish(ctx context.Context, id string, at time.Time) error {
+	if _, ok := domen.TenantFromContext(ctx); ok {
+		if _, err := r.Get(ctx, id); err != nil {
+			return err
+		}
+	}
+	return r.repo.Finish(ctx, id, at)
+}
+
+func (r *tenantBatchRepo) ListUnfinished(ctx context.Context) ([]*domen.Batch, error) {
+	batches, err := r.repo.ListUnfinished(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tenant, ok := domen.TenantFromContext(ctx)
+	if !ok {
+		return batches, nil
+	}
+	own := batches[:0]
+	for _, b := range batches {
+		if b.TenantID == tenant {
+			own = append(own, b)
+		}
+	}
+	return own, nil
+}